@@ -0,0 +1,69 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"github.com/tyroneavnit/keel/pkg/keel"
+)
+
+var amendCmd = &cobra.Command{
+	Use:   "amend <id>",
+	Short: "Correct an active decision's rationale, links or dates",
+	Long: `Update an active decision in place, for corrections that don't change what
+was decided: a clearer rationale, files or symbols that were missed, a moved
+anchor, or a new review date. To change the choice itself, use keel supersede.
+
+Only the flags given are changed. A list flag replaces the decision's list.`,
+	Args: cobra.ExactArgs(1),
+	RunE: runAmend,
+}
+
+var (
+	amendRationale  string
+	amendFiles      string
+	amendSymbols    string
+	amendAnchors    string
+	amendRefs       string
+	amendReviewBy   string
+	amendExpiresAt  string
+	amendNoSymCheck bool
+)
+
+func init() {
+	amendCmd.Flags().StringVar(&amendRationale, "rationale", "", "New rationale")
+	amendCmd.Flags().StringVar(&amendFiles, "files", "", "Comma-separated list of affected files")
+	amendCmd.Flags().StringVar(&amendSymbols, "symbols", "", "Comma-separated list of affected symbols")
+	amendCmd.Flags().StringVar(&amendAnchors, "anchor", "", "Comma-separated line ranges the decision applies to (file:start-end)")
+	amendCmd.Flags().StringVar(&amendRefs, "refs", "", "Comma-separated list of external references (issues, epics, etc.)")
+	amendCmd.Flags().StringVar(&amendReviewBy, "review-by", "", "Date this decision should be revisited (YYYY-MM-DD or RFC3339)")
+	amendCmd.Flags().StringVar(&amendExpiresAt, "expires-at", "", "Date this decision stops applying (YYYY-MM-DD or RFC3339)")
+	amendCmd.Flags().BoolVar(&amendNoSymCheck, "no-symbol-check", false, "Skip resolving Go symbols against the source tree")
+	rootCmd.AddCommand(amendCmd)
+}
+
+func runAmend(cmd *cobra.Command, args []string) error {
+	l, err := openLedger(cmd)
+	if err != nil {
+		return err
+	}
+	defer l.Close()
+
+	decision, err := l.Amend(cmd.Context(), args[0], keel.Amendment{
+		Rationale:     amendRationale,
+		Files:         repoPaths(splitAndTrim(amendFiles)),
+		Symbols:       splitAndTrim(amendSymbols),
+		Anchors:       repoPaths(splitAndTrim(amendAnchors)),
+		Refs:          splitAndTrim(amendRefs),
+		ReviewBy:      amendReviewBy,
+		ExpiresAt:     amendExpiresAt,
+		NoSymbolCheck: amendNoSymCheck,
+	})
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Amended %s\n", bold(decision.ID))
+	printLinks(cmd, l, decision)
+	return nil
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+)
+
+var approveCmd = &cobra.Command{
+	Use:   "approve <id>",
+	Short: "Sign off on a decision",
+	Long: `Record a human approval for a decision.
+
+Approvals are appended to the ledger with the approver's git identity.
+Rules under policy.approvals in .keel/config.yaml decide which decisions
+need approval and how many:
+
+  policy:
+    approvals:
+      - types: [constraint, product]
+        required: 1
+      - paths: ["src/billing/*"]
+        required: 2
+
+Paths match listed and anchored files. Rules apply to agent decisions
+unless "roles" says otherwise.
+Until a decision has enough approvals, keel context lists it as pending.
+Set policy.require_approvals: false in .keel/config.yaml to turn the rules off.`,
+	Args: cobra.ExactArgs(1),
+	RunE: runApprove,
+}
+
+var approveAs string
+
+func init() {
+	approveCmd.Flags().StringVar(&approveAs, "as", "", "Approver identifier (defaults to git user.email)")
+	rootCmd.AddCommand(approveCmd)
+}
+
+func runApprove(cmd *cobra.Command, args []string) error {
+	l, err := openLedger(cmd)
+	if err != nil {
+		return err
+	}
+	defer l.Close()
+
+	res, err := l.Approve(cmd.Context(), args[0], approveAs)
+	if err != nil {
+		return err
+	}
+
+	d := res.Decision
+	switch {
+	case !res.Added:
+		fmt.Printf("%s already approved %s\n", res.By, d.ID)
+	case res.Required > 0:
+		fmt.Printf("Approved %s (%d/%d approvals)\n", bold(d.ID), len(d.Approvals), res.Required)
+	default:
+		fmt.Printf("Approved %s\n", bold(d.ID))
+	}
+	return nil
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/tyroneavnit/keel/pkg/keel"
+)
+
+var blameCmd = &cobra.Command{
+	Use:   "blame <id>",
+	Short: "Show who recorded or changed a decision, and in which commit",
+	Long: `List every ledger line recorded for a decision, oldest first: its creation,
+amendments, approvals, anchor relocations and supersession. Each is mapped
+with git blame on decisions.jsonl to the commit that added it, its author,
+and the pull request it arrived in, read from squash commit subjects
+("Fix retries (#123)") or the merge commit that brought it in.
+
+Lines not yet committed are marked as such. keel why --history shows the
+same timeline below the decision.`,
+	Args: cobra.ExactArgs(1),
+	RunE: runBlame,
+}
+
+var blameJSON bool
+
+func init() {
+	blameCmd.Flags().BoolVar(&blameJSON, "json", false, "Output as JSON")
+	rootCmd.AddCommand(blameCmd)
+}
+
+func runBlame(cmd *cobra.Command, args []string) error {
+	l, err := openLedger(cmd)
+	if err != nil {
+		return err
+	}
+	defer l.Close()
+
+	events, err := l.Blame(cmd.Context(), args[0])
+	if err != nil {
+		return err
+	}
+
+	if blameJSON {
+		data, _ := json.MarshalIndent(events, "", "  ")
+		fmt.Println(string(data))
+		return nil
+	}
+	printTimeline(events)
+	return nil
+}
+
+// printTimeline prints a decision's events with their provenance
+func printTimeline(events []keel.Event) {
+	for _, ev := range events {
+		when := ev.At
+		if when == "" && ev.Commit != nil {
+			when = ev.Commit.Time
+		}
+		if when == "" {
+			when = "-"
+		}
+		when = strings.SplitN(when, "T", 2)[0]
+
+		kind := fmt.Sprintf("%-10s", ev.Kind)
+		if ev.Kind == keel.EventSuperseded && ev.Decision.SupersededBy != nil {
+			kind = fmt.Sprintf("%s by %s", ev.Kind, bold(*ev.Decision.SupersededBy))
+		}
+		fmt.Printf("%s  %s  %s\n", dim(fmt.Sprintf("%-10s", when)), kind, provenance(ev.Commit))
+		if ev.Commit != nil {
+			fmt.Printf("            %s\n", dim(ev.Commit.Summary))
+		}
+		// The event line already says who superseded the decision
+		if ev.Kind == keel.EventSuperseded {
+			continue
+		}
+		for _, f := range ev.Fields {
+			fmt.Printf("            %s %s → %s\n", dim(f.Field+":"), formatValue(f.Before), formatValue(f.After))
+		}
+	}
+}
+
+// printChain prints each decision in a supersession chain with its
+// timeline, marking the decision shown and the chain's head
+func printChain(versions []keel.Version, shown string) {
+	for i, v := range versions {
+		d := v.Decision
+		note := ""
+		if d.ID == shown {
+			note += " " + dim("(this decision)")
+		}
+		if v.Head {
+			note += " " + green("← head")
+		}
+		if i > 0 {
+			fmt.Println()
+		}
+		fmt.Printf("%s [%s] %s%s%s\n", bold(d.ID), colorStatus(string(d.Status)), truncate(d.Choice, 60), note, ledgerNote(d))
+		printTimeline(v.History)
+	}
+}
+
+// provenance describes the commit a ledger line arrived in
+func provenance(c *keel.Commit) string {
+	if c == nil {
+		return yellow("not committed yet")
+	}
+	s := fmt.Sprintf("%s %s <%s>", shortCommit(c.Hash), c.Author, c.Email)
+	if c.PR != "" {
+		s += " " + bold("#"+c.PR)
+	}
+	return s
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"github.com/tyroneavnit/keel/internal/config"
+	"gopkg.in/yaml.v3"
+)
+
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "Read or change repository configuration",
+	Long: `Read or change settings in .keel/config.yaml.
+
+Keys:
+  id.prefix                  Prefix for new decision IDs (default DEC)
+  id.length                  Hex characters in new decision IDs, 4-8 (default 4)
+  types.<name>.description   Description of a decision type
+  types.<name>.color         Color for the type: red, green, yellow, blue, magenta, cyan
+  types.<name>.required      Fields the type must carry, e.g. [rationale, refs]; any of
+                             rationale, files, symbols, refs, review_by, expires_at
+  types.<name>.fields        Per-field rules, e.g. {refs: {pattern: "^THREAT-", min: 1}}
+  refs.patterns              Regular expressions every --refs value must match
+  output.format              Default output format: text or json
+  output.color               Colored output: auto, always or never
+  policy.approvals           Approval rules, e.g. [{types: [constraint], required: 1}]; see keel approve
+  policy.require_approvals   Enforce the policy.approvals rules (default true)
+  policy.allow_self_approval Let authors approve their own decisions (default false)
+  defaults.type              Default --type for search and curate
+  defaults.status            Default --status for search: active, superseded or all
+  defaults.author            Default --author for search, curate and context
+  defaults.limit             Default --limit for search
+  defaults.budget            Default --budget for context --format prompt
+
+Examples:
+  keel config get
+  keel config get output.format
+  keel config set output.format json
+  keel config set defaults.type constraint
+  keel config set types.security.description "Security requirements"
+  keel config set types.security.required "[rationale, refs]"
+  keel config set types.security.fields.refs '{pattern: "^THREAT-"}'`,
+}
+
+var configGetCmd = &cobra.Command{
+	Use:   "get [key]",
+	Short: "Show a config value, or the whole effective config",
+	Args:  cobra.MaximumNArgs(1),
+	RunE:  runConfigGet,
+}
+
+var configSetCmd = &cobra.Command{
+	Use:   "set <key> <value>",
+	Short: "Set a config value",
+	Args:  cobra.ExactArgs(2),
+	RunE:  runConfigSet,
+}
+
+var configJSON bool
+
+func init() {
+	configGetCmd.Flags().BoolVar(&configJSON, "json", false, "Output as JSON")
+	configCmd.AddCommand(configGetCmd)
+	configCmd.AddCommand(configSetCmd)
+	rootCmd.AddCommand(configCmd)
+}
+
+func runConfigGet(cmd *cobra.Command, args []string) error {
+	key := ""
+	if len(args) > 0 {
+		key = args[0]
+	}
+
+	value, err := cfg.Get(key)
+	if err != nil {
+		return err
+	}
+
+	if configJSON {
+		data, _ := json.MarshalIndent(value, "", "  ")
+		fmt.Println(string(data))
+		return nil
+	}
+
+	switch v := value.(type) {
+	case map[string]interface{}, []interface{}:
+		data, _ := yaml.Marshal(v)
+		fmt.Print(string(data))
+	default:
+		fmt.Println(v)
+	}
+	return nil
+}
+
+func runConfigSet(cmd *cobra.Command, args []string) error {
+	if err := config.Set(repoRoot, args[0], args[1]); err != nil {
+		return err
+	}
+
+	fmt.Printf("Set %s = %s\n", bold(args[0]), args[1])
+	return nil
+}
@@ -1,114 +1,270 @@
 package main
 
 import (
+	"bufio"
 	"encoding/json"
 	"fmt"
 	"os"
+	"strings"
+	"time"
 
 	"github.com/spf13/cobra"
-	"github.com/tyroneavnit/keel/internal/index"
-	"github.com/tyroneavnit/keel/internal/query"
+	"github.com/tyroneavnit/keel/internal/errs"
+	"github.com/tyroneavnit/keel/internal/git"
+	"github.com/tyroneavnit/keel/internal/store"
 	"github.com/tyroneavnit/keel/internal/types"
+	"github.com/tyroneavnit/keel/pkg/keel"
 )
 
 var contextCmd = &cobra.Command{
-	Use:   "context [path]",
-	Short: "Get decisions affecting a file, symbol, or reference",
-	Long:  `Display all decisions that affect a given file path, symbol, or external reference.`,
-	RunE:  runContext,
+	Use:   "context [path[:line]...]",
+	Short: "Get decisions affecting files, a symbol, or a reference",
+	Long: `Display all decisions that affect a given file path, symbol, or external reference.
+
+With file:line, decisions anchored to a line range covering that line are listed first.
+
+Several paths can be given at once, or read with --stdin, --git-staged or
+--git-diff. Each decision is listed once with the files it matched, and
+active constraints are printed once for the whole set.
+
+Paths given as arguments or read with --stdin are relative to the working
+directory. One that doesn't exist there is taken as repo-relative, so
+git diff --name-only output can be piped in.`,
+	Annotations: map[string]string{usesDefaults: "true"},
+	RunE:        runContext,
 }
 
 var (
-	contextJSON bool
-	contextRef  string
+	contextJSON      bool
+	contextRef       string
+	contextAuthor    string
+	contextSymbolAt  string
+	contextBudget    int
+	contextFormat    string
+	contextStdin     bool
+	contextGitStaged bool
+	contextGitDiff   string
+	contextFollow    bool
 )
 
 func init() {
 	contextCmd.Flags().BoolVar(&contextJSON, "json", false, "Output as JSON")
 	contextCmd.Flags().StringVar(&contextRef, "ref", "", "Get decisions linked to an external reference (issue, epic, etc.)")
+	contextCmd.Flags().BoolVar(&contextFollow, "follow", false, "With --ref, replace superseded decisions with the decisions now in force")
+	contextCmd.Flags().StringVar(&contextSymbolAt, "symbol-at", "", "Get decisions for the symbol enclosing file:line, and for its file")
+	contextCmd.Flags().IntVar(&contextBudget, "budget", 0, "Approximate token budget for --format prompt (0 = unlimited)")
+	contextCmd.Flags().StringVar(&contextFormat, "format", "text", "Output format: text, json or prompt")
+	contextCmd.Flags().BoolVar(&contextStdin, "stdin", false, "Read paths from stdin, one per line")
+	contextCmd.Flags().BoolVar(&contextGitStaged, "git-staged", false, "Get decisions for files staged in git")
+	contextCmd.Flags().StringVar(&contextGitDiff, "git-diff", "", "Get decisions for files changed in a git range (e.g. main...HEAD)")
+	contextCmd.Flags().StringVar(&contextAuthor, "author", "", "Only show decisions made by this identifier (email or agent name)")
+	contextCmd.Flags().String("as-of", "", "Read the ledgers as of a git revision or RFC 3339 time")
 	rootCmd.AddCommand(contextCmd)
 }
 
 func runContext(cmd *cobra.Command, args []string) error {
-	repoRoot, _ := os.Getwd()
-	db, err := index.Open(repoRoot)
+	switch contextFormat {
+	case "text":
+	case "json":
+		contextJSON = true
+	case "prompt":
+		contextJSON = false
+	default:
+		return errs.New(errs.Usage, "invalid format: %s. Must be one of: text, json, prompt", contextFormat)
+	}
+	if contextBudget < 0 {
+		return errs.New(errs.Usage, "invalid budget: %d", contextBudget)
+	}
+
+	batch := contextStdin || contextGitStaged || contextGitDiff != ""
+	if batch && (contextSymbolAt != "" || contextRef != "") {
+		return errs.New(errs.Usage, "--stdin, --git-staged and --git-diff cannot be combined with --ref or --symbol-at")
+	}
+	if contextFollow && contextRef == "" {
+		return errs.New(errs.Usage, "--follow only applies to --ref")
+	}
+
+	l, err := openLedger(cmd)
+	if err != nil {
+		return err
+	}
+	defer l.Close()
+
+	paths, err := contextPaths(l.Root(), args)
 	if err != nil {
-		return fmt.Errorf("failed to open index: %w", err)
+		return err
+	}
+	if !batch && len(paths) == 0 && contextSymbolAt == "" && contextRef == "" {
+		return errs.New(errs.Usage, "must provide a path, --ref, --symbol-at, --stdin, --git-staged or --git-diff option")
 	}
-	defer db.Close()
 
-	var decisions []*types.Decision
-	var constraints []*types.Decision
-	var path string
+	res, err := l.Context(cmd.Context(), keel.ContextRequest{
+		Paths:    paths,
+		Ref:      contextRef,
+		Follow:   contextFollow,
+		SymbolAt: repoPath(contextSymbolAt),
+		Author:   contextAuthor,
+		Batch:    batch,
+	})
+	if err != nil {
+		return err
+	}
 
-	if contextRef != "" {
-		// Query by ref
-		path = fmt.Sprintf("ref:%s", contextRef)
-		decisions, err = query.ByRef(db, contextRef)
-		if err != nil {
-			return err
+	if contextFormat == "prompt" {
+		fmt.Print(res.Prompt(contextBudget))
+	} else if contextJSON {
+		data, _ := json.MarshalIndent(contextOutput(res), "", "  ")
+		fmt.Println(string(data))
+	} else if res.Batch {
+		printBatchResult(res)
+		printPending(res.Pending, l)
+	} else {
+		printContextResult(res.Decisions, res.Constraints)
+		printPending(res.Pending, l)
+	}
+
+	return nil
+}
+
+// contextPaths gathers the paths to look up from arguments, stdin and git
+func contextPaths(repoRoot string, args []string) ([]string, error) {
+	paths := repoPaths(args)
+
+	if contextStdin {
+		scanner := bufio.NewScanner(os.Stdin)
+		for scanner.Scan() {
+			if line := strings.TrimSpace(scanner.Text()); line != "" {
+				paths = append(paths, repoPath(line))
+			}
+		}
+		if err := scanner.Err(); err != nil {
+			return nil, fmt.Errorf("failed to read stdin: %w", err)
 		}
-		constraints, err = query.ActiveConstraints(db)
+	}
+
+	if contextGitStaged {
+		staged, err := git.StagedFiles(repoRoot)
 		if err != nil {
-			return err
+			return nil, err
+		}
+		paths = append(paths, staged...)
+	}
+
+	if contextGitDiff != "" {
+		if strings.HasPrefix(contextGitDiff, "-") {
+			return nil, errs.New(errs.Usage, "invalid git range: %s", contextGitDiff)
 		}
-	} else if len(args) > 0 {
-		// Query by file path
-		path = args[0]
-		result, err := query.ForContext(db, path)
+		changed, err := git.DiffFiles(repoRoot, contextGitDiff)
 		if err != nil {
-			return err
+			return nil, err
 		}
-		decisions = result.Decisions
-		constraints = result.Constraints
-
-		// If no file decisions, try symbol lookup
-		if len(decisions) == 0 {
-			decisions, err = query.BySymbol(db, path)
-			if err != nil {
-				return err
-			}
+		paths = append(paths, changed...)
+	}
+
+	return paths, nil
+}
+
+// BatchDecision is a decision matched by one or more of the requested paths
+type BatchDecision struct {
+	*types.Decision
+	MatchedFiles []string `json:"matched_files"`
+}
+
+// contextOutput shapes a context result for --json
+func contextOutput(r *keel.ContextResult) map[string]interface{} {
+	if !r.Batch {
+		return map[string]interface{}{
+			"path":        r.Title,
+			"decisions":   r.Decisions,
+			"constraints": r.Constraints,
+			"pending":     r.Pending,
 		}
-	} else {
-		return fmt.Errorf("must provide a path or --ref option")
 	}
 
-	if contextJSON {
-		output := map[string]interface{}{
-			"path":        path,
-			"decisions":   decisions,
-			"constraints": constraints,
+	batch := make([]BatchDecision, 0, len(r.Decisions))
+	for _, d := range r.Decisions {
+		batch = append(batch, BatchDecision{Decision: d, MatchedFiles: r.Matched[d.ID]})
+	}
+	return map[string]interface{}{
+		"paths":       r.Paths,
+		"decisions":   batch,
+		"constraints": r.Constraints,
+		"pending":     r.Pending,
+	}
+}
+
+func printBatchResult(r *keel.ContextResult) {
+	if len(r.Decisions) > 0 {
+		fmt.Print(bold(fmt.Sprintf("Decisions affecting %d files:", len(r.Paths))), "\n\n")
+		for _, d := range r.Decisions {
+			printDecisionSummary(d)
+			fmt.Printf("  %s %s\n", dim("Files:"), strings.Join(r.Matched[d.ID], ", "))
+			fmt.Println()
 		}
-		data, _ := json.MarshalIndent(output, "", "  ")
-		fmt.Println(string(data))
+	} else if len(r.Paths) == 0 {
+		fmt.Println(dim("No files to check."))
 	} else {
-		printContextResult(decisions, constraints)
+		fmt.Println(dim(fmt.Sprintf("No decisions directly affect these %d files.", len(r.Paths))))
 	}
-
-	return nil
+	printConstraints(r.Constraints)
 }
 
 func printContextResult(decisions, constraints []*types.Decision) {
 	if len(decisions) > 0 {
-		fmt.Println("\033[1mDecisions affecting this file:\033[0m\n")
+		fmt.Print(bold("Decisions affecting this file:"), "\n\n")
 		for _, d := range decisions {
 			printDecisionSummary(d)
 			fmt.Println()
 		}
 	} else {
-		fmt.Println("\033[2mNo decisions directly affect this file.\033[0m")
+		fmt.Println(dim("No decisions directly affect this file."))
 	}
 
+	printConstraints(constraints)
+}
+
+func printConstraints(constraints []*types.Decision) {
 	if len(constraints) > 0 {
-		fmt.Println("\n\033[1mActive constraints:\033[0m\n")
+		fmt.Print("\n", bold("Active constraints:"), "\n\n")
 		for _, c := range constraints {
-			fmt.Printf("  \033[1m%s\033[0m %s\n", c.ID, c.Choice)
+			fmt.Printf("  %s %s%s\n", bold(c.ID), c.Choice, expiryNote(c))
 		}
 	}
 }
 
+func printPending(pending []*types.Decision, l *keel.Ledger) {
+	if len(pending) == 0 {
+		return
+	}
+	fmt.Print("\n", bold("Pending approval:"), "\n\n")
+	for _, d := range pending {
+		fmt.Printf("  %s [%s] %s %s\n", bold(d.ID), colorType(string(d.Type)), d.Choice,
+			yellow(fmt.Sprintf("(%d/%d approvals)", len(d.Approvals), l.RequiredApprovals(d))))
+	}
+}
+
 func printDecisionSummary(d *types.Decision) {
-	fmt.Printf("\033[1m%s\033[0m [%s] %s\n", d.ID, colorType(string(d.Type)), colorStatus(string(d.Status)))
-	fmt.Printf("  \033[2mProblem:\033[0m %s\n", d.Problem)
-	fmt.Printf("  \033[2mChoice:\033[0m %s\n", d.Choice)
+	fmt.Printf("%s [%s] %s%s%s\n", bold(d.ID), colorType(string(d.Type)), colorStatus(string(d.Status)), expiryNote(d), ledgerNote(d))
+	fmt.Printf("  %s %s\n", dim("Problem:"), d.Problem)
+	fmt.Printf("  %s %s\n", dim("Choice:"), d.Choice)
+}
+
+// ledgerNote names the nested ledger a decision comes from
+func ledgerNote(d *types.Decision) string {
+	if d.Ledger == "" || d.Ledger == store.RootLedger {
+		return ""
+	}
+	return " " + dim("in "+d.Ledger)
+}
+
+// expiryNote annotates decisions that have expired or are due for review
+func expiryNote(d *types.Decision) string {
+	now := time.Now()
+	if d.IsExpired(now) {
+		return " " + red(fmt.Sprintf("(expired %s)", *d.ExpiresAt))
+	}
+	if d.IsReviewDue(now) {
+		return " " + yellow(fmt.Sprintf("(review due %s)", *d.ReviewBy))
+	}
+	return ""
 }
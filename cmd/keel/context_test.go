@@ -0,0 +1,161 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func gitCmd(t *testing.T, dir string, args ...string) {
+	t.Helper()
+	cmd := exec.Command("git", append([]string{"-c", "user.name=Ana", "-c", "user.email=ana@example.com"}, args...)...)
+	cmd.Dir = dir
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("git %v: %v\n%s", args, err, out)
+	}
+}
+
+func TestContextBatch(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	root := initLedger(t,
+		`{"id":"DEC-0001","created_at":"2026-01-01T00:00:00Z","type":"product","problem":"p","choice":"Bill monthly","files":["billing/invoice.go","billing/tax.go"],"decided_by":{"role":"human"},"status":"active"}`,
+		`{"id":"DEC-0002","created_at":"2026-01-02T00:00:00Z","type":"product","problem":"p","choice":"Render in the browser","files":["web/app.ts"],"decided_by":{"role":"human"},"status":"active"}`,
+		`{"id":"DEC-0003","created_at":"2026-01-03T00:00:00Z","type":"constraint","problem":"p","choice":"No new dependencies","decided_by":{"role":"human"},"status":"active"}`,
+	)
+	for _, name := range []string{"billing/invoice.go", "billing/tax.go", "web/app.ts"} {
+		path := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte("v1\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	gitCmd(t, root, "init", "--quiet")
+	gitCmd(t, root, "add", ".")
+	gitCmd(t, root, "commit", "--quiet", "-m", "First")
+	if err := os.WriteFile(filepath.Join(root, "web/app.ts"), []byte("v2\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	gitCmd(t, root, "commit", "--quiet", "-am", "Second")
+	if err := os.WriteFile(filepath.Join(root, "billing/tax.go"), []byte("v3\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	gitCmd(t, root, "add", "billing/tax.go")
+
+	tests := []struct {
+		name      string
+		dir       string // working directory, relative to root
+		stdin     string
+		args      []string
+		wantPaths []string
+		want      map[string][]string // decision ID to matched files
+	}{
+		{
+			name:      "stdin deduplicates decisions",
+			stdin:     "billing/invoice.go\n\nbilling/tax.go\nbilling/invoice.go\n",
+			args:      []string{"--stdin"},
+			wantPaths: []string{"billing/invoice.go", "billing/tax.go"},
+			want:      map[string][]string{"DEC-0001": {"billing/invoice.go", "billing/tax.go"}},
+		},
+		{
+			name:      "stdin relative to the working directory",
+			dir:       "web",
+			stdin:     "./app.ts\nbilling/tax.go\n",
+			args:      []string{"--stdin"},
+			wantPaths: []string{"web/app.ts", "billing/tax.go"},
+			want: map[string][]string{
+				"DEC-0001": {"billing/tax.go"},
+				"DEC-0002": {"web/app.ts"},
+			},
+		},
+		{
+			name:      "stdin and arguments",
+			dir:       "billing",
+			stdin:     "billing/invoice.go\n",
+			args:      []string{"--stdin", "tax.go"},
+			wantPaths: []string{"billing/tax.go", "billing/invoice.go"},
+			want:      map[string][]string{"DEC-0001": {"billing/tax.go", "billing/invoice.go"}},
+		},
+		{
+			name:      "git staged",
+			args:      []string{"--git-staged"},
+			wantPaths: []string{"billing/tax.go"},
+			want:      map[string][]string{"DEC-0001": {"billing/tax.go"}},
+		},
+		{
+			name:      "git diff",
+			args:      []string{"--git-diff", "HEAD~1..HEAD"},
+			wantPaths: []string{"web/app.ts"},
+			want:      map[string][]string{"DEC-0002": {"web/app.ts"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			wd, err := os.Getwd()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := os.Chdir(filepath.Join(root, tt.dir)); err != nil {
+				t.Fatal(err)
+			}
+			defer os.Chdir(wd)
+
+			stdin := filepath.Join(t.TempDir(), "stdin")
+			if err := os.WriteFile(stdin, []byte(tt.stdin), 0644); err != nil {
+				t.Fatal(err)
+			}
+			f, err := os.Open(stdin)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer f.Close()
+			saved := os.Stdin
+			os.Stdin = f
+			defer func() { os.Stdin = saved }()
+
+			var runErr error
+			out := captureStdout(t, func() {
+				_, runErr = execute(t, root, append([]string{"context", "--json"}, tt.args...)...)
+			})
+			if runErr != nil {
+				t.Fatal(runErr)
+			}
+
+			var got struct {
+				Paths     []string `json:"paths"`
+				Decisions []struct {
+					ID           string   `json:"id"`
+					MatchedFiles []string `json:"matched_files"`
+				} `json:"decisions"`
+				Constraints []struct {
+					ID string `json:"id"`
+				} `json:"constraints"`
+			}
+			if err := json.Unmarshal([]byte(out), &got); err != nil {
+				t.Fatalf("output is not JSON: %v\n%s", err, out)
+			}
+			if !reflect.DeepEqual(got.Paths, tt.wantPaths) {
+				t.Errorf("paths = %q, want %q", got.Paths, tt.wantPaths)
+			}
+			matched := map[string][]string{}
+			for _, d := range got.Decisions {
+				if _, dup := matched[d.ID]; dup {
+					t.Errorf("%s is listed twice", d.ID)
+				}
+				matched[d.ID] = d.MatchedFiles
+			}
+			if !reflect.DeepEqual(matched, tt.want) {
+				t.Errorf("matched files = %q, want %q", matched, tt.want)
+			}
+			if len(got.Constraints) != 1 || got.Constraints[0].ID != "DEC-0003" {
+				t.Errorf("constraints = %+v, want DEC-0003 once", got.Constraints)
+			}
+		})
+	}
+}
@@ -3,26 +3,25 @@ package main
 import (
 	"encoding/json"
 	"fmt"
-	"os"
 	"time"
 
 	"github.com/spf13/cobra"
-	"github.com/tyroneavnit/keel/internal/index"
-	"github.com/tyroneavnit/keel/internal/query"
-	"github.com/tyroneavnit/keel/internal/types"
+	"github.com/tyroneavnit/keel/pkg/keel"
 )
 
 var curateCmd = &cobra.Command{
-	Use:   "curate",
-	Short: "Get decisions ready for summarization by an agent",
-	Long:  `List decisions that are candidates for summarization, filtered by age, type, or file pattern.`,
-	RunE:  runCurate,
+	Use:         "curate",
+	Short:       "Get decisions ready for summarization by an agent",
+	Long:        `List decisions that are candidates for summarization, filtered by age, type, or file pattern.`,
+	Annotations: map[string]string{usesDefaults: "true"},
+	RunE:        runCurate,
 }
 
 var (
 	curateOlderThan   int
 	curateType        string
 	curateFilePattern string
+	curateAuthor      string
 	curateJSON        bool
 )
 
@@ -30,31 +29,31 @@ func init() {
 	curateCmd.Flags().IntVar(&curateOlderThan, "older-than", 0, "Only include decisions older than N days")
 	curateCmd.Flags().StringVarP(&curateType, "type", "t", "", "Filter by type: product, process, constraint")
 	curateCmd.Flags().StringVarP(&curateFilePattern, "file-pattern", "f", "", "Filter by file pattern (e.g., 'src/auth/*')")
+	curateCmd.Flags().StringVar(&curateAuthor, "author", "", "Filter by decided_by identifier (email or agent name)")
 	curateCmd.Flags().BoolVar(&curateJSON, "json", false, "Output as JSON")
 	rootCmd.AddCommand(curateCmd)
 }
 
 type CurationCandidate struct {
-	Decision     *types.Decision `json:"decision"`
-	Age          int             `json:"age_days"`
-	RelatedCount int             `json:"related_count"`
+	Decision     *keel.Decision `json:"decision"`
+	Age          int            `json:"age_days"`
+	RelatedCount int            `json:"related_count"`
 }
 
 func runCurate(cmd *cobra.Command, args []string) error {
-	repoRoot, _ := os.Getwd()
-	db, err := index.Open(repoRoot)
+	l, err := openLedger(cmd)
 	if err != nil {
-		return fmt.Errorf("failed to open index: %w", err)
+		return err
 	}
-	defer db.Close()
+	defer l.Close()
 
 	// Get all active decisions
-	opts := query.Options{Status: "active"}
-	if curateType != "" {
-		opts.Type = curateType
-	}
-
-	decisions, err := query.All(db, opts)
+	decisions, err := l.List(cmd.Context(), keel.Filter{
+		Type:   curateType,
+		Status: string(keel.StatusActive),
+		Author: curateAuthor,
+		Local:  true,
+	})
 	if err != nil {
 		return err
 	}
@@ -107,7 +106,7 @@ func runCurate(cmd *cobra.Command, args []string) error {
 	} else {
 		fmt.Printf("Found %d decisions for potential summarization:\n\n", len(candidates))
 		for _, c := range candidates {
-			fmt.Printf("\033[1m%s\033[0m [%s] (%d days old)\n", c.Decision.ID, c.Decision.Type, c.Age)
+			fmt.Printf("%s [%s] (%d days old)\n", bold(c.Decision.ID), c.Decision.Type, c.Age)
 			fmt.Printf("  Problem: %s\n", c.Decision.Problem)
 			fmt.Printf("  Choice: %s\n\n", c.Decision.Choice)
 		}
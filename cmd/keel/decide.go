@@ -2,14 +2,11 @@ package main
 
 import (
 	"fmt"
-	"os"
 	"strings"
 
 	"github.com/spf13/cobra"
-	"github.com/tyroneavnit/keel/internal/id"
-	"github.com/tyroneavnit/keel/internal/index"
-	"github.com/tyroneavnit/keel/internal/store"
 	"github.com/tyroneavnit/keel/internal/types"
+	"github.com/tyroneavnit/keel/pkg/keel"
 )
 
 var decideCmd = &cobra.Command{
@@ -20,7 +17,13 @@ var decideCmd = &cobra.Command{
 Decision types:
   product    - Business logic decisions (e.g., "Free plan = 5 users")
   process    - How-to-work decisions (e.g., "Use functional style")
-  constraint - Hard limits and requirements (e.g., "Must support IE11")`,
+  constraint - Hard limits and requirements (e.g., "Must support IE11")
+  learning   - Failed approaches and discoveries (e.g., "Redis cache caused OOM")
+
+Custom types and their required fields are declared in .keel/config.yaml.
+
+In a repository with nested ledgers, the decision goes to the nearest
+ledger above the working directory; --ledger picks another.`,
 	RunE: runDecide,
 }
 
@@ -31,21 +34,33 @@ var (
 	decideRationale  string
 	decideFiles      string
 	decideSymbols    string
+	decideAnchors    string
 	decideRefs       string
 	decideAgent      bool
+	decideAs         string
 	decideSupersedes string
+	decideReviewBy   string
+	decideExpiresAt  string
+	decideLedger     string
+	decideNoSymCheck bool
 )
 
 func init() {
-	decideCmd.Flags().StringVarP(&decideType, "type", "t", "", "Decision type: product, process, constraint (required)")
+	decideCmd.Flags().StringVarP(&decideType, "type", "t", "", "Decision type: product, process, constraint, learning, or a configured type (required)")
 	decideCmd.Flags().StringVar(&decideProblem, "problem", "", "What problem this addresses (required)")
 	decideCmd.Flags().StringVar(&decideChoice, "choice", "", "What was decided (required)")
 	decideCmd.Flags().StringVar(&decideRationale, "rationale", "", "Why this choice was made")
 	decideCmd.Flags().StringVar(&decideFiles, "files", "", "Comma-separated list of affected files")
 	decideCmd.Flags().StringVar(&decideSymbols, "symbols", "", "Comma-separated list of affected symbols")
+	decideCmd.Flags().StringVar(&decideAnchors, "anchor", "", "Comma-separated line ranges the decision applies to (file:start-end)")
 	decideCmd.Flags().StringVar(&decideRefs, "refs", "", "Comma-separated list of external references (issues, epics, etc.)")
-	decideCmd.Flags().BoolVar(&decideAgent, "agent", false, "Mark as an agent decision")
+	decideCmd.Flags().BoolVar(&decideAgent, "agent", false, "Mark as an agent decision (implied when $KEEL_AGENT is set)")
+	decideCmd.Flags().StringVar(&decideAs, "as", "", "Identifier of who made the decision (defaults to $KEEL_AGENT or git user.email)")
 	decideCmd.Flags().StringVar(&decideSupersedes, "supersedes", "", "ID of decision this supersedes")
+	decideCmd.Flags().BoolVar(&decideNoSymCheck, "no-symbol-check", false, "Skip resolving Go symbols against the source tree")
+	decideCmd.Flags().StringVar(&decideReviewBy, "review-by", "", "Date this decision should be revisited (YYYY-MM-DD or RFC3339)")
+	decideCmd.Flags().StringVar(&decideExpiresAt, "expires-at", "", "Date this decision stops applying (YYYY-MM-DD or RFC3339)")
+	decideCmd.Flags().StringVar(&decideLedger, "ledger", "", "Directory of the ledger to write to (default: the nearest one)")
 
 	decideCmd.MarkFlagRequired("type")
 	decideCmd.MarkFlagRequired("problem")
@@ -55,99 +70,52 @@ func init() {
 }
 
 func runDecide(cmd *cobra.Command, args []string) error {
-	repoRoot, _ := os.Getwd()
-
-	// Check initialization
-	if err := store.RequireInit(repoRoot); err != nil {
-		return err
-	}
-
-	// Validate type
-	if !types.IsValidType(decideType) {
-		return fmt.Errorf("invalid type: %s. Must be one of: product, process, constraint", decideType)
-	}
-
-	// Build input
-	input := types.DecisionInput{
-		Type:    types.DecisionType(decideType),
-		Problem: decideProblem,
-		Choice:  decideChoice,
-	}
-
-	if decideRationale != "" {
-		input.Rationale = &decideRationale
-	}
-
-	if decideFiles != "" {
-		input.Files = splitAndTrim(decideFiles)
-	}
-
-	if decideSymbols != "" {
-		input.Symbols = splitAndTrim(decideSymbols)
+	if decideLedger != "" {
+		ledgerDir = ledgerPath(decideLedger)
 	}
-
-	if decideRefs != "" {
-		input.Refs = splitAndTrim(decideRefs)
-	}
-
-	if decideSupersedes != "" {
-		normalized, err := id.Normalize(decideSupersedes)
-		if err != nil {
-			return err
-		}
-		input.Supersedes = &normalized
-	}
-
-	// Set decided_by
-	role := "human"
-	if decideAgent {
-		role = "agent"
-	}
-	input.DecidedBy = &types.DecidedBy{Role: role}
-
-	// Generate ID
-	decisionID := id.Generate(input.Problem, input.Choice)
-
-	// Create decision
-	decision := types.NewDecision(decisionID, input)
-
-	// Append to JSONL
-	if err := store.AppendDecision(decision, repoRoot); err != nil {
-		return fmt.Errorf("failed to save decision: %w", err)
+	l, err := openLedger(cmd)
+	if err != nil {
+		return err
 	}
-
-	// Update index
-	db, err := index.Open(repoRoot)
+	defer l.Close()
+
+	decision, err := l.Decide(cmd.Context(), keel.DecisionRequest{
+		Type:          decideType,
+		Problem:       decideProblem,
+		Choice:        decideChoice,
+		Rationale:     decideRationale,
+		Files:         repoPaths(splitAndTrim(decideFiles)),
+		Symbols:       splitAndTrim(decideSymbols),
+		Anchors:       repoPaths(splitAndTrim(decideAnchors)),
+		Refs:          splitAndTrim(decideRefs),
+		Supersedes:    decideSupersedes,
+		ReviewBy:      decideReviewBy,
+		ExpiresAt:     decideExpiresAt,
+		Agent:         decideAgent,
+		As:            decideAs,
+		NoSymbolCheck: decideNoSymCheck,
+	})
 	if err != nil {
-		return fmt.Errorf("failed to open index: %w", err)
+		return err
 	}
-	defer db.Close()
 
-	if err := db.IndexDecision(decision); err != nil {
-		return fmt.Errorf("failed to index decision: %w", err)
-	}
+	fmt.Printf("Created %s%s\n", bold(decision.ID), ledgerNote(decision))
+	printLinks(cmd, l, decision)
+	return nil
+}
 
-	// Handle supersedes
-	if input.Supersedes != nil {
-		// Mark old decision as superseded
-		oldDecision, err := store.GetDecisionByID(*input.Supersedes, repoRoot)
-		if err != nil {
-			return fmt.Errorf("failed to get superseded decision: %w", err)
-		}
-		if oldDecision != nil {
-			oldDecision.Status = types.StatusSuperseded
-			oldDecision.SupersededBy = &decisionID
-			if err := store.AppendDecision(oldDecision, repoRoot); err != nil {
-				return fmt.Errorf("failed to update superseded decision: %w", err)
-			}
-			if err := db.IndexDecision(oldDecision); err != nil {
-				return fmt.Errorf("failed to index superseded decision: %w", err)
-			}
+// printLinks lists where a decision's Go symbols resolved, and its anchors
+func printLinks(cmd *cobra.Command, l *keel.Ledger, d *types.Decision) {
+	for _, name := range d.Symbols {
+		sym, err := l.Symbol(cmd.Context(), name)
+		if err != nil || sym == nil {
+			continue
 		}
+		fmt.Printf("  %s %s %s:%d\n", sym.Name, dim("→"), sym.File, sym.Line)
+	}
+	for _, a := range d.Anchors {
+		fmt.Printf("  %s %s\n", dim("anchored"), a)
 	}
-
-	fmt.Printf("Created \033[1m%s\033[0m\n", decisionID)
-	return nil
 }
 
 func splitAndTrim(s string) []string {
@@ -0,0 +1,199 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/tyroneavnit/keel/internal/errs"
+	"github.com/tyroneavnit/keel/pkg/keel"
+)
+
+var diffCmd = &cobra.Command{
+	Use:   "diff [<rev1> <rev2> | <range>]",
+	Short: "Show how decisions changed between two git revisions",
+	Long: `Compare the ledgers committed at two git revisions: decisions added,
+superseded, changed in status, amended or removed, and links to files,
+symbols and refs that were dropped.
+
+Both sides are replayed from decisions.jsonl exactly as the index does, so
+a decision updated several times shows only its net change. With no
+arguments, the range is main...HEAD: the changes a pull request makes.
+A range a..b compares a with b; a...b compares b with its merge base with a.
+
+Output is a changelog, Markdown for PR comments, or JSON.
+
+Examples:
+  keel diff
+  keel diff v1.3.0 v1.4.0
+  keel diff origin/main...HEAD --format markdown`,
+	Args: cobra.MaximumNArgs(2),
+	RunE: runDiff,
+}
+
+var (
+	diffFormat string
+	diffJSON   bool
+)
+
+func init() {
+	diffCmd.Flags().StringVar(&diffFormat, "format", "text", "Output format: text, markdown or json")
+	diffCmd.Flags().BoolVar(&diffJSON, "json", false, "Output as JSON")
+	rootCmd.AddCommand(diffCmd)
+}
+
+func runDiff(cmd *cobra.Command, args []string) error {
+	switch diffFormat {
+	case "text", "markdown":
+	case "json":
+		diffJSON = true
+	default:
+		return errs.New(errs.Usage, "invalid format: %s. Must be one of: text, markdown, json", diffFormat)
+	}
+
+	from, to := "main...HEAD", ""
+	switch len(args) {
+	case 1:
+		from = args[0]
+	case 2:
+		from, to = args[0], args[1]
+	}
+
+	l, err := openLedger(cmd)
+	if err != nil {
+		return err
+	}
+	defer l.Close()
+
+	res, err := l.Diff(cmd.Context(), from, to)
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case diffJSON:
+		data, _ := json.MarshalIndent(res, "", "  ")
+		fmt.Println(string(data))
+	case diffFormat == "markdown":
+		fmt.Print(diffMarkdown(res))
+	default:
+		printDiff(res)
+	}
+	return nil
+}
+
+// printDiff prints a changelog, one line per changed decision
+func printDiff(res *keel.DiffResult) {
+	span := fmt.Sprintf("%s and %s", shortCommit(res.From), shortCommit(res.To))
+	if len(res.Changes) == 0 {
+		fmt.Printf("No decision changes between %s.\n", span)
+		return
+	}
+
+	fmt.Printf("Decision changes between %s:\n\n", span)
+	for _, c := range res.Changes {
+		d := c.Decision
+		switch c.Kind {
+		case keel.ChangeAdded:
+			fmt.Printf("%s %s [%s] %s%s\n", green("+"), bold(c.ID), colorType(string(d.Type)), d.Choice, ledgerNote(d))
+		case keel.ChangeSuperseded:
+			by := ""
+			if d.SupersededBy != nil {
+				by = " by " + bold(*d.SupersededBy)
+			}
+			fmt.Printf("%s %s superseded%s%s\n", yellow("~"), bold(c.ID), by, ledgerNote(d))
+		case keel.ChangeStatus:
+			fmt.Printf("%s %s is now %s%s\n", yellow("~"), bold(c.ID), colorStatus(string(d.Status)), ledgerNote(d))
+		case keel.ChangeAmended:
+			fmt.Printf("%s %s amended%s\n", yellow("*"), bold(c.ID), ledgerNote(d))
+		case keel.ChangeRemoved:
+			fmt.Printf("%s %s removed: %s%s\n", red("-"), bold(c.ID), d.Choice, ledgerNote(d))
+		}
+		for _, f := range c.Fields {
+			fmt.Printf("    %s %s → %s\n", dim(f.Field+":"), formatValue(f.Before), formatValue(f.After))
+		}
+		for _, link := range c.RemovedLinks {
+			fmt.Printf("    %s %s %s\n", red("- "+link.Kind), link.Target, dim("(link removed)"))
+		}
+	}
+}
+
+// diffSections are the Markdown headings for each kind of change
+var diffSections = []struct{ kind, title string }{
+	{keel.ChangeAdded, "Added"},
+	{keel.ChangeSuperseded, "Superseded"},
+	{keel.ChangeStatus, "Status changed"},
+	{keel.ChangeAmended, "Amended"},
+	{keel.ChangeRemoved, "Removed"},
+}
+
+// diffMarkdown renders changes for a pull request comment
+func diffMarkdown(res *keel.DiffResult) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "### Decision changes\n\n`%s`...`%s`", shortCommit(res.From), shortCommit(res.To))
+	if len(res.Changes) == 0 {
+		b.WriteString(": no decision changes.\n")
+		return b.String()
+	}
+	b.WriteString("\n")
+
+	for _, section := range diffSections {
+		var changes []keel.Change
+		for _, c := range res.Changes {
+			if c.Kind == section.kind {
+				changes = append(changes, c)
+			}
+		}
+		if len(changes) == 0 {
+			continue
+		}
+
+		fmt.Fprintf(&b, "\n**%s**\n\n", section.title)
+		for _, c := range changes {
+			d := c.Decision
+			ledger := ""
+			if c.Ledger != "" && c.Ledger != "." {
+				ledger = fmt.Sprintf(" in `%s`", c.Ledger)
+			}
+			switch c.Kind {
+			case keel.ChangeSuperseded:
+				by := ""
+				if d.SupersededBy != nil {
+					by = fmt.Sprintf(" by **%s**", *d.SupersededBy)
+				}
+				fmt.Fprintf(&b, "- ~~**%s**~~ %s%s%s\n", c.ID, markdownLine(d.Choice), by, ledger)
+			case keel.ChangeRemoved:
+				fmt.Fprintf(&b, "- ~~**%s**~~ %s%s\n", c.ID, markdownLine(d.Choice), ledger)
+			case keel.ChangeStatus:
+				fmt.Fprintf(&b, "- **%s** %s: now %s%s\n", c.ID, markdownLine(d.Choice), d.Status, ledger)
+			default:
+				fmt.Fprintf(&b, "- **%s** (%s) %s%s\n", c.ID, d.Type, markdownLine(d.Choice), ledger)
+			}
+			for _, f := range c.Fields {
+				fmt.Fprintf(&b, "  - `%s`: %s → %s\n", f.Field, markdownLine(formatValue(f.Before)), markdownLine(formatValue(f.After)))
+			}
+			for _, link := range c.RemovedLinks {
+				fmt.Fprintf(&b, "  - removed %s link `%s`\n", link.Kind, link.Target)
+			}
+		}
+	}
+	return b.String()
+}
+
+// formatValue renders a field value from a change for display
+func formatValue(v interface{}) string {
+	switch v := v.(type) {
+	case nil:
+		return "(none)"
+	case []string:
+		return strings.Join(v, ", ")
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// markdownLine collapses text to one line for a Markdown list item
+func markdownLine(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"github.com/tyroneavnit/keel/internal/errs"
+	"github.com/tyroneavnit/keel/pkg/keel"
+)
+
+var doctorCmd = &cobra.Command{
+	Use:   "doctor",
+	Short: "Audit decisions against the repository config",
+	Long: `Check every active decision against .keel/config.yaml.
+
+Reports decisions with undeclared types, missing required fields, fields
+that break their type's schema, refs outside the configured patterns, and
+supersession links that point at unknown decisions.`,
+	RunE: runDoctor,
+}
+
+var doctorJSON bool
+
+func init() {
+	doctorCmd.Flags().BoolVar(&doctorJSON, "json", false, "Output as JSON")
+	rootCmd.AddCommand(doctorCmd)
+}
+
+type DoctorIssue struct {
+	DecisionID string `json:"decision_id"`
+	Type       string `json:"type"`
+	Issue      string `json:"issue"`
+}
+
+func runDoctor(cmd *cobra.Command, args []string) error {
+	l, err := openLedger(cmd)
+	if err != nil {
+		return err
+	}
+	defer l.Close()
+
+	decisions, err := l.List(cmd.Context(), keel.Filter{Status: string(keel.StatusActive), Local: true})
+	if err != nil {
+		return err
+	}
+
+	var issues []DoctorIssue
+	for _, d := range decisions {
+		for _, problem := range l.Config().CheckDecision(d) {
+			issues = append(issues, DoctorIssue{DecisionID: d.ID, Type: string(d.Type), Issue: problem})
+		}
+
+		if d.Supersedes != nil {
+			_, err := l.Get(cmd.Context(), *d.Supersedes)
+			if err != nil && keel.KindOf(err) != keel.KindNotFound && keel.KindOf(err) != keel.KindInvalidID {
+				return err
+			}
+			if err != nil {
+				issues = append(issues, DoctorIssue{
+					DecisionID: d.ID,
+					Type:       string(d.Type),
+					Issue:      fmt.Sprintf("supersedes unknown decision %s", *d.Supersedes),
+				})
+			}
+		}
+	}
+
+	if doctorJSON {
+		data, _ := json.MarshalIndent(issues, "", "  ")
+		fmt.Println(string(data))
+	} else {
+		if len(issues) == 0 {
+			fmt.Printf("%s\n", green(fmt.Sprintf("✓ All %d active decisions match the repository config", len(decisions))))
+		} else {
+			fmt.Printf("%s\n\n", red(fmt.Sprintf("✗ Found %d issues:", len(issues))))
+			for _, issue := range issues {
+				fmt.Printf("  %s [%s]: %s\n", bold(issue.DecisionID), colorType(issue.Type), issue.Issue)
+			}
+		}
+	}
+
+	if len(issues) > 0 {
+		return errs.Reported(errs.ValidationFailed, "found %d config issues", len(issues))
+	}
+
+	return nil
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/tyroneavnit/keel/pkg/keel"
+)
+
+var dueCmd = &cobra.Command{
+	Use:   "due",
+	Short: "List decisions past their review or expiry date",
+	Long: `List active decisions whose review_by or expires_at date has passed.
+
+Expired decisions are no longer in force and should be superseded or renewed.
+Decisions due for review are still in force but should be revisited.`,
+	RunE: runDue,
+}
+
+var (
+	dueWithin int
+	dueJSON   bool
+)
+
+func init() {
+	dueCmd.Flags().IntVar(&dueWithin, "within", 0, "Also include decisions due within N days")
+	dueCmd.Flags().BoolVar(&dueJSON, "json", false, "Output as JSON")
+	rootCmd.AddCommand(dueCmd)
+}
+
+type DueDecision struct {
+	Decision  *keel.Decision `json:"decision"`
+	Expired   bool           `json:"expired"`
+	ReviewDue bool           `json:"review_due"`
+}
+
+func runDue(cmd *cobra.Command, args []string) error {
+	l, err := openLedger(cmd)
+	if err != nil {
+		return err
+	}
+	defer l.Close()
+
+	now := time.Now()
+	decisions, err := l.Due(cmd.Context(), now.AddDate(0, 0, dueWithin))
+	if err != nil {
+		return err
+	}
+
+	var due []DueDecision
+	for _, d := range decisions {
+		due = append(due, DueDecision{
+			Decision:  d,
+			Expired:   d.IsExpired(now),
+			ReviewDue: d.IsReviewDue(now),
+		})
+	}
+
+	if dueJSON {
+		data, _ := json.MarshalIndent(due, "", "  ")
+		fmt.Println(string(data))
+		return nil
+	}
+
+	if len(due) == 0 {
+		fmt.Println("No decisions are due for review.")
+		return nil
+	}
+
+	fmt.Printf("Found %d decisions due for review:\n\n", len(due))
+	for _, dd := range due {
+		d := dd.Decision
+		fmt.Printf("%s [%s] %s\n", bold(d.ID), colorType(string(d.Type)), dueLabel(dd))
+		fmt.Printf("  %s %s\n", dim("Problem:"), d.Problem)
+		fmt.Printf("  %s %s\n", dim("Choice:"), d.Choice)
+		if d.ReviewBy != nil {
+			fmt.Printf("  %s %s\n", dim("Review by:"), *d.ReviewBy)
+		}
+		if d.ExpiresAt != nil {
+			fmt.Printf("  %s %s\n", dim("Expires:"), *d.ExpiresAt)
+		}
+		fmt.Println()
+	}
+
+	return nil
+}
+
+func dueLabel(dd DueDecision) string {
+	switch {
+	case dd.Expired:
+		return red("expired")
+	case dd.ReviewDue:
+		return yellow("review due")
+	default:
+		return dim("due soon")
+	}
+}
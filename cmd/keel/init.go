@@ -3,19 +3,29 @@ package main
 import (
 	"fmt"
 	"os"
-	"path/filepath"
+	"path"
+	"strings"
+	"time"
 
 	"github.com/spf13/cobra"
+	"github.com/tyroneavnit/keel/internal/errs"
+	"github.com/tyroneavnit/keel/internal/git"
 	"github.com/tyroneavnit/keel/internal/store"
 )
 
 var initCmd = &cobra.Command{
-	Use:   "init",
+	Use:   "init [dir]",
 	Short: "Initialize Keel in the current repository",
 	Long: `Initialize Keel decision tracking in the current git repository.
 
 This creates the .keel/ directory and sets up the decision ledger.
-This command should be run once by a human, not by agents.`,
+This command should be run once by a human, not by agents.
+
+With a directory, adds a nested ledger there for a team or service in a
+monorepo. Decisions recorded below the directory go to it, and context for
+its files merges it with the ledgers above. The root must be initialized
+first; its config and policy apply to every ledger.`,
+	Args: cobra.MaximumNArgs(1),
 	RunE: runInit,
 }
 
@@ -24,9 +34,10 @@ func init() {
 }
 
 func runInit(cmd *cobra.Command, args []string) error {
-	repoRoot, err := os.Getwd()
-	if err != nil {
-		return err
+	if len(args) == 1 {
+		if ledger := ledgerPath(args[0]); ledger != store.RootLedger {
+			return initNestedLedger(ledger)
+		}
 	}
 
 	// Check if already initialized
@@ -36,33 +47,73 @@ func runInit(cmd *cobra.Command, args []string) error {
 		return nil
 	}
 
-	// Check if this is a git repo
-	gitDir := filepath.Join(repoRoot, ".git")
-	if _, err := os.Stat(gitDir); os.IsNotExist(err) {
+	// Check if this is a git repo. In worktrees and submodules .git is a
+	// file, so ask git rather than looking for the directory.
+	if !git.IsRepo(repoRoot) {
 		return fmt.Errorf("not a git repository. Run 'git init' first")
 	}
 
-	// Create .keel directory
-	if err := store.EnsureKeelDir(repoRoot); err != nil {
-		return fmt.Errorf("failed to create .keel directory: %w", err)
-	}
-
-	// Create empty decisions.jsonl
-	decisionsPath := store.GetDecisionsPath(repoRoot)
-	f, err := os.Create(decisionsPath)
-	if err != nil {
-		return fmt.Errorf("failed to create decisions file: %w", err)
+	if err := createLedger(repoRoot); err != nil {
+		return err
 	}
-	f.Close()
 
-	fmt.Println("\033[32m✓ Keel initialized\033[0m")
+	fmt.Println(green("✓ Keel initialized"))
 	fmt.Println()
 	fmt.Println("Created .keel/ directory with empty decision ledger.")
 	fmt.Println()
 	fmt.Println("Next steps:")
-	fmt.Println("  1. Add '.keel/index.sqlite' to .gitignore")
+	fmt.Println("  1. Add '.keel/index.sqlite' and '.keel/cache/' to .gitignore")
 	fmt.Println("  2. Commit '.keel/decisions.jsonl' to git")
 	fmt.Println("  3. Record your first decision: keel decide --type product ...")
 
 	return nil
 }
+
+// initNestedLedger creates a ledger in a repo-relative directory below the root
+func initNestedLedger(ledger string) error {
+	if err := store.RequireInit(repoRoot); err != nil {
+		return err
+	}
+	if ledger == ".." || strings.HasPrefix(ledger, "../") || path.IsAbs(ledger) {
+		return errs.New(errs.Usage, "%s is outside the repository", ledger)
+	}
+	dir := store.LedgerDir(repoRoot, ledger)
+	if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+		return errs.New(errs.Usage, "%s is not a directory", ledger)
+	}
+	if store.IsInitialized(dir) {
+		fmt.Printf("%s already has a ledger.\n", ledger)
+		return nil
+	}
+
+	if err := createLedger(dir); err != nil {
+		return err
+	}
+	// Indexes list ledgers again when the root directory changes; touch it
+	// so they find this one before it is added to git
+	now := time.Now()
+	if err := os.Chtimes(repoRoot, now, now); err != nil {
+		return fmt.Errorf("failed to update repository root: %w", err)
+	}
+
+	fmt.Println(green("✓ Ledger created in " + ledger))
+	fmt.Println()
+	fmt.Printf("Decisions recorded under %s/ now go to %s/.keel/decisions.jsonl.\n", ledger, ledger)
+	fmt.Println("Commit it to git; the index at the repository root covers it.")
+	return nil
+}
+
+// createLedger creates the .keel directory and an empty ledger in dir
+func createLedger(dir string) error {
+	if err := store.EnsureKeelDir(dir); err != nil {
+		return fmt.Errorf("failed to create .keel directory: %w", err)
+	}
+
+	// Create empty decisions.jsonl
+	decisionsPath := store.GetDecisionsPath(dir)
+	f, err := os.Create(decisionsPath)
+	if err != nil {
+		return fmt.Errorf("failed to create decisions file: %w", err)
+	}
+	return f.Close()
+}
@@ -0,0 +1,330 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/tyroneavnit/keel/internal/errs"
+	"github.com/tyroneavnit/keel/internal/lsp"
+	"github.com/tyroneavnit/keel/internal/store"
+	"github.com/tyroneavnit/keel/internal/symbols"
+	"github.com/tyroneavnit/keel/internal/types"
+	"github.com/tyroneavnit/keel/pkg/keel"
+)
+
+var lspCmd = &cobra.Command{
+	Use:   "lsp",
+	Short: "Serve decision hovers, code lenses and diagnostics to editors (LSP over stdio)",
+	Long: `Run a Language Server Protocol server on stdin/stdout.
+
+  Hover             Decisions linked to the line, enclosing symbol and file;
+                    on a DEC-xxxx token, that decision
+  Code lens         "N decisions" above the file, each linked symbol and
+                    each anchored range
+  Go to definition  On a DEC-xxxx token, jumps to its line in decisions.jsonl
+  Diagnostics       Warnings on DEC-xxxx references to superseded decisions
+
+Symbols and anchors are read from the saved file. Point your editor's
+generic LSP client at "keel lsp" for all file types, e.g. in Neovim:
+
+  vim.lsp.start({ name = "keel", cmd = { "keel", "lsp" }, root_dir = vim.fn.getcwd() })`,
+	Args: cobra.NoArgs,
+	RunE: runLSP,
+}
+
+func init() {
+	rootCmd.AddCommand(lspCmd)
+}
+
+// showDecisionsCommand is attached to code lenses. Editors that don't
+// implement it still show the lens title.
+const showDecisionsCommand = "keel.showDecisions"
+
+func runLSP(cmd *cobra.Command, args []string) error {
+	l, err := openLedger(cmd)
+	if err != nil {
+		return err
+	}
+	defer l.Close()
+
+	ls := &languageServer{ctx: cmd.Context(), ledger: l}
+	server := &lsp.Server{
+		Name:        "keel",
+		Version:     version,
+		Hover:       ls.hover,
+		Definition:  ls.definition,
+		CodeLenses:  ls.codeLenses,
+		Diagnostics: ls.diagnostics,
+		BeforeRequest: func() error {
+			// Source files may have changed since the last request
+			return l.Refresh(ls.ctx)
+		},
+	}
+	return server.Serve(os.Stdin, os.Stdout)
+}
+
+type languageServer struct {
+	ctx    context.Context
+	ledger *keel.Ledger
+}
+
+func (ls *languageServer) hover(doc *lsp.Document, pos lsp.Position) (string, error) {
+	if ref := ls.idAt(doc, pos); ref != "" {
+		d, err := ls.decision(ref)
+		if err != nil || d == nil {
+			return "", err
+		}
+		return decisionMarkdown(d), nil
+	}
+
+	rel := ls.relPath(doc)
+	if rel == "" {
+		return "", nil
+	}
+
+	location := fmt.Sprintf("%s:%d", rel, pos.Line+1)
+	res, err := ls.ledger.Context(ls.ctx, keel.ContextRequest{SymbolAt: location})
+	if err != nil {
+		// A file that doesn't parse yet has no enclosing symbol, but its
+		// file and anchored decisions still apply
+		res, err = ls.ledger.Context(ls.ctx, keel.ContextRequest{Paths: []string{location}})
+		if err != nil {
+			return "", err
+		}
+	}
+	if len(res.Decisions) == 0 {
+		return "", nil
+	}
+
+	// Constraints apply everywhere, so they would crowd every hover
+	res.Constraints = nil
+	return res.Prompt(0), nil
+}
+
+func (ls *languageServer) definition(doc *lsp.Document, pos lsp.Position) (*lsp.Location, error) {
+	ref := ls.idAt(doc, pos)
+	if ref == "" {
+		return nil, nil
+	}
+
+	d, err := ls.decision(ref)
+	if err != nil || d == nil {
+		return nil, err
+	}
+	path := store.GetDecisionsPath(store.LedgerDir(ls.ledger.Root(), d.Ledger))
+	line, err := ledgerLine(path, ref)
+	if err != nil || line < 0 {
+		return nil, err
+	}
+	at := lsp.Position{Line: line}
+	return &lsp.Location{URI: lsp.PathToURI(path), Range: lsp.Range{Start: at, End: at}}, nil
+}
+
+func (ls *languageServer) codeLenses(doc *lsp.Document) ([]lsp.CodeLens, error) {
+	rel := ls.relPath(doc)
+	if rel == "" {
+		return nil, nil
+	}
+
+	decisions, err := ls.ledger.List(ls.ctx, keel.Filter{File: rel, Status: string(keel.StatusActive)})
+	if err != nil {
+		return nil, err
+	}
+
+	var lenses []lsp.CodeLens
+	if len(decisions) > 0 {
+		lenses = append(lenses, decisionsLens(0, decisions))
+	}
+
+	if extractor := symbols.ExtractorFor(rel); extractor != nil {
+		// A file that doesn't parse yet keeps its file-level lens
+		syms, _ := extractor.Extract(ls.ledger.Root(), rel)
+		for _, sym := range syms {
+			linked, err := ls.ledger.List(ls.ctx, keel.Filter{Symbol: sym.Name, Status: string(keel.StatusActive)})
+			if err != nil {
+				return nil, err
+			}
+			if len(linked) > 0 {
+				lenses = append(lenses, decisionsLens(sym.Line-1, linked))
+			}
+		}
+	}
+
+	for _, d := range decisions {
+		for _, a := range d.Anchors {
+			if a.File != rel {
+				continue
+			}
+			lenses = append(lenses, lsp.CodeLens{
+				Range: lineRange(a.StartLine - 1),
+				Command: &lsp.Command{
+					Title:     fmt.Sprintf("%s: %s", d.ID, truncate(d.Choice, 60)),
+					Command:   showDecisionsCommand,
+					Arguments: []interface{}{[]string{d.ID}},
+				},
+			})
+		}
+	}
+	return lenses, nil
+}
+
+func (ls *languageServer) diagnostics(doc *lsp.Document) ([]lsp.Diagnostic, error) {
+	// Ledgers themselves legitimately mention superseded decisions
+	if rel := ls.relPath(doc); strings.Contains("/"+rel, "/"+store.KeelDir+"/") {
+		return nil, nil
+	}
+
+	ids := ls.ledger.Config().IDFormat()
+	pattern := ids.Pattern()
+	seen := make(map[string]*types.Decision)
+	var diagnostics []lsp.Diagnostic
+	for n, line := range strings.Split(doc.Text, "\n") {
+		for _, loc := range pattern.FindAllStringIndex(line, -1) {
+			ref, err := ids.Normalize(line[loc[0]:loc[1]])
+			if err != nil {
+				continue
+			}
+			d, ok := seen[ref]
+			if !ok {
+				if d, err = ls.decision(ref); err != nil {
+					return nil, err
+				}
+				seen[ref] = d
+			}
+			if d == nil || d.Status != types.StatusSuperseded {
+				continue
+			}
+
+			message := fmt.Sprintf("%s is superseded", ref)
+			if d.SupersededBy != nil {
+				message = fmt.Sprintf("%s is superseded by %s", ref, *d.SupersededBy)
+			}
+			diagnostics = append(diagnostics, lsp.Diagnostic{
+				Range: lsp.Range{
+					Start: lsp.Position{Line: n, Character: lsp.Character(line, loc[0])},
+					End:   lsp.Position{Line: n, Character: lsp.Character(line, loc[1])},
+				},
+				Severity: lsp.SeverityWarning,
+				Source:   "keel",
+				Message:  message,
+			})
+		}
+	}
+	return diagnostics, nil
+}
+
+// relPath returns the document's repo-relative slash path, or "" when it
+// lies outside the repository
+func (ls *languageServer) relPath(doc *lsp.Document) string {
+	path := doc.Path()
+	if path == "" {
+		return ""
+	}
+	rel, err := filepath.Rel(ls.ledger.Root(), path)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return ""
+	}
+	return filepath.ToSlash(rel)
+}
+
+// decision returns a decision by ID, or nil when the ledger has no such decision
+func (ls *languageServer) decision(ref string) (*types.Decision, error) {
+	d, err := ls.ledger.Get(ls.ctx, ref)
+	if errs.KindOf(err) == errs.NotFound {
+		return nil, nil
+	}
+	return d, err
+}
+
+// idAt returns the normalized decision ID under the cursor, or ""
+func (ls *languageServer) idAt(doc *lsp.Document, pos lsp.Position) string {
+	ids := ls.ledger.Config().IDFormat()
+	line := doc.Line(pos.Line)
+	offset := lsp.ByteOffset(line, pos.Character)
+	for _, loc := range ids.Pattern().FindAllStringIndex(line, -1) {
+		if loc[0] <= offset && offset <= loc[1] {
+			if ref, err := ids.Normalize(line[loc[0]:loc[1]]); err == nil {
+				return ref
+			}
+		}
+	}
+	return ""
+}
+
+// ledgerLine returns the zero-based line of decisions.jsonl that first
+// records a decision, or -1
+func ledgerLine(path, decisionID string) (int, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return -1, err
+	}
+	defer f.Close()
+
+	needle := fmt.Sprintf(`"id":%q`, decisionID)
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for n := 0; scanner.Scan(); n++ {
+		if strings.Contains(scanner.Text(), needle) {
+			return n, nil
+		}
+	}
+	return -1, scanner.Err()
+}
+
+func decisionsLens(line int, decisions []*types.Decision) lsp.CodeLens {
+	ids := make([]string, len(decisions))
+	for i, d := range decisions {
+		ids[i] = d.ID
+	}
+	title := fmt.Sprintf("%d decisions", len(decisions))
+	if len(decisions) == 1 {
+		title = fmt.Sprintf("1 decision: %s", truncate(decisions[0].Choice, 60))
+	}
+	return lsp.CodeLens{
+		Range:   lineRange(line),
+		Command: &lsp.Command{Title: title, Command: showDecisionsCommand, Arguments: []interface{}{ids}},
+	}
+}
+
+// decisionMarkdown renders one decision for a hover
+func decisionMarkdown(d *types.Decision) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "**%s** (%s, %s) %s\n\n", d.ID, d.Type, d.Status, d.Choice)
+	fmt.Fprintf(&b, "**Problem:** %s\n\n", d.Problem)
+	if d.Rationale != nil && *d.Rationale != "" {
+		fmt.Fprintf(&b, "**Why:** %s\n\n", *d.Rationale)
+	}
+	if d.SupersededBy != nil {
+		fmt.Fprintf(&b, "Superseded by %s\n\n", *d.SupersededBy)
+	}
+	if d.Supersedes != nil {
+		fmt.Fprintf(&b, "Supersedes %s\n\n", *d.Supersedes)
+	}
+	author := d.DecidedBy.Role
+	if d.DecidedBy.Identifier != nil {
+		author = *d.DecidedBy.Identifier
+	}
+	fmt.Fprintf(&b, "_Decided by %s on %s_", author, strings.SplitN(d.CreatedAt, "T", 2)[0])
+	return b.String()
+}
+
+func lineRange(line int) lsp.Range {
+	if line < 0 {
+		line = 0
+	}
+	at := lsp.Position{Line: line}
+	return lsp.Range{Start: at, End: at}
+}
+
+func truncate(s string, n int) string {
+	runes := []rune(strings.Join(strings.Fields(s), " "))
+	if len(runes) <= n {
+		return string(runes)
+	}
+	return string(runes[:n-1]) + "…"
+}
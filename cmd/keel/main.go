@@ -1,14 +1,35 @@
 package main
 
 import (
+	"encoding/json"
 	"fmt"
 	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
 
 	"github.com/spf13/cobra"
+	"github.com/tyroneavnit/keel/internal/config"
+	"github.com/tyroneavnit/keel/internal/errs"
+	"github.com/tyroneavnit/keel/internal/store"
+	"github.com/tyroneavnit/keel/pkg/keel"
 )
 
 var version = "0.1.0"
 
+// cfg is the repository config, loaded before every command runs
+var cfg = config.Default()
+
+// repoRoot is the repository keel works on, found before every command runs
+var repoRoot string
+
+// repoFlag overrides repository discovery
+var repoFlag string
+
+// ledgerDir is the repo-relative ledger new decisions are written to: the
+// nearest one above the working directory or --repo
+var ledgerDir string
+
 var rootCmd = &cobra.Command{
 	Use:   "keel",
 	Short: "Git-native decision ledger CLI",
@@ -16,12 +37,188 @@ var rootCmd = &cobra.Command{
 
 Built to be called by LLM-based coding agents (Claude, GPT, Codex, etc).
 Keel provides the data and storage - your agent does the thinking.`,
-	Version: version,
+	Version:           version,
+	PersistentPreRunE: loadConfig,
+
+	// main reports errors itself, as text or JSON
+	SilenceErrors: true,
+	SilenceUsage:  true,
+}
+
+func init() {
+	rootCmd.PersistentFlags().StringVar(&repoFlag, "repo", "", "Repository root (default: found from the working directory, or $KEEL_DIR)")
+	rootCmd.SetFlagErrorFunc(func(cmd *cobra.Command, err error) error {
+		return &errs.Error{Kind: errs.Usage, Message: err.Error(), Err: err}
+	})
 }
 
 func main() {
-	if err := rootCmd.Execute(); err != nil {
-		fmt.Fprintln(os.Stderr, err)
-		os.Exit(1)
+	markUsageErrors(rootCmd)
+	cmd, err := rootCmd.ExecuteC()
+	if err == nil {
+		return
+	}
+	reportError(cmd, err)
+	os.Exit(errs.ExitCode(err))
+}
+
+// markUsageErrors classifies argument validation failures, like a missing
+// decision ID, as usage errors
+func markUsageErrors(cmd *cobra.Command) {
+	if validate := cmd.Args; validate != nil {
+		cmd.Args = func(cmd *cobra.Command, args []string) error {
+			if err := validate(cmd, args); err != nil {
+				return &errs.Error{Kind: errs.Usage, Message: err.Error(), Err: err}
+			}
+			return nil
+		}
+	}
+	for _, sub := range cmd.Commands() {
+		markUsageErrors(sub)
+	}
+}
+
+// ErrorOutput is the JSON error object written to stderr when a command
+// run with --json fails
+type ErrorOutput struct {
+	Error struct {
+		Code     errs.Kind `json:"code"`
+		Message  string    `json:"message"`
+		ExitCode int       `json:"exit_code"`
+	} `json:"error"`
+}
+
+// reportError prints a failed command's error to stderr: as a JSON object
+// when the command was asked for JSON output, otherwise as text unless the
+// command already showed the failure
+func reportError(cmd *cobra.Command, err error) {
+	if wantsJSON(cmd) {
+		var out ErrorOutput
+		out.Error.Code = errs.KindOf(err)
+		out.Error.Message = err.Error()
+		out.Error.ExitCode = errs.ExitCode(err)
+		data, _ := json.Marshal(out)
+		fmt.Fprintln(os.Stderr, string(data))
+		return
+	}
+
+	if errs.IsReported(err) {
+		return
+	}
+	fmt.Fprintln(os.Stderr, "Error:", err)
+	if errs.KindOf(err) == errs.Usage {
+		fmt.Fprintf(os.Stderr, "Run '%s --help' for usage.\n", cmd.CommandPath())
+	}
+}
+
+// wantsJSON reports whether a command was run with --json (or --format json)
+func wantsJSON(cmd *cobra.Command) bool {
+	if cmd == nil {
+		return false
+	}
+	if flag := cmd.Flags().Lookup("json"); flag != nil && flag.Value.String() == "true" {
+		return true
+	}
+	if flag := cmd.Flags().Lookup("format"); flag != nil && flag.Value.String() == "json" {
+		return true
+	}
+	return false
+}
+
+// loadConfig finds the repository, then reads .keel/config.yaml and applies
+// it to the command about to run
+func loadConfig(cmd *cobra.Command, args []string) error {
+	root, err := store.ResolveRoot(repoFlag)
+	if err != nil {
+		return err
+	}
+	repoRoot = root
+	if ledgerDir, err = store.CurrentLedger(repoFlag, repoRoot); err != nil {
+		return err
+	}
+
+	loaded, err := config.Load(repoRoot)
+	if err != nil {
+		return err
+	}
+	cfg = loaded
+	colorEnabled = cfg.ColorEnabled(isTerminal(os.Stdout))
+
+	// output.format: json makes --json the default for commands that support it
+	if flag := cmd.Flags().Lookup("json"); flag != nil && !flag.Changed && cfg.Output.Format == "json" {
+		if err := flag.Value.Set("true"); err != nil {
+			return err
+		}
+	}
+
+	// defaults.* fill in the filters of listing commands run without them
+	if cmd.Annotations[usesDefaults] != "" {
+		for name, value := range cfg.Defaults.Flags() {
+			if flag := cmd.Flags().Lookup(name); flag != nil && !flag.Changed {
+				if err := flag.Value.Set(value); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// usesDefaults marks commands whose filter flags take defaults.* from the config
+const usesDefaults = "keel:defaults"
+
+// openLedger opens the repository's ledger with the config loaded for the command
+// Commands that read history take --as-of.
+func openLedger(cmd *cobra.Command) (*keel.Ledger, error) {
+	opts := []keel.Option{keel.WithRepoRoot(repoRoot), keel.WithLedger(ledgerDir), keel.WithConfig(cfg)}
+	if flag := cmd.Flags().Lookup("as-of"); flag != nil && flag.Value.String() != "" {
+		opts = append(opts, keel.WithAsOf(flag.Value.String()))
+	}
+	return keel.Open(cmd.Context(), opts...)
+}
+
+// locationSuffix matches the :line or :start-end that may follow a path
+var locationSuffix = regexp.MustCompile(`:\d+(-\d+)?$`)
+
+// repoPath converts a path from the command line, which may end in :line or
+// :start-end, to the repo-relative slash path the ledger records. Paths are
+// relative to the working directory. One that doesn't exist there and isn't
+// written as ./ or ../ is taken to be repo-relative already, so symbol names,
+// globs and deleted files pass through unchanged.
+func repoPath(arg string) string {
+	path, suffix := arg, ""
+	if loc := locationSuffix.FindStringIndex(arg); loc != nil {
+		path, suffix = arg[:loc[0]], arg[loc[0]:]
+	}
+
+	slashed := filepath.ToSlash(path)
+	explicit := filepath.IsAbs(path) || slashed == "." || slashed == ".." ||
+		strings.HasPrefix(slashed, "./") || strings.HasPrefix(slashed, "../")
+	if _, err := os.Stat(path); err != nil && !explicit {
+		return arg
+	}
+
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return arg
+	}
+	// Match repoRoot, whose symlinks are resolved, without following a
+	// symlinked file to its target
+	if dir, err := filepath.EvalSymlinks(filepath.Dir(abs)); err == nil {
+		abs = filepath.Join(dir, filepath.Base(abs))
+	}
+	rel, err := filepath.Rel(repoRoot, abs)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return arg
+	}
+	return filepath.ToSlash(rel) + suffix
+}
+
+// repoPaths converts each path with repoPath
+func repoPaths(args []string) []string {
+	paths := make([]string, len(args))
+	for i, arg := range args {
+		paths[i] = repoPath(arg)
 	}
+	return paths
 }
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"github.com/tyroneavnit/keel/internal/errs"
+	"github.com/tyroneavnit/keel/internal/store"
+)
+
+func TestMain(m *testing.M) {
+	markUsageErrors(rootCmd)
+	os.Exit(m.Run())
+}
+
+// initLedger creates a repository whose ledger holds lines
+func initLedger(t *testing.T, lines ...string) string {
+	t.Helper()
+	root, err := filepath.EvalSymlinks(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := store.EnsureKeelDir(root); err != nil {
+		t.Fatal(err)
+	}
+	var ledger string
+	for _, line := range lines {
+		ledger += line + "\n"
+	}
+	if err := os.WriteFile(store.GetDecisionsPath(root), []byte(ledger), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return root
+}
+
+// execute runs keel in root as main does, with every flag back at its
+// default first, and returns the command that ran and its error
+func execute(t *testing.T, root string, args ...string) (*cobra.Command, error) {
+	t.Helper()
+	resetFlags(rootCmd)
+	rootCmd.SetArgs(append([]string{"--repo", root}, args...))
+	return rootCmd.ExecuteC()
+}
+
+func resetFlags(cmd *cobra.Command) {
+	reset := func(f *pflag.Flag) {
+		if s, ok := f.Value.(pflag.SliceValue); ok {
+			s.Replace(nil)
+		} else {
+			f.Value.Set(f.DefValue)
+		}
+		f.Changed = false
+	}
+	cmd.Flags().VisitAll(reset)
+	cmd.PersistentFlags().VisitAll(reset)
+	for _, sub := range cmd.Commands() {
+		resetFlags(sub)
+	}
+}
+
+// captureStdout returns what fn writes to standard output
+func captureStdout(t *testing.T, fn func()) string {
+	t.Helper()
+	return capture(t, &os.Stdout, fn)
+}
+
+func capture(t *testing.T, file **os.File, fn func()) string {
+	t.Helper()
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	saved := *file
+	*file = w
+	done := make(chan string)
+	go func() {
+		data, _ := io.ReadAll(r)
+		done <- string(data)
+	}()
+	defer func() { *file = saved }()
+	fn()
+	w.Close()
+	return <-done
+}
+
+func TestUsageExitCodes(t *testing.T) {
+	root := initLedger(t)
+
+	tests := []struct {
+		name string
+		args []string
+		want int
+	}{
+		{name: "context format", args: []string{"context", "--format", "xml", "main.go"}, want: 2},
+		{name: "context budget", args: []string{"context", "--budget", "-1", "main.go"}, want: 2},
+		{name: "context without paths", args: []string{"context"}, want: 2},
+		{name: "batch with ref", args: []string{"context", "--git-staged", "--ref", "JIRA-1"}, want: 2},
+		{name: "follow without ref", args: []string{"context", "--follow", "main.go"}, want: 2},
+		{name: "git range option", args: []string{"context", "--git-diff", "--output=x"}, want: 2},
+		{name: "symbol location", args: []string{"context", "--symbol-at", "main.go"}, want: 2},
+		{name: "symbol line", args: []string{"context", "--symbol-at", "main.go:0"}, want: 2},
+		{name: "diff format", args: []string{"diff", "--format", "yaml"}, want: 2},
+		{name: "unknown flag", args: []string{"context", "--nope"}, want: 2},
+		{name: "context", args: []string{"context", "main.go"}, want: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var err error
+			captureStdout(t, func() { _, err = execute(t, root, tt.args...) })
+			if got := errs.ExitCode(err); got != tt.want {
+				t.Errorf("exit code = %d (%v), want %d", got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestJSONErrorOutput(t *testing.T) {
+	root := initLedger(t)
+	var cmd *cobra.Command
+	var err error
+	captureStdout(t, func() { cmd, err = execute(t, root, "context", "--json", "--follow", "main.go") })
+	out := capture(t, &os.Stderr, func() { reportError(cmd, err) })
+
+	var got ErrorOutput
+	if err := json.Unmarshal([]byte(out), &got); err != nil {
+		t.Fatalf("stderr is not a JSON error: %q", out)
+	}
+	if got.Error.Code != errs.Usage || got.Error.ExitCode != 2 || !strings.Contains(got.Error.Message, "--follow") {
+		t.Errorf("error = %+v, want code usage and exit code 2", got.Error)
+	}
+}
@@ -0,0 +1,345 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/tyroneavnit/keel/internal/errs"
+	"github.com/tyroneavnit/keel/internal/mcp"
+	"github.com/tyroneavnit/keel/pkg/keel"
+)
+
+var mcpCmd = &cobra.Command{
+	Use:   "mcp",
+	Short: "Serve keel to agents over the Model Context Protocol (stdio)",
+	Long: `Run an MCP server on stdin/stdout so agents can call keel as tools instead of
+spawning the CLI and parsing its output.
+
+Tools:    context, search, why, decide, supersede, sql
+Resource: keel://constraints (active constraints), keel://decisions/<id>
+
+The index stays open for the life of the server and is refreshed whenever
+.keel/decisions.jsonl changes. Decisions recorded through the server are
+marked as agent decisions.
+
+Example client configuration:
+  {"mcpServers": {"keel": {"command": "keel", "args": ["mcp"]}}}`,
+	Args: cobra.NoArgs,
+	RunE: runMCP,
+}
+
+func init() {
+	rootCmd.AddCommand(mcpCmd)
+}
+
+const (
+	constraintsURI    = "keel://constraints"
+	decisionURIPrefix = "keel://decisions/"
+)
+
+func runMCP(cmd *cobra.Command, args []string) error {
+	l, err := openLedger(cmd)
+	if err != nil {
+		return err
+	}
+	defer l.Close()
+
+	ctx := cmd.Context()
+	server := &mcp.Server{
+		Name:    "keel",
+		Version: version,
+		Tools:   mcpTools(ctx, l),
+		ListResources: func() ([]mcp.Resource, error) {
+			return mcpResources(ctx, l)
+		},
+		ReadResource: func(uri string) (string, string, error) {
+			return readMCPResource(ctx, l, uri)
+		},
+		BeforeRequest: func() error {
+			return l.Refresh(ctx)
+		},
+	}
+	return server.Serve(os.Stdin, os.Stdout)
+}
+
+func mcpTools(ctx context.Context, l *keel.Ledger) []mcp.Tool {
+	return []mcp.Tool{
+		{
+			Name: "context",
+			Description: "Get the decisions and active constraints that apply to files, a line, a symbol or an external reference. " +
+				"Call before changing code. Paths may be file or file:line.",
+			InputSchema: objectSchema(map[string]interface{}{
+				"paths":     arrayProp("Repo-relative file paths, optionally file:line"),
+				"ref":       stringProp("External reference (issue, epic) instead of paths"),
+				"symbol_at": stringProp("file:line whose enclosing function, method or type to look up"),
+				"author":    stringProp("Only decisions made by this identifier"),
+				"format":    enumProp("prompt (ranked Markdown, default) or json", "prompt", "json"),
+				"budget":    intProp("Approximate token budget for the prompt format (0 = unlimited)"),
+			}),
+			Handler: func(raw json.RawMessage) (string, error) {
+				var args struct {
+					Paths    []string `json:"paths"`
+					Ref      string   `json:"ref"`
+					SymbolAt string   `json:"symbol_at"`
+					Author   string   `json:"author"`
+					Format   string   `json:"format"`
+					Budget   int      `json:"budget"`
+				}
+				if err := decodeArgs(raw, &args); err != nil {
+					return "", err
+				}
+				if len(args.Paths) == 0 && args.Ref == "" && args.SymbolAt == "" {
+					return "", errs.New(errs.Usage, "must provide paths, ref or symbol_at")
+				}
+				res, err := l.Context(ctx, keel.ContextRequest{
+					Paths:    args.Paths,
+					Ref:      args.Ref,
+					SymbolAt: args.SymbolAt,
+					Author:   args.Author,
+				})
+				if err != nil {
+					return "", err
+				}
+				switch args.Format {
+				case "", "prompt":
+					return res.Prompt(args.Budget), nil
+				case "json":
+					return toJSON(contextOutput(res))
+				}
+				return "", errs.New(errs.Usage, "invalid format: %s. Must be one of: prompt, json", args.Format)
+			},
+		},
+		{
+			Name:        "search",
+			Description: "Full-text search over decision problems, choices and rationales, with optional type, status and author filters.",
+			InputSchema: objectSchema(map[string]interface{}{
+				"text":   stringProp("Words to search for; empty lists by filters alone"),
+				"type":   stringProp("Decision type, e.g. constraint"),
+				"status": enumProp("active (default), superseded or all", "active", "superseded", "all"),
+				"author": stringProp("Only decisions made by this identifier"),
+				"limit":  intProp("Maximum number of results"),
+			}),
+			Handler: func(raw json.RawMessage) (string, error) {
+				var args struct {
+					Text   string `json:"text"`
+					Type   string `json:"type"`
+					Status string `json:"status"`
+					Author string `json:"author"`
+					Limit  int    `json:"limit"`
+				}
+				if err := decodeArgs(raw, &args); err != nil {
+					return "", err
+				}
+				if args.Status == "" {
+					args.Status = string(keel.StatusActive)
+				}
+				decisions, err := l.Search(ctx, args.Text, keel.Filter{
+					Type:   args.Type,
+					Status: args.Status,
+					Author: args.Author,
+					Limit:  args.Limit,
+				})
+				if err != nil {
+					return "", err
+				}
+				return toJSON(nonNil(decisions))
+			},
+		},
+		{
+			Name:        "why",
+			Description: "Show the full record of a decision by ID.",
+			InputSchema: objectSchema(map[string]interface{}{
+				"id": stringProp("Decision ID, e.g. DEC-a1b2"),
+			}, "id"),
+			Handler: func(raw json.RawMessage) (string, error) {
+				var args struct {
+					ID string `json:"id"`
+				}
+				if err := decodeArgs(raw, &args); err != nil {
+					return "", err
+				}
+				d, err := l.Get(ctx, args.ID)
+				if err != nil {
+					return "", err
+				}
+				return toJSON(d)
+			},
+		},
+		{
+			Name:        "decide",
+			Description: "Record a new decision in the ledger. Recorded as an agent decision.",
+			InputSchema: objectSchema(map[string]interface{}{
+				"type":       stringProp("product, process, constraint, learning, or a configured type"),
+				"problem":    stringProp("What problem this addresses"),
+				"choice":     stringProp("What was decided"),
+				"rationale":  stringProp("Why this choice was made"),
+				"files":      arrayProp("Affected files"),
+				"symbols":    arrayProp("Affected symbols, e.g. billing.(*Invoice).Total"),
+				"anchors":    arrayProp("Line ranges the decision applies to, as file:start-end"),
+				"refs":       arrayProp("External references (issues, epics)"),
+				"supersedes": stringProp("ID of a decision this replaces"),
+				"review_by":  stringProp("Date to revisit (YYYY-MM-DD or RFC3339)"),
+				"expires_at": stringProp("Date the decision stops applying (YYYY-MM-DD or RFC3339)"),
+				"as":         stringProp("Identifier of the agent (defaults to $KEEL_AGENT)"),
+			}, "type", "problem", "choice"),
+			Handler: func(raw json.RawMessage) (string, error) {
+				var req keel.DecisionRequest
+				if err := decodeArgs(raw, &req); err != nil {
+					return "", err
+				}
+				req.Agent = true
+				d, err := l.Decide(ctx, req)
+				if err != nil {
+					return "", err
+				}
+				return toJSON(d)
+			},
+		},
+		{
+			Name:        "supersede",
+			Description: "Replace a decision with a new one. The original is marked superseded. Its type, problem, symbols and refs are kept unless given; its files and anchors are kept unless either is given.",
+			InputSchema: objectSchema(map[string]interface{}{
+				"id":         stringProp("ID of the decision to replace"),
+				"choice":     stringProp("The new choice"),
+				"type":       stringProp("New decision type"),
+				"problem":    stringProp("New problem statement"),
+				"rationale":  stringProp("Why this supersedes the original"),
+				"files":      arrayProp("Affected files"),
+				"symbols":    arrayProp("Affected symbols, e.g. billing.(*Invoice).Total"),
+				"anchors":    arrayProp("Line ranges the decision applies to, as file:start-end"),
+				"refs":       arrayProp("External references"),
+				"review_by":  stringProp("Date to revisit (YYYY-MM-DD or RFC3339)"),
+				"expires_at": stringProp("Date the decision stops applying (YYYY-MM-DD or RFC3339)"),
+				"as":         stringProp("Identifier of the agent (defaults to $KEEL_AGENT)"),
+			}, "id", "choice"),
+			Handler: func(raw json.RawMessage) (string, error) {
+				var args supersedeRequest
+				if err := decodeArgs(raw, &args); err != nil {
+					return "", err
+				}
+				args.Agent = true
+				d, err := l.Supersede(ctx, args.ID, args.DecisionRequest)
+				if err != nil {
+					return "", err
+				}
+				return toJSON(d)
+			},
+		},
+		{
+			Name: "sql",
+			Description: "Run a read-only SQL query against the decision index. Tables: decisions, decision_files, " +
+				"decision_anchors, decision_annotations, decision_refs, decision_symbols.",
+			InputSchema: objectSchema(map[string]interface{}{
+				"query": stringProp("A SELECT statement"),
+			}, "query"),
+			Handler: func(raw json.RawMessage) (string, error) {
+				var args struct {
+					Query string `json:"query"`
+				}
+				if err := decodeArgs(raw, &args); err != nil {
+					return "", err
+				}
+				result, err := l.Query(ctx, args.Query)
+				if err != nil {
+					return "", err
+				}
+				if result.Rows == nil {
+					result.Rows = []map[string]interface{}{}
+				}
+				return toJSON(result.Rows)
+			},
+		},
+	}
+}
+
+// mcpResources lists the active constraints as one resource, and each individually
+func mcpResources(ctx context.Context, l *keel.Ledger) ([]mcp.Resource, error) {
+	constraints, err := l.Constraints(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	resources := []mcp.Resource{{
+		URI:         constraintsURI,
+		Name:        "Active constraints",
+		Description: "Hard limits that apply to every change in this repository",
+		MimeType:    "application/json",
+	}}
+	for _, c := range constraints {
+		resources = append(resources, mcp.Resource{
+			URI:         decisionURIPrefix + c.ID,
+			Name:        fmt.Sprintf("%s: %s", c.ID, c.Choice),
+			Description: c.Problem,
+			MimeType:    "application/json",
+		})
+	}
+	return resources, nil
+}
+
+func readMCPResource(ctx context.Context, l *keel.Ledger, uri string) (string, string, error) {
+	if uri == constraintsURI {
+		constraints, err := l.Constraints(ctx)
+		if err != nil {
+			return "", "", err
+		}
+		text, err := toJSON(nonNil(constraints))
+		return "application/json", text, err
+	}
+
+	if rawID, ok := strings.CutPrefix(uri, decisionURIPrefix); ok {
+		d, err := l.Get(ctx, rawID)
+		if err != nil {
+			return "", "", err
+		}
+		text, err := toJSON(d)
+		return "application/json", text, err
+	}
+
+	return "", "", errs.New(errs.NotFound, "unknown resource: %s", uri)
+}
+
+func decodeArgs(raw json.RawMessage, v interface{}) error {
+	if err := json.Unmarshal(raw, v); err != nil {
+		return errs.New(errs.Usage, "invalid arguments: %w", err)
+	}
+	return nil
+}
+
+func toJSON(v interface{}) (string, error) {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+func objectSchema(properties map[string]interface{}, required ...string) map[string]interface{} {
+	schema := map[string]interface{}{
+		"type":       "object",
+		"properties": properties,
+	}
+	if len(required) > 0 {
+		schema["required"] = required
+	}
+	return schema
+}
+
+func stringProp(description string) map[string]interface{} {
+	return map[string]interface{}{"type": "string", "description": description}
+}
+
+func intProp(description string) map[string]interface{} {
+	return map[string]interface{}{"type": "integer", "description": description}
+}
+
+func arrayProp(description string) map[string]interface{} {
+	return map[string]interface{}{"type": "array", "items": map[string]string{"type": "string"}, "description": description}
+}
+
+func enumProp(description string, values ...string) map[string]interface{} {
+	return map[string]interface{}{"type": "string", "enum": values, "description": description}
+}
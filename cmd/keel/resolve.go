@@ -0,0 +1,58 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+var resolveCmd = &cobra.Command{
+	Use:   "resolve <id>",
+	Short: "Follow a decision's supersession chain to the decision in force",
+	Long: `Follow superseded_by links from a decision, such as an old DEC-xxxx found in a
+comment or commit message, to the head of its chain: the decision in force
+today. A decision that isn't superseded resolves to itself.
+
+A chain that loops back on itself is reported as a validation error, and a
+link to a decision missing from the ledger as not found.
+
+The decision_heads SQL view maps every ID to its head for bulk lookups.`,
+	Args: cobra.ExactArgs(1),
+	RunE: runResolve,
+}
+
+var resolveJSON bool
+
+func init() {
+	resolveCmd.Flags().BoolVar(&resolveJSON, "json", false, "Output as JSON")
+	rootCmd.AddCommand(resolveCmd)
+}
+
+func runResolve(cmd *cobra.Command, args []string) error {
+	l, err := openLedger(cmd)
+	if err != nil {
+		return err
+	}
+	defer l.Close()
+
+	res, err := l.Resolve(cmd.Context(), args[0])
+	if err != nil {
+		return err
+	}
+
+	if resolveJSON {
+		data, _ := json.MarshalIndent(res, "", "  ")
+		fmt.Println(string(data))
+		return nil
+	}
+
+	if len(res.Chain) == 1 {
+		fmt.Printf("%s is not superseded.\n\n", res.ID)
+	} else {
+		fmt.Printf("%s\n\n", strings.Join(res.Chain, " → "))
+	}
+	printDecisionSummary(res.Head)
+	return nil
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"github.com/tyroneavnit/keel/internal/errs"
+)
+
+var scanCmd = &cobra.Command{
+	Use:   "scan",
+	Short: "Index keel:DEC-xxxx annotations in source comments",
+	Long: `Find keel:DEC-xxxx markers in source comments and index them as links
+between decisions and the files and lines that mention them.
+
+Files ignored by .gitignore are skipped. Annotated decisions are returned by
+keel context for the file alongside decisions linked with --files. Markers
+that point at unknown or superseded decisions are reported.
+
+Example:
+  // keel:DEC-a1b2 retries are capped by the payment provider's rate limit`,
+	RunE: runScan,
+}
+
+var scanJSON bool
+
+func init() {
+	scanCmd.Flags().BoolVar(&scanJSON, "json", false, "Output as JSON")
+	rootCmd.AddCommand(scanCmd)
+}
+
+func runScan(cmd *cobra.Command, args []string) error {
+	l, err := openLedger(cmd)
+	if err != nil {
+		return err
+	}
+	defer l.Close()
+
+	result, err := l.Scan(cmd.Context())
+	if err != nil {
+		return err
+	}
+
+	if scanJSON {
+		data, _ := json.MarshalIndent(result, "", "  ")
+		fmt.Println(string(data))
+	} else {
+		files := make(map[string]bool)
+		for _, a := range result.Annotations {
+			files[a.File] = true
+		}
+		fmt.Printf("Scanned %d files: %d annotations in %d files\n", result.Files, len(result.Annotations), len(files))
+		if len(result.Issues) > 0 {
+			fmt.Printf("\n%s\n\n", red(fmt.Sprintf("✗ Found %d stale annotations:", len(result.Issues))))
+			for _, issue := range result.Issues {
+				fmt.Printf("  %s:%d: %s - %s\n", issue.FilePath, issue.Line, bold(issue.DecisionID), issue.Issue)
+			}
+		}
+	}
+
+	if len(result.Issues) > 0 {
+		return errs.Reported(errs.ValidationFailed, "found %d stale annotations", len(result.Issues))
+	}
+
+	return nil
+}
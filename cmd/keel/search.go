@@ -0,0 +1,92 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/tyroneavnit/keel/pkg/keel"
+)
+
+var searchCmd = &cobra.Command{
+	Use:   "search [text]",
+	Short: "Search decisions by text, type and status",
+	Long: `Full-text search over the problem, choice and rationale of decisions.
+Every word must match, as a prefix; results are ordered by relevance.
+
+Without text, lists decisions matching the filters, newest first.
+
+Examples:
+  keel search "rate limit"
+  keel search retry --type learning
+  keel search --type constraint --json
+  keel search cache --ledger services/billing`,
+	Annotations: map[string]string{usesDefaults: "true"},
+	RunE:        runSearch,
+}
+
+var (
+	searchType   string
+	searchStatus string
+	searchAuthor string
+	searchLedger string
+	searchLimit  int
+	searchJSON   bool
+)
+
+func init() {
+	searchCmd.Flags().StringVarP(&searchType, "type", "t", "", "Only decisions of this type")
+	searchCmd.Flags().StringVar(&searchStatus, "status", "active", "Only decisions with this status (active, superseded, or all)")
+	searchCmd.Flags().StringVar(&searchAuthor, "author", "", "Only decisions made by this identifier (email or agent name)")
+	searchCmd.Flags().StringVar(&searchLedger, "ledger", "", "Only decisions from the ledger in this directory")
+	searchCmd.Flags().IntVar(&searchLimit, "limit", 0, "Maximum number of results (0 = no limit)")
+	searchCmd.Flags().BoolVar(&searchJSON, "json", false, "Output as JSON")
+	searchCmd.Flags().String("as-of", "", "Read the ledgers as of a git revision or RFC 3339 time")
+	rootCmd.AddCommand(searchCmd)
+}
+
+func runSearch(cmd *cobra.Command, args []string) error {
+	l, err := openLedger(cmd)
+	if err != nil {
+		return err
+	}
+	defer l.Close()
+
+	decisions, err := l.Search(cmd.Context(), strings.Join(args, " "), keel.Filter{
+		Type:   searchType,
+		Status: searchStatus,
+		Author: searchAuthor,
+		Ledger: ledgerPath(searchLedger),
+		Limit:  searchLimit,
+	})
+	if err != nil {
+		return err
+	}
+
+	if searchJSON {
+		data, _ := json.MarshalIndent(nonNil(decisions), "", "  ")
+		fmt.Println(string(data))
+		return nil
+	}
+
+	if len(decisions) == 0 {
+		fmt.Println(dim("No matching decisions."))
+		return nil
+	}
+	for _, d := range decisions {
+		printDecisionSummary(d)
+		fmt.Println()
+	}
+	return nil
+}
+
+// ledgerPath converts a --ledger directory to the repo-relative form the
+// index records
+func ledgerPath(dir string) string {
+	if dir == "" {
+		return ""
+	}
+	return path.Clean(repoPath(dir))
+}
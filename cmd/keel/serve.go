@@ -0,0 +1,330 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"mime"
+	"net/http"
+	"os"
+	"os/signal"
+	"strconv"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/tyroneavnit/keel/internal/errs"
+	"github.com/tyroneavnit/keel/internal/types"
+	"github.com/tyroneavnit/keel/pkg/keel"
+)
+
+var serveCmd = &cobra.Command{
+	Use:   "serve",
+	Short: "Serve a local HTTP/JSON API over the ledger",
+	Long: `Serve decisions over HTTP so dashboards and editor plugins can query them
+without shelling out. Decisions use the same JSON shape as --json output.
+
+Endpoints:
+  GET  /api/decisions                  List decisions (?type, status, author, file, ref, symbol, limit)
+  GET  /api/decisions/{id}             One decision, as keel why
+  GET  /api/context                    Context (?path (repeatable), ref, symbol_at, author, format=json|prompt, budget)
+  GET  /api/search                     Full-text search (?q, type, status, author, limit)
+  GET  /api/graph                      Supersession and ref links (?files=true adds file links)
+  POST /api/decisions                  Record a decision (JSON body, fields as in keel decide)
+  POST /api/decisions/{id}/supersede   Supersede a decision (JSON body, fields as in keel supersede)
+
+Writes take the same ledger lock as the CLI. The index is refreshed whenever
+.keel/decisions.jsonl changes, including writes made by other processes.
+
+The server binds to localhost by default and has no authentication; do not
+expose it on a shared network.`,
+	Args: cobra.NoArgs,
+	RunE: runServe,
+}
+
+var serveAddr string
+
+func init() {
+	serveCmd.Flags().StringVar(&serveAddr, "addr", "127.0.0.1:7777", "Address to listen on")
+	rootCmd.AddCommand(serveCmd)
+}
+
+// watchInterval is how often the server checks decisions.jsonl for changes
+const watchInterval = time.Second
+
+// maxBodyBytes caps POST bodies
+const maxBodyBytes = 1 << 20
+
+// apiServer serves the HTTP API. Requests are handled one at a time, since
+// a write and the index refresh it triggers must not interleave with reads.
+type apiServer struct {
+	mu     sync.Mutex
+	ledger *keel.Ledger
+}
+
+// kindStatus maps typed errors to HTTP statuses; others are 500s
+var kindStatus = map[errs.Kind]int{
+	errs.Usage:            http.StatusBadRequest,
+	errs.InvalidID:        http.StatusBadRequest,
+	errs.ValidationFailed: http.StatusUnprocessableEntity,
+	errs.NotFound:         http.StatusNotFound,
+	errs.Conflict:         http.StatusConflict,
+}
+
+func runServe(cmd *cobra.Command, args []string) error {
+	l, err := openLedger(cmd)
+	if err != nil {
+		return err
+	}
+	defer l.Close()
+
+	s := &apiServer{ledger: l}
+
+	ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	go s.watch(ctx)
+
+	srv := &http.Server{Addr: serveAddr, Handler: s.routes(), ReadHeaderTimeout: 10 * time.Second}
+	serveErr := make(chan error, 1)
+	go func() { serveErr <- srv.ListenAndServe() }()
+
+	fmt.Printf("Serving keel API on http://%s\n", serveAddr)
+
+	select {
+	case err := <-serveErr:
+		return err
+	case <-ctx.Done():
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		return srv.Shutdown(shutdownCtx)
+	}
+}
+
+// routes registers the API endpoints
+func (s *apiServer) routes() *http.ServeMux {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/decisions", s.handle(s.listDecisions))
+	mux.HandleFunc("GET /api/decisions/{id}", s.handle(s.getDecision))
+	mux.HandleFunc("GET /api/context", s.handle(s.context))
+	mux.HandleFunc("GET /api/search", s.handle(s.search))
+	mux.HandleFunc("GET /api/graph", s.handle(s.graph))
+	mux.HandleFunc("POST /api/decisions", s.handle(s.decide))
+	mux.HandleFunc("POST /api/decisions/{id}/supersede", s.handle(s.supersede))
+	return mux
+}
+
+// watch refreshes the index whenever decisions.jsonl changes on disk, so
+// edits from git pulls and other keel processes show up without a request
+// paying for the rebuild
+func (s *apiServer) watch(ctx context.Context) {
+	ticker := time.NewTicker(watchInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.mu.Lock()
+			err := s.ledger.Refresh(ctx)
+			s.mu.Unlock()
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Warning: failed to refresh index: %v\n", err)
+			}
+		}
+	}
+}
+
+// handle serializes requests, refreshes the index and writes the handler's
+// result as JSON, or its error as {"error": "...", "code": "..."}
+func (s *apiServer) handle(fn func(r *http.Request) (int, interface{}, error)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		// Source files may have changed since the last request
+		status, body, err := http.StatusOK, interface{}(nil), s.ledger.Refresh(r.Context())
+		if err == nil {
+			status, body, err = fn(r)
+		}
+		if err != nil {
+			status = http.StatusInternalServerError
+			if code, ok := kindStatus[errs.KindOf(err)]; ok {
+				status = code
+			}
+			body = map[string]string{"error": err.Error(), "code": string(errs.KindOf(err))}
+		}
+
+		if text, ok := body.(string); ok {
+			w.Header().Set("Content-Type", "text/markdown; charset=utf-8")
+			w.WriteHeader(status)
+			fmt.Fprint(w, text)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		enc.Encode(body)
+	}
+}
+
+func (s *apiServer) listDecisions(r *http.Request) (int, interface{}, error) {
+	q := r.URL.Query()
+	limit, err := intParam(q.Get("limit"))
+	if err != nil {
+		return 0, nil, err
+	}
+	decisions, err := s.ledger.List(r.Context(), keel.Filter{
+		Type:   q.Get("type"),
+		Status: q.Get("status"),
+		Author: q.Get("author"),
+		File:   q.Get("file"),
+		Ref:    q.Get("ref"),
+		Symbol: q.Get("symbol"),
+		Limit:  limit,
+	})
+	if err != nil {
+		return 0, nil, err
+	}
+	return http.StatusOK, nonNil(decisions), nil
+}
+
+func (s *apiServer) getDecision(r *http.Request) (int, interface{}, error) {
+	d, err := s.ledger.Get(r.Context(), r.PathValue("id"))
+	if err != nil {
+		return 0, nil, err
+	}
+	return http.StatusOK, d, nil
+}
+
+func (s *apiServer) context(r *http.Request) (int, interface{}, error) {
+	q := r.URL.Query()
+	req := keel.ContextRequest{
+		Paths:    q["path"],
+		Ref:      q.Get("ref"),
+		SymbolAt: q.Get("symbol_at"),
+		Author:   q.Get("author"),
+	}
+	if len(req.Paths) == 0 && req.Ref == "" && req.SymbolAt == "" {
+		return 0, nil, errs.New(errs.Usage, "must provide path, ref or symbol_at")
+	}
+	budget, err := intParam(q.Get("budget"))
+	if err != nil {
+		return 0, nil, err
+	}
+
+	res, err := s.ledger.Context(r.Context(), req)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	switch q.Get("format") {
+	case "", "json":
+		return http.StatusOK, contextOutput(res), nil
+	case "prompt":
+		return http.StatusOK, res.Prompt(budget), nil
+	}
+	return 0, nil, errs.New(errs.Usage, "invalid format: %s. Must be one of: json, prompt", q.Get("format"))
+}
+
+func (s *apiServer) search(r *http.Request) (int, interface{}, error) {
+	q := r.URL.Query()
+	limit, err := intParam(q.Get("limit"))
+	if err != nil {
+		return 0, nil, err
+	}
+	status := q.Get("status")
+	if status == "" {
+		status = string(keel.StatusActive)
+	}
+
+	decisions, err := s.ledger.Search(r.Context(), q.Get("q"), keel.Filter{
+		Type:   q.Get("type"),
+		Status: status,
+		Author: q.Get("author"),
+		Limit:  limit,
+	})
+	if err != nil {
+		return 0, nil, err
+	}
+	return http.StatusOK, nonNil(decisions), nil
+}
+
+func (s *apiServer) graph(r *http.Request) (int, interface{}, error) {
+	decisions, err := s.ledger.List(r.Context(), keel.Filter{})
+	if err != nil {
+		return 0, nil, err
+	}
+	links, err := s.ledger.Links(r.Context(), r.URL.Query().Get("files") == "true")
+	if err != nil {
+		return 0, nil, err
+	}
+	return http.StatusOK, map[string]interface{}{
+		"decisions": nonNil(decisions),
+		"edges":     links,
+	}, nil
+}
+
+func (s *apiServer) decide(r *http.Request) (int, interface{}, error) {
+	var req keel.DecisionRequest
+	if err := decodeBody(r, &req); err != nil {
+		return 0, nil, err
+	}
+
+	d, err := s.ledger.Decide(r.Context(), req)
+	if err != nil {
+		return 0, nil, err
+	}
+	return http.StatusCreated, d, nil
+}
+
+func (s *apiServer) supersede(r *http.Request) (int, interface{}, error) {
+	var req keel.DecisionRequest
+	if err := decodeBody(r, &req); err != nil {
+		return 0, nil, err
+	}
+
+	d, err := s.ledger.Supersede(r.Context(), r.PathValue("id"), req)
+	if err != nil {
+		return 0, nil, err
+	}
+	return http.StatusCreated, d, nil
+}
+
+// decodeBody reads a JSON request body. Requiring application/json means
+// browsers must send a CORS preflight, which this server never answers, so
+// web pages can't write to the ledger.
+func decodeBody(r *http.Request, v interface{}) error {
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if mediaType != "application/json" {
+		return errs.New(errs.Usage, "Content-Type must be application/json")
+	}
+
+	dec := json.NewDecoder(http.MaxBytesReader(nil, r.Body, maxBodyBytes))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(v); err != nil {
+		return errs.New(errs.Usage, "invalid request body: %w", err)
+	}
+	return nil
+}
+
+func intParam(value string) (int, error) {
+	if value == "" {
+		return 0, nil
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 0 {
+		return 0, errs.New(errs.Usage, "invalid number: %s", value)
+	}
+	return n, nil
+}
+
+// nonNil makes empty results encode as [] rather than null
+func nonNil(decisions []*types.Decision) []*types.Decision {
+	if decisions == nil {
+		return []*types.Decision{}
+	}
+	return decisions
+}
@@ -0,0 +1,183 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/tyroneavnit/keel/internal/errs"
+	"github.com/tyroneavnit/keel/internal/store"
+	"github.com/tyroneavnit/keel/pkg/keel"
+)
+
+const (
+	activeDecision     = `{"id":"DEC-0001","created_at":"2026-01-01T00:00:00Z","type":"constraint","problem":"p","choice":"Use Postgres","files":["db/conn.go"],"decided_by":{"role":"human"},"status":"active"}`
+	supersededDecision = `{"id":"DEC-0002","created_at":"2026-01-02T00:00:00Z","type":"constraint","problem":"p","choice":"Use MySQL","decided_by":{"role":"human"},"status":"superseded","superseded_by":"DEC-0001"}`
+)
+
+// newTestServer serves the API over a ledger holding lines
+func newTestServer(t *testing.T, lines ...string) (*httptest.Server, *keel.Ledger, string) {
+	t.Helper()
+	root := initLedger(t, lines...)
+	l, err := keel.Open(context.Background(), keel.WithRepoRoot(root))
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := httptest.NewServer((&apiServer{ledger: l}).routes())
+	t.Cleanup(func() {
+		srv.Close()
+		l.Close()
+	})
+	return srv, l, root
+}
+
+// post sends body to path as JSON
+func post(t *testing.T, srv *httptest.Server, path, body string) *http.Response {
+	t.Helper()
+	resp, err := http.Post(srv.URL+path, "application/json", strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return resp
+}
+
+func TestServeStatus(t *testing.T) {
+	srv, _, _ := newTestServer(t, activeDecision, supersededDecision)
+	decision := `{"type":"constraint","problem":"Where do sessions live?","choice":"Redis","as":"alice@example.com"}`
+
+	tests := []struct {
+		name        string
+		method      string
+		path        string
+		contentType string
+		body        string
+		want        int
+		wantCode    errs.Kind
+	}{
+		{name: "list", method: "GET", path: "/api/decisions", want: 200},
+		{name: "get", method: "GET", path: "/api/decisions/DEC-0001", want: 200},
+		{name: "unknown decision", method: "GET", path: "/api/decisions/DEC-9999", want: 404, wantCode: errs.NotFound},
+		{name: "invalid id", method: "GET", path: "/api/decisions/nope", want: 400, wantCode: errs.InvalidID},
+		{name: "invalid limit", method: "GET", path: "/api/decisions?limit=-1", want: 400, wantCode: errs.Usage},
+		{name: "invalid status", method: "GET", path: "/api/search?status=open", want: 400, wantCode: errs.Usage},
+		{name: "context", method: "GET", path: "/api/context?path=db/conn.go", want: 200},
+		{name: "context without path", method: "GET", path: "/api/context", want: 400, wantCode: errs.Usage},
+		{name: "context format", method: "GET", path: "/api/context?path=db/conn.go&format=xml", want: 400, wantCode: errs.Usage},
+		{name: "decide", method: "POST", path: "/api/decisions", contentType: "application/json", body: decision, want: 201},
+		{name: "form body", method: "POST", path: "/api/decisions", contentType: "text/plain", body: decision, want: 400, wantCode: errs.Usage},
+		{name: "unknown field", method: "POST", path: "/api/decisions", contentType: "application/json", body: `{"choise":"Redis"}`, want: 400, wantCode: errs.Usage},
+		{name: "missing choice", method: "POST", path: "/api/decisions", contentType: "application/json", body: `{"type":"constraint","problem":"p"}`, want: 422, wantCode: errs.ValidationFailed},
+		{name: "supersede superseded", method: "POST", path: "/api/decisions/DEC-0002/supersede", contentType: "application/json", body: decision, want: 409, wantCode: errs.Conflict},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := http.NewRequest(tt.method, srv.URL+tt.path, strings.NewReader(tt.body))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.contentType != "" {
+				req.Header.Set("Content-Type", tt.contentType)
+			}
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer resp.Body.Close()
+
+			if resp.StatusCode != tt.want {
+				t.Errorf("status = %d, want %d", resp.StatusCode, tt.want)
+			}
+			if tt.wantCode == "" {
+				return
+			}
+			var body map[string]string
+			if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+				t.Fatalf("error body is not JSON: %v", err)
+			}
+			if body["code"] != string(tt.wantCode) || body["error"] == "" {
+				t.Errorf("error body = %v, want code %q and a message", body, tt.wantCode)
+			}
+		})
+	}
+}
+
+func TestServeInternalError(t *testing.T) {
+	srv, l, _ := newTestServer(t, activeDecision)
+	l.Close()
+
+	resp, err := http.Get(srv.URL + "/api/decisions")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	var body map[string]string
+	json.NewDecoder(resp.Body).Decode(&body)
+	if resp.StatusCode != http.StatusInternalServerError || body["code"] != string(errs.Internal) {
+		t.Errorf("status = %d, body = %v, want 500 with code %q", resp.StatusCode, body, errs.Internal)
+	}
+}
+
+func TestServeWriteLock(t *testing.T) {
+	srv, _, root := newTestServer(t, activeDecision)
+
+	unlock, err := store.Lock(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	done := make(chan int)
+	go func() {
+		resp := post(t, srv, "/api/decisions", `{"type":"constraint","problem":"p","choice":"Queue writes","as":"bob@example.com"}`)
+		resp.Body.Close()
+		done <- resp.StatusCode
+	}()
+
+	select {
+	case status := <-done:
+		t.Fatalf("decide finished with %d while another process held the lock", status)
+	case <-time.After(200 * time.Millisecond):
+	}
+	unlock()
+	if status := <-done; status != http.StatusCreated {
+		t.Fatalf("status after unlock = %d, want 201", status)
+	}
+
+	// Concurrent writers must each get their own line and ID
+	const writers = 5
+	ids := make(chan string, writers)
+	var wg sync.WaitGroup
+	for i := 0; i < writers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			resp := post(t, srv, "/api/decisions", `{"type":"constraint","problem":"p","choice":"Write concurrently","as":"bob@example.com"}`)
+			defer resp.Body.Close()
+			var d keel.Decision
+			json.NewDecoder(resp.Body).Decode(&d)
+			ids <- d.ID
+		}()
+	}
+	wg.Wait()
+	close(ids)
+
+	seen := map[string]bool{}
+	for id := range ids {
+		if id == "" || seen[id] {
+			t.Errorf("concurrent decide returned ID %q twice or not at all", id)
+		}
+		seen[id] = true
+	}
+	data, err := os.ReadFile(store.GetDecisionsPath(root))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lines := strings.Count(string(data), "\n"); lines != writers+2 {
+		t.Errorf("ledger has %d lines, want %d", lines, writers+2)
+	}
+}
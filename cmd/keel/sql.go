@@ -3,11 +3,8 @@ package main
 import (
 	"encoding/json"
 	"fmt"
-	"os"
-	"strings"
 
 	"github.com/spf13/cobra"
-	"github.com/tyroneavnit/keel/internal/index"
 )
 
 var sqlCmd = &cobra.Command{
@@ -16,14 +13,29 @@ var sqlCmd = &cobra.Command{
 	Long: `Execute a SQL query directly against the SQLite index.
 
 Schema:
-  decisions (id, type, status, problem, choice, rationale, created_at, raw_json)
+  decisions (id, type, status, problem, choice, rationale, created_at,
+             decided_by_role, decided_by_identifier, decided_by_session,
+             review_by, expires_at, ledger, upstream, raw_json)
   decision_files (decision_id, file_path)
+  decision_anchors (decision_id, file_path, start_line, end_line, fingerprint)
+  decision_annotations (decision_id, file_path, line)
   decision_refs (decision_id, ref_id)
   decision_symbols (decision_id, symbol)
+  decision_heads (id, head_id, hops)      -- view
+
+ledger is the directory of the ledger holding the decision, "." for the
+root; nested ledgers such as services/billing/.keel are indexed together.
+upstream names the upstream a read-only decision was synced from (its ID
+is platform:DEC-xxxx), and is NULL for this repository's decisions.
+decision_heads maps every decision to the head of its supersession chain,
+the decision in force, hops links away. head_id is NULL when the chain
+loops or ends in a link to a decision missing from the index.
 
 Examples:
   keel sql "SELECT raw_json FROM decisions WHERE status = 'active'"
   keel sql "SELECT * FROM decisions WHERE type = 'constraint'"
+  keel sql "SELECT ledger, count(*) FROM decisions GROUP BY ledger"
+  keel sql "SELECT id, head_id FROM decision_heads WHERE hops > 0"
   keel sql "SELECT raw_json FROM decisions WHERE problem LIKE '%auth%'"
   keel sql "SELECT d.raw_json FROM decisions d JOIN decision_files df ON d.id = df.decision_id WHERE df.file_path LIKE '%billing%'"`,
 	Args: cobra.ExactArgs(1),
@@ -34,76 +46,25 @@ var sqlJSON bool
 
 func init() {
 	sqlCmd.Flags().BoolVar(&sqlJSON, "json", false, "Output as JSON array")
+	sqlCmd.Flags().String("as-of", "", "Read the ledgers as of a git revision or RFC 3339 time")
 	rootCmd.AddCommand(sqlCmd)
 }
 
 func runSQL(cmd *cobra.Command, args []string) error {
-	query := args[0]
-
-	// Block write operations
-	upperQuery := strings.ToUpper(strings.TrimSpace(query))
-	if strings.HasPrefix(upperQuery, "INSERT") ||
-		strings.HasPrefix(upperQuery, "UPDATE") ||
-		strings.HasPrefix(upperQuery, "DELETE") ||
-		strings.HasPrefix(upperQuery, "DROP") ||
-		strings.HasPrefix(upperQuery, "ALTER") ||
-		strings.HasPrefix(upperQuery, "CREATE") {
-		return fmt.Errorf("only SELECT queries are allowed")
-	}
-
-	repoRoot, _ := os.Getwd()
-	db, err := index.Open(repoRoot)
+	l, err := openLedger(cmd)
 	if err != nil {
-		return fmt.Errorf("failed to open index: %w", err)
+		return err
 	}
-	defer db.Close()
+	defer l.Close()
 
-	rows, err := db.Query(query)
+	result, err := l.Query(cmd.Context(), args[0])
 	if err != nil {
-		return fmt.Errorf("query failed: %w", err)
-	}
-	defer rows.Close()
-
-	// Get column names
-	columns, err := rows.Columns()
-	if err != nil {
-		return fmt.Errorf("failed to get columns: %w", err)
-	}
-
-	// Collect all results
-	var results []map[string]interface{}
-	for rows.Next() {
-		// Create a slice of interface{} to hold each column
-		values := make([]interface{}, len(columns))
-		valuePtrs := make([]interface{}, len(columns))
-		for i := range values {
-			valuePtrs[i] = &values[i]
-		}
-
-		if err := rows.Scan(valuePtrs...); err != nil {
-			return fmt.Errorf("failed to scan row: %w", err)
-		}
-
-		// Build map for this row
-		row := make(map[string]interface{})
-		for i, col := range columns {
-			val := values[i]
-			// Convert []byte to string for readability
-			if b, ok := val.([]byte); ok {
-				row[col] = string(b)
-			} else {
-				row[col] = val
-			}
-		}
-		results = append(results, row)
-	}
-
-	if err := rows.Err(); err != nil {
-		return fmt.Errorf("error iterating rows: %w", err)
+		return err
 	}
+	columns, results := result.Columns, result.Rows
 
 	if len(results) == 0 {
-		fmt.Println("\033[2mNo results.\033[0m")
+		fmt.Println(dim("No results."))
 		return nil
 	}
 
@@ -119,7 +80,7 @@ func runSQL(cmd *cobra.Command, args []string) error {
 			} else {
 				// Multiple columns - print as key: value pairs
 				for _, col := range columns {
-					fmt.Printf("\033[2m%s:\033[0m %v\n", col, row[col])
+					fmt.Printf("%s %v\n", dim(col+":"), row[col])
 				}
 			}
 			if i < len(results)-1 {
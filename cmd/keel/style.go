@@ -0,0 +1,43 @@
+package main
+
+import (
+	"os"
+
+	"github.com/tyroneavnit/keel/internal/config"
+)
+
+// colorEnabled controls ANSI styling; set from output.color in config and
+// whether stdout is a terminal
+var colorEnabled = isTerminal(os.Stdout) && os.Getenv("NO_COLOR") == ""
+
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+func style(code, s string) string {
+	if !colorEnabled || code == "" {
+		return s
+	}
+	return "\033[" + code + "m" + s + "\033[0m"
+}
+
+func bold(s string) string   { return style("1", s) }
+func dim(s string) string    { return style("2", s) }
+func red(s string) string    { return style("31", s) }
+func green(s string) string  { return style("32", s) }
+func yellow(s string) string { return style("33", s) }
+
+func colorType(t string) string {
+	return style(config.ColorCode(cfg.Types[t].Color), t)
+}
+
+func colorStatus(s string) string {
+	switch s {
+	case "active":
+		return green(s)
+	case "superseded":
+		return dim(s)
+	}
+	return s
+}
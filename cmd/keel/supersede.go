@@ -2,40 +2,52 @@ package main
 
 import (
 	"fmt"
-	"os"
 
 	"github.com/spf13/cobra"
-	"github.com/tyroneavnit/keel/internal/id"
-	"github.com/tyroneavnit/keel/internal/index"
-	"github.com/tyroneavnit/keel/internal/query"
-	"github.com/tyroneavnit/keel/internal/store"
-	"github.com/tyroneavnit/keel/internal/types"
+	"github.com/tyroneavnit/keel/pkg/keel"
 )
 
 var supersedeCmd = &cobra.Command{
 	Use:   "supersede <id>",
 	Short: "Replace a decision with a new one",
-	Long:  `Create a new decision that supersedes an existing one. The old decision is marked as superseded.`,
-	Args:  cobra.ExactArgs(1),
-	RunE:  runSupersede,
+	Long: `Create a new decision that supersedes an existing one. The old decision is marked as superseded.
+
+The type, problem, symbols and refs are copied from the original unless given.
+Files and anchors are copied together unless --files or --anchor is given.`,
+	Args: cobra.ExactArgs(1),
+	RunE: runSupersede,
 }
 
 var (
-	supersedeProblem   string
-	supersedeChoice    string
-	supersedeRationale string
-	supersedeFiles     string
-	supersedeRefs      string
-	supersedeAgent     bool
+	supersedeType       string
+	supersedeProblem    string
+	supersedeChoice     string
+	supersedeRationale  string
+	supersedeFiles      string
+	supersedeSymbols    string
+	supersedeAnchors    string
+	supersedeRefs       string
+	supersedeNoSymCheck bool
+	supersedeAgent      bool
+	supersedeAs         string
+	supersedeReviewBy   string
+	supersedeExpiresAt  string
 )
 
 func init() {
+	supersedeCmd.Flags().StringVarP(&supersedeType, "type", "t", "", "New decision type (defaults to original)")
 	supersedeCmd.Flags().StringVar(&supersedeProblem, "problem", "", "New problem statement (defaults to original)")
 	supersedeCmd.Flags().StringVar(&supersedeChoice, "choice", "", "New choice (required)")
 	supersedeCmd.Flags().StringVar(&supersedeRationale, "rationale", "", "Why this supersedes the original")
 	supersedeCmd.Flags().StringVar(&supersedeFiles, "files", "", "Comma-separated list of affected files")
+	supersedeCmd.Flags().StringVar(&supersedeSymbols, "symbols", "", "Comma-separated list of affected symbols")
+	supersedeCmd.Flags().StringVar(&supersedeAnchors, "anchor", "", "Comma-separated line ranges the decision applies to (file:start-end)")
 	supersedeCmd.Flags().StringVar(&supersedeRefs, "refs", "", "Comma-separated list of external references (issues, epics, etc.)")
-	supersedeCmd.Flags().BoolVar(&supersedeAgent, "agent", false, "Mark as an agent decision")
+	supersedeCmd.Flags().BoolVar(&supersedeNoSymCheck, "no-symbol-check", false, "Skip resolving Go symbols against the source tree")
+	supersedeCmd.Flags().BoolVar(&supersedeAgent, "agent", false, "Mark as an agent decision (implied when $KEEL_AGENT is set)")
+	supersedeCmd.Flags().StringVar(&supersedeAs, "as", "", "Identifier of who made the decision (defaults to $KEEL_AGENT or git user.email)")
+	supersedeCmd.Flags().StringVar(&supersedeReviewBy, "review-by", "", "Date the new decision should be revisited (YYYY-MM-DD or RFC3339)")
+	supersedeCmd.Flags().StringVar(&supersedeExpiresAt, "expires-at", "", "Date the new decision stops applying (YYYY-MM-DD or RFC3339)")
 
 	supersedeCmd.MarkFlagRequired("choice")
 
@@ -43,92 +55,37 @@ func init() {
 }
 
 func runSupersede(cmd *cobra.Command, args []string) error {
-	repoRoot, _ := os.Getwd()
-
-	// Check initialization
-	if err := store.RequireInit(repoRoot); err != nil {
-		return err
-	}
-
-	rawID := args[0]
-	normalizedID, err := id.Normalize(rawID)
+	l, err := openLedger(cmd)
 	if err != nil {
 		return err
 	}
-	db, err := index.Open(repoRoot)
-	if err != nil {
-		return fmt.Errorf("failed to open index: %w", err)
-	}
-	defer db.Close()
-
-	// Get original decision
-	original, err := query.ByID(db, normalizedID)
+	defer l.Close()
+
+	newDecision, err := l.Supersede(cmd.Context(), args[0], keel.DecisionRequest{
+		Type:          supersedeType,
+		Problem:       supersedeProblem,
+		Choice:        supersedeChoice,
+		Rationale:     supersedeRationale,
+		Files:         repoPaths(splitAndTrim(supersedeFiles)),
+		Symbols:       splitAndTrim(supersedeSymbols),
+		Anchors:       repoPaths(splitAndTrim(supersedeAnchors)),
+		Refs:          splitAndTrim(supersedeRefs),
+		ReviewBy:      supersedeReviewBy,
+		ExpiresAt:     supersedeExpiresAt,
+		Agent:         supersedeAgent,
+		As:            supersedeAs,
+		NoSymbolCheck: supersedeNoSymCheck,
+	})
 	if err != nil {
 		return err
 	}
-	if original == nil {
-		return fmt.Errorf("decision %s not found", normalizedID)
-	}
-
-	// Build new decision input
-	problem := original.Problem
-	if supersedeProblem != "" {
-		problem = supersedeProblem
-	}
-
-	input := types.DecisionInput{
-		Type:       original.Type,
-		Problem:    problem,
-		Choice:     supersedeChoice,
-		Supersedes: &normalizedID,
-	}
-
-	if supersedeRationale != "" {
-		input.Rationale = &supersedeRationale
-	}
 
-	if supersedeFiles != "" {
-		input.Files = splitAndTrim(supersedeFiles)
-	} else {
-		input.Files = original.Files
-	}
-
-	if supersedeRefs != "" {
-		input.Refs = splitAndTrim(supersedeRefs)
-	} else {
-		input.Refs = original.Refs
-	}
-
-	role := "human"
-	if supersedeAgent {
-		role = "agent"
-	}
-	input.DecidedBy = &types.DecidedBy{Role: role}
-
-	// Generate new ID
-	newID := id.Generate(input.Problem, input.Choice)
-
-	// Create new decision
-	newDecision := types.NewDecision(newID, input)
-
-	// Save new decision
-	if err := store.AppendDecision(newDecision, repoRoot); err != nil {
-		return fmt.Errorf("failed to save decision: %w", err)
-	}
-	if err := db.IndexDecision(newDecision); err != nil {
-		return fmt.Errorf("failed to index decision: %w", err)
-	}
-
-	// Mark original as superseded
-	original.Status = types.StatusSuperseded
-	original.SupersededBy = &newID
-	if err := store.AppendDecision(original, repoRoot); err != nil {
-		return fmt.Errorf("failed to update original decision: %w", err)
-	}
-	if err := db.IndexDecision(original); err != nil {
-		return fmt.Errorf("failed to index original decision: %w", err)
-	}
-
-	fmt.Printf("Created \033[1m%s\033[0m (supersedes %s)\n", newID, normalizedID)
+	fmt.Printf("Created %s (supersedes %s)\n", bold(newDecision.ID), *newDecision.Supersedes)
 	return nil
 }
+
+// supersedeRequest names the decision to replace along with the new fields
+type supersedeRequest struct {
+	ID string `json:"id"`
+	keel.DecisionRequest
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"github.com/tyroneavnit/keel/pkg/keel"
+)
+
+var syncUpstreamCmd = &cobra.Command{
+	Use:   "sync-upstream [name...]",
+	Short: "Refresh decisions from upstream repositories",
+	Long: `Fetch the ledgers of upstream repositories listed in .keel/config.yaml into
+.keel/upstream/ and index them.
+
+  upstream:
+    platform:
+      url: ../platform        # local clone (bare or not), or a git remote
+      ref: main               # default HEAD
+      path: .keel/decisions.jsonl
+
+Upstream decisions are read-only here and their IDs carry the upstream's
+name, as in platform:DEC-a1b2. Their active constraints appear in keel
+context alongside local ones.
+
+Without names, every configured upstream is synced and upstreams that were
+removed from the config are dropped.`,
+	RunE: runSyncUpstream,
+}
+
+var syncUpstreamJSON bool
+
+func init() {
+	syncUpstreamCmd.Flags().BoolVar(&syncUpstreamJSON, "json", false, "Output as JSON")
+	rootCmd.AddCommand(syncUpstreamCmd)
+}
+
+func runSyncUpstream(cmd *cobra.Command, args []string) error {
+	l, err := openLedger(cmd)
+	if err != nil {
+		return err
+	}
+	defer l.Close()
+
+	if len(args) == 0 && len(cfg.Upstream) == 0 && !syncUpstreamJSON {
+		fmt.Println("No upstreams configured. Add one under upstream in .keel/config.yaml.")
+	}
+
+	results, err := l.SyncUpstream(cmd.Context(), args...)
+	if syncUpstreamJSON {
+		if results == nil {
+			results = []*keel.UpstreamResult{}
+		}
+		data, _ := json.MarshalIndent(results, "", "  ")
+		fmt.Println(string(data))
+		return err
+	}
+
+	for _, res := range results {
+		source := ""
+		if res.Commit != "" {
+			source = dim(fmt.Sprintf(" (%s)", shortCommit(res.Commit)))
+		}
+		fmt.Printf("%s %s: %d decisions%s\n", green("✓"), bold(res.Name), res.Decisions, source)
+	}
+	return err
+}
+
+// shortCommit abbreviates a commit hash for display
+func shortCommit(commit string) string {
+	if len(commit) > 7 {
+		return commit[:7]
+	}
+	return commit
+}
@@ -3,60 +3,52 @@ package main
 import (
 	"encoding/json"
 	"fmt"
-	"os"
-	"path/filepath"
 
 	"github.com/spf13/cobra"
-	"github.com/tyroneavnit/keel/internal/index"
-	"github.com/tyroneavnit/keel/internal/query"
+	"github.com/tyroneavnit/keel/internal/errs"
 )
 
 var validateCmd = &cobra.Command{
 	Use:   "validate",
-	Short: "Check that file references still exist",
-	Long:  `Validate that all files referenced by decisions still exist in the repository.`,
-	RunE:  runValidate,
+	Short: "Check that file and symbol references still exist",
+	Long: `Validate that all files referenced by decisions still exist in the repository.
+
+In Go repositories, qualified symbols (e.g. billing.(*Invoice).Total) are also
+resolved against the source tree and reported when they no longer exist.
+
+Line-range anchors are compared with their content fingerprint. Anchored code
+that moved or was edited is located again by fuzzy matching; --fix records the
+relocated anchors in the ledger.`,
+	RunE: runValidate,
 }
 
-var validateJSON bool
+var (
+	validateJSON bool
+	validateFix  bool
+)
 
 func init() {
 	validateCmd.Flags().BoolVar(&validateJSON, "json", false, "Output as JSON")
+	validateCmd.Flags().BoolVar(&validateFix, "fix", false, "Record relocated anchors in the ledger")
 	rootCmd.AddCommand(validateCmd)
 }
 
-type ValidationIssue struct {
-	DecisionID string `json:"decision_id"`
-	FilePath   string `json:"file_path"`
-	Issue      string `json:"issue"`
-}
-
 func runValidate(cmd *cobra.Command, args []string) error {
-	repoRoot, _ := os.Getwd()
-	db, err := index.Open(repoRoot)
+	l, err := openLedger(cmd)
 	if err != nil {
-		return fmt.Errorf("failed to open index: %w", err)
+		return err
 	}
-	defer db.Close()
+	defer l.Close()
 
-	// Get all active decisions
-	decisions, err := query.All(db, query.Options{Status: "active"})
+	issues, err := l.Validate(cmd.Context(), validateFix)
 	if err != nil {
 		return err
 	}
 
-	var issues []ValidationIssue
-
-	for _, d := range decisions {
-		for _, file := range d.Files {
-			fullPath := filepath.Join(repoRoot, file)
-			if _, err := os.Stat(fullPath); os.IsNotExist(err) {
-				issues = append(issues, ValidationIssue{
-					DecisionID: d.ID,
-					FilePath:   file,
-					Issue:      "file not found",
-				})
-			}
+	unresolved := 0
+	for _, issue := range issues {
+		if !issue.Fixed {
+			unresolved++
 		}
 	}
 
@@ -64,18 +56,29 @@ func runValidate(cmd *cobra.Command, args []string) error {
 		data, _ := json.MarshalIndent(issues, "", "  ")
 		fmt.Println(string(data))
 	} else {
-		if len(issues) == 0 {
-			fmt.Println("\033[32m✓ All file references are valid\033[0m")
+		if unresolved == 0 {
+			fmt.Println(green("✓ All file and symbol references are valid"))
 		} else {
-			fmt.Printf("\033[31m✗ Found %d validation issues:\033[0m\n\n", len(issues))
-			for _, issue := range issues {
-				fmt.Printf("  \033[1m%s\033[0m: %s - %s\n", issue.DecisionID, issue.FilePath, issue.Issue)
+			fmt.Printf("%s\n\n", red(fmt.Sprintf("✗ Found %d validation issues:", unresolved)))
+		}
+		for _, issue := range issues {
+			target := issue.FilePath
+			if issue.Symbol != "" {
+				target = issue.Symbol
+			}
+			if issue.Anchor != "" {
+				target = issue.Anchor
+			}
+			if issue.Fixed {
+				fmt.Printf("  %s %s: %s - %s\n", green("fixed"), bold(issue.DecisionID), target, issue.Issue)
+			} else {
+				fmt.Printf("  %s: %s - %s\n", bold(issue.DecisionID), target, issue.Issue)
 			}
 		}
 	}
 
-	if len(issues) > 0 {
-		os.Exit(1)
+	if unresolved > 0 {
+		return errs.Reported(errs.ValidationFailed, "found %d validation issues", unresolved)
 	}
 
 	return nil
@@ -3,99 +3,149 @@ package main
 import (
 	"encoding/json"
 	"fmt"
-	"os"
+	"strings"
 
 	"github.com/spf13/cobra"
-	"github.com/tyroneavnit/keel/internal/id"
-	"github.com/tyroneavnit/keel/internal/index"
-	"github.com/tyroneavnit/keel/internal/query"
+	"github.com/tyroneavnit/keel/internal/errs"
 	"github.com/tyroneavnit/keel/internal/types"
+	"github.com/tyroneavnit/keel/pkg/keel"
 )
 
 var whyCmd = &cobra.Command{
 	Use:   "why <id>",
 	Short: "Show full decision details",
-	Long:  `Display the complete details of a decision by its ID.`,
-	Args:  cobra.ExactArgs(1),
-	RunE:  runWhy,
+	Long: `Display the complete details of a decision by its ID.
+
+With --follow, a superseded decision is followed to the head of its chain,
+the decision in force, as keel resolve does.
+
+With --history, the decision is followed by its supersession chain in
+both directions, from the first decision it replaced to the head now in
+force. Each lists every ledger line recorded for it, the fields that line
+changed, and the commit, author and pull request that added it.`,
+	Args: cobra.ExactArgs(1),
+	RunE: runWhy,
 }
 
-var whyJSON bool
+var (
+	whyJSON    bool
+	whyHistory bool
+	whyFollow  bool
+)
 
 func init() {
 	whyCmd.Flags().BoolVar(&whyJSON, "json", false, "Output as JSON")
+	whyCmd.Flags().BoolVar(&whyFollow, "follow", false, "Show the decision in force at the head of the supersession chain")
+	whyCmd.Flags().BoolVar(&whyHistory, "history", false, "Also show every version of the decision and its supersession chain, with the commit behind each change")
+	whyCmd.Flags().String("as-of", "", "Read the ledgers as of a git revision or RFC 3339 time")
 	rootCmd.AddCommand(whyCmd)
 }
 
 func runWhy(cmd *cobra.Command, args []string) error {
-	rawID := args[0]
-	normalizedID, err := id.Normalize(rawID)
+	l, err := openLedger(cmd)
 	if err != nil {
 		return err
 	}
+	defer l.Close()
 
-	repoRoot, _ := os.Getwd()
-	db, err := index.Open(repoRoot)
-	if err != nil {
-		return fmt.Errorf("failed to open index: %w", err)
-	}
-	defer db.Close()
-
-	decision, err := query.ByID(db, normalizedID)
+	decision, err := l.Get(cmd.Context(), args[0])
 	if err != nil {
 		return err
 	}
+	var followed []string
+	if whyFollow {
+		res, err := l.Resolve(cmd.Context(), decision.ID)
+		if err != nil {
+			return err
+		}
+		decision, followed = res.Head, res.Chain
+	}
 
-	if decision == nil {
-		return fmt.Errorf("decision %s not found", normalizedID)
+	var chain []keel.Version
+	if whyHistory {
+		if l.AsOf() != "" {
+			return errs.New(errs.Usage, "--history shows the full history; it can't be combined with --as-of")
+		}
+		if chain, err = l.History(cmd.Context(), decision.ID); err != nil {
+			return err
+		}
 	}
 
 	if whyJSON {
-		output, _ := json.MarshalIndent(decision, "", "  ")
+		var output []byte
+		if whyHistory {
+			output, _ = json.MarshalIndent(struct {
+				*types.Decision
+				Chain []keel.Version `json:"chain"`
+			}{decision, chain}, "", "  ")
+		} else {
+			output, _ = json.MarshalIndent(decision, "", "  ")
+		}
 		fmt.Println(string(output))
 	} else {
+		if len(followed) > 1 {
+			fmt.Printf("%s\n\n", dim("Followed "+strings.Join(followed, " → ")))
+		}
 		printDecisionFull(decision)
+		if whyHistory {
+			fmt.Printf("\n%s\n", bold("History"))
+			printChain(chain, decision.ID)
+		}
 	}
 
 	return nil
 }
 
 func printDecisionFull(d *types.Decision) {
-	fmt.Printf("\033[1mDecision %s\033[0m\n\n", d.ID)
-	fmt.Printf("\033[2mType:\033[0m     %s\n", colorType(string(d.Type)))
-	fmt.Printf("\033[2mStatus:\033[0m   %s\n", colorStatus(string(d.Status)))
-	fmt.Printf("\033[2mCreated:\033[0m  %s\n\n", d.CreatedAt)
+	fmt.Printf("%s\n\n", bold("Decision "+d.ID))
+	fmt.Printf("%s     %s\n", dim("Type:"), colorType(string(d.Type)))
+	fmt.Printf("%s   %s\n", dim("Status:"), colorStatus(string(d.Status)))
+	fmt.Printf("%s  %s\n", dim("Created:"), d.CreatedAt)
+	if d.ReviewBy != nil {
+		fmt.Printf("%s   %s\n", dim("Review:"), *d.ReviewBy)
+	}
+	if d.ExpiresAt != nil {
+		fmt.Printf("%s  %s%s\n", dim("Expires:"), *d.ExpiresAt, expiryNote(d))
+	}
+	fmt.Println()
 
-	fmt.Printf("\033[1mProblem\033[0m\n%s\n\n", d.Problem)
-	fmt.Printf("\033[1mChoice\033[0m\n%s\n", d.Choice)
+	fmt.Printf("%s\n%s\n\n", bold("Problem"), d.Problem)
+	fmt.Printf("%s\n%s\n", bold("Choice"), d.Choice)
 
 	if d.Rationale != nil && *d.Rationale != "" {
-		fmt.Printf("\n\033[1mRationale\033[0m\n%s\n", *d.Rationale)
+		fmt.Printf("\n%s\n%s\n", bold("Rationale"), *d.Rationale)
 	}
 
 	if len(d.Tradeoffs) > 0 {
-		fmt.Printf("\n\033[1mTradeoffs\033[0m\n")
+		fmt.Printf("\n%s\n", bold("Tradeoffs"))
 		for _, t := range d.Tradeoffs {
 			fmt.Printf("  - %s\n", t)
 		}
 	}
 
 	if len(d.Files) > 0 {
-		fmt.Printf("\n\033[1mFiles\033[0m\n")
+		fmt.Printf("\n%s\n", bold("Files"))
 		for _, f := range d.Files {
 			fmt.Printf("  %s\n", f)
 		}
 	}
 
+	if len(d.Anchors) > 0 {
+		fmt.Printf("\n%s\n", bold("Anchors"))
+		for _, a := range d.Anchors {
+			fmt.Printf("  %s %s\n", a, dim(a.Fingerprint))
+		}
+	}
+
 	if len(d.Symbols) > 0 {
-		fmt.Printf("\n\033[1mSymbols\033[0m\n")
+		fmt.Printf("\n%s\n", bold("Symbols"))
 		for _, s := range d.Symbols {
 			fmt.Printf("  %s\n", s)
 		}
 	}
 
 	if len(d.Refs) > 0 {
-		fmt.Printf("\n\033[1mRefs\033[0m\n")
+		fmt.Printf("\n%s\n", bold("Refs"))
 		for _, r := range d.Refs {
 			fmt.Printf("  %s\n", r)
 		}
@@ -106,37 +156,18 @@ func printDecisionFull(d *types.Decision) {
 	if d.DecidedBy.Identifier != nil {
 		identifier = fmt.Sprintf(" (%s)", *d.DecidedBy.Identifier)
 	}
-	fmt.Printf("\033[2mDecided by:\033[0m %s%s\n", d.DecidedBy.Role, identifier)
-
-	if d.Supersedes != nil {
-		fmt.Printf("\033[2mSupersedes:\033[0m %s\n", *d.Supersedes)
-	}
-	if d.SupersededBy != nil {
-		fmt.Printf("\033[2mSuperseded by:\033[0m %s\n", *d.SupersededBy)
-	}
-}
-
-func colorType(t string) string {
-	colors := map[string]string{
-		"product":    "\033[34m",
-		"process":    "\033[35m",
-		"constraint": "\033[31m",
+	if d.DecidedBy.Session != nil {
+		identifier += fmt.Sprintf(" [session %s]", *d.DecidedBy.Session)
 	}
-	color := colors[t]
-	if color == "" {
-		return t
+	fmt.Printf("%s %s%s\n", dim("Decided by:"), d.DecidedBy.Role, identifier)
+	for _, a := range d.Approvals {
+		fmt.Printf("%s %s (%s)\n", dim("Approved by:"), a.By, a.ApprovedAt)
 	}
-	return color + t + "\033[0m"
-}
 
-func colorStatus(s string) string {
-	colors := map[string]string{
-		"active":     "\033[32m",
-		"superseded": "\033[2m",
+	if d.Supersedes != nil {
+		fmt.Printf("%s %s\n", dim("Supersedes:"), *d.Supersedes)
 	}
-	color := colors[s]
-	if color == "" {
-		return s
+	if d.SupersededBy != nil {
+		fmt.Printf("%s %s\n", dim("Superseded by:"), *d.SupersededBy)
 	}
-	return color + s + "\033[0m"
 }
@@ -0,0 +1,272 @@
+package anchor
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash/fnv"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/tyroneavnit/keel/internal/types"
+)
+
+// Status describes how an anchor compares to the current file
+type Status string
+
+const (
+	StatusOK      Status = "ok"      // the anchored lines are unchanged
+	StatusMoved   Status = "moved"   // the anchored lines are unchanged but elsewhere in the file
+	StatusDrifted Status = "drifted" // the anchored lines were edited; the closest match was found
+	StatusLost    Status = "lost"    // no sufficiently similar lines remain
+	StatusMissing Status = "missing" // the file no longer exists
+)
+
+// MinSimilarity is the share of anchored lines a region must keep to count as drifted rather than lost
+const MinSimilarity = 0.6
+
+// Result is the outcome of checking an anchor against the working tree
+type Result struct {
+	Status     Status
+	Anchor     types.Anchor // relocated anchor; equal to the original when ok, lost or missing
+	Similarity float64
+}
+
+// Parse splits an anchor spec of the form file:line or file:start-end
+func Parse(spec string) (file string, start, end int, err error) {
+	i := strings.LastIndex(spec, ":")
+	if i <= 0 {
+		return "", 0, 0, fmt.Errorf("invalid anchor: %s. Expected file:start-end", spec)
+	}
+	file, lines := spec[:i], spec[i+1:]
+
+	startStr, endStr, ranged := strings.Cut(lines, "-")
+	start, err = strconv.Atoi(startStr)
+	if err != nil || start < 1 {
+		return "", 0, 0, fmt.Errorf("invalid start line in anchor %s", spec)
+	}
+	end = start
+	if ranged {
+		end, err = strconv.Atoi(endStr)
+		if err != nil || end < start {
+			return "", 0, 0, fmt.Errorf("invalid end line in anchor %s", spec)
+		}
+	}
+	return filepath.ToSlash(filepath.Clean(file)), start, end, nil
+}
+
+// New fingerprints lines start through end of a repo-relative file
+func New(repoRoot, file string, start, end int) (types.Anchor, error) {
+	lines, err := readLines(repoRoot, file)
+	if err != nil {
+		return types.Anchor{}, fmt.Errorf("failed to read %s: %w", file, err)
+	}
+	if end > len(lines) {
+		return types.Anchor{}, fmt.Errorf("anchor %s:%d-%d is past the end of the file (%d lines)", file, start, end, len(lines))
+	}
+
+	region := lines[start-1 : end]
+	return types.Anchor{
+		File:        file,
+		StartLine:   start,
+		EndLine:     end,
+		Fingerprint: Fingerprint(region),
+		LineHashes:  lineHashes(region),
+	}, nil
+}
+
+// Fingerprint hashes a region of code. Indentation, runs of whitespace and
+// blank lines are ignored so reformatting alone does not count as drift.
+func Fingerprint(lines []string) string {
+	h := sha256.New()
+	for _, line := range lines {
+		if n := normalize(line); n != "" {
+			h.Write([]byte(n))
+			h.Write([]byte{'\n'})
+		}
+	}
+	return hex.EncodeToString(h.Sum(nil))[:16]
+}
+
+// Check compares an anchor with the current contents of its file and, when
+// the code has moved or drifted, finds the region that matches it best
+func Check(repoRoot string, a types.Anchor) (Result, error) {
+	lines, err := readLines(repoRoot, a.File)
+	if os.IsNotExist(err) {
+		return Result{Status: StatusMissing, Anchor: a}, nil
+	}
+	if err != nil {
+		return Result{}, err
+	}
+
+	if a.EndLine <= len(lines) && Fingerprint(lines[a.StartLine-1:a.EndLine]) == a.Fingerprint {
+		return Result{Status: StatusOK, Anchor: a, Similarity: 1}, nil
+	}
+
+	// Exact content at another position
+	size := a.EndLine - a.StartLine + 1
+	best, found := -1, false
+	for start := 1; start+size-1 <= len(lines); start++ {
+		if Fingerprint(lines[start-1:start-1+size]) != a.Fingerprint {
+			continue
+		}
+		if !found || distance(start, a.StartLine) < distance(best, a.StartLine) {
+			best, found = start, true
+		}
+	}
+	if found {
+		return Result{Status: StatusMoved, Anchor: relocate(a, lines, best, best+size-1), Similarity: 1}, nil
+	}
+
+	// Closest region by shared lines, allowing it to grow or shrink
+	if len(a.LineHashes) == 0 {
+		return Result{Status: StatusLost, Anchor: a}, nil
+	}
+	hashes := make([]string, len(lines))
+	for i, line := range lines {
+		hashes[i] = lineHash(line)
+	}
+	slack := size/4 + 1
+	bestStart, bestEnd, bestScore := 0, 0, 0.0
+	for start := 1; start <= len(lines); start++ {
+		for n := size - slack; n <= size+slack; n++ {
+			end := start + n - 1
+			if n < 1 || end > len(lines) {
+				continue
+			}
+			// The order-blind score bounds the ordered one, so only promising
+			// regions pay for the longest-common-subsequence comparison
+			window := hashes[start-1 : end]
+			if bound := similarity(a.LineHashes, window); bound < MinSimilarity || bound < bestScore {
+				continue
+			}
+			score := orderedSimilarity(a.LineHashes, window)
+			if score > bestScore || (score == bestScore && bestScore > 0 && distance(start, a.StartLine) < distance(bestStart, a.StartLine)) {
+				bestStart, bestEnd, bestScore = start, end, score
+			}
+		}
+	}
+	if bestScore < MinSimilarity {
+		return Result{Status: StatusLost, Anchor: a, Similarity: bestScore}, nil
+	}
+
+	relocated := relocate(a, lines, bestStart, bestEnd)
+	status := StatusDrifted
+	if relocated.Fingerprint == a.Fingerprint {
+		status = StatusMoved
+	}
+	return Result{Status: status, Anchor: relocated, Similarity: bestScore}, nil
+}
+
+// relocate points an anchor at a new region, trimming blank edge lines and refreshing its hashes
+func relocate(a types.Anchor, lines []string, start, end int) types.Anchor {
+	for start < end && normalize(lines[start-1]) == "" {
+		start++
+	}
+	for end > start && normalize(lines[end-1]) == "" {
+		end--
+	}
+	region := lines[start-1 : end]
+	a.StartLine = start
+	a.EndLine = end
+	a.Fingerprint = Fingerprint(region)
+	a.LineHashes = lineHashes(region)
+	return a
+}
+
+// similarity is the Dice coefficient of two multisets of line hashes.
+// Blank lines carry no hash and are ignored.
+func similarity(want, have []string) float64 {
+	counts := make(map[string]int, len(want))
+	for _, h := range want {
+		counts[h]++
+	}
+	shared, total := 0, len(want)
+	for _, h := range have {
+		if h == "" {
+			continue
+		}
+		total++
+		if counts[h] > 0 {
+			counts[h]--
+			shared++
+		}
+	}
+	if total == 0 {
+		return 0
+	}
+	return 2 * float64(shared) / float64(total)
+}
+
+// orderedSimilarity is like similarity but counts only lines that appear in
+// the same order, using the longest common subsequence
+func orderedSimilarity(want, have []string) float64 {
+	var lines []string
+	for _, h := range have {
+		if h != "" {
+			lines = append(lines, h)
+		}
+	}
+	if len(want)+len(lines) == 0 {
+		return 0
+	}
+
+	prev := make([]int, len(lines)+1)
+	cur := make([]int, len(lines)+1)
+	for i := range want {
+		for j := range lines {
+			switch {
+			case want[i] == lines[j]:
+				cur[j+1] = prev[j] + 1
+			case prev[j+1] > cur[j]:
+				cur[j+1] = prev[j+1]
+			default:
+				cur[j+1] = cur[j]
+			}
+		}
+		prev, cur = cur, prev
+	}
+	return 2 * float64(prev[len(lines)]) / float64(len(want)+len(lines))
+}
+
+func lineHashes(lines []string) []string {
+	var hashes []string
+	for _, line := range lines {
+		if h := lineHash(line); h != "" {
+			hashes = append(hashes, h)
+		}
+	}
+	return hashes
+}
+
+// lineHash returns a short hash of a normalized line, or "" for a blank line
+func lineHash(line string) string {
+	n := normalize(line)
+	if n == "" {
+		return ""
+	}
+	h := fnv.New32a()
+	h.Write([]byte(n))
+	return fmt.Sprintf("%08x", h.Sum32())
+}
+
+func normalize(line string) string {
+	return strings.Join(strings.Fields(line), " ")
+}
+
+func distance(a, b int) int {
+	if a > b {
+		return a - b
+	}
+	return b - a
+}
+
+func readLines(repoRoot, file string) ([]string, error) {
+	data, err := os.ReadFile(filepath.Join(repoRoot, filepath.FromSlash(file)))
+	if err != nil {
+		return nil, err
+	}
+	return strings.Split(strings.TrimSuffix(string(data), "\n"), "\n"), nil
+}
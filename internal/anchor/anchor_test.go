@@ -0,0 +1,155 @@
+package anchor
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		spec       string
+		file       string
+		start, end int
+		wantErr    bool
+	}{
+		{spec: "billing/invoice.go:12-20", file: "billing/invoice.go", start: 12, end: 20},
+		{spec: "./main.go:7", file: "main.go", start: 7, end: 7},
+		{spec: "C:/src/main.go:3-4", file: "C:/src/main.go", start: 3, end: 4},
+		{spec: "main.go", wantErr: true},
+		{spec: "main.go:0", wantErr: true},
+		{spec: "main.go:9-3", wantErr: true},
+		{spec: "main.go:a-b", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			file, start, end, err := Parse(tt.spec)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("Parse() = %s, %d, %d, want an error", file, start, end)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if file != tt.file || start != tt.start || end != tt.end {
+				t.Errorf("Parse() = %s, %d, %d, want %s, %d, %d", file, start, end, tt.file, tt.start, tt.end)
+			}
+		})
+	}
+}
+
+var retrySource = []string{
+	"package main",
+	"",
+	"func retry() {",
+	"	for i := 0; i < 3; i++ {",
+	"		if call() == nil {",
+	"			return",
+	"		}",
+	"	}",
+	"}",
+	"",
+	"func other() {}",
+}
+
+func TestCheck(t *testing.T) {
+	tests := []struct {
+		name       string
+		lines      []string // nil removes the file
+		status     Status
+		start, end int
+	}{
+		{
+			name:   "unchanged",
+			lines:  retrySource,
+			status: StatusOK, start: 3, end: 9,
+		},
+		{
+			name:   "reformatted",
+			lines:  replace(retrySource, 3, "func retry()  {  "),
+			status: StatusOK, start: 3, end: 9,
+		},
+		{
+			name:   "moved down",
+			lines:  insert(retrySource, 2, "import \"log\"", "", "var logger = log.Default()", ""),
+			status: StatusMoved, start: 7, end: 13,
+		},
+		{
+			name:   "edited and moved",
+			lines:  insert(replace(retrySource, 4, "	for i := 0; i < 5; i++ {"), 2, "const attempts = 5", ""),
+			status: StatusDrifted, start: 5, end: 11,
+		},
+		{
+			name:   "rewritten",
+			lines:  []string{"package main", "", "func retry() error {", "	return backoff.Retry(call)", "}"},
+			status: StatusLost, start: 3, end: 9,
+		},
+		{
+			name:   "file removed",
+			status: StatusMissing, start: 3, end: 9,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := t.TempDir()
+			writeLines(t, root, retrySource)
+			a, err := New(root, "main.go", 3, 9)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if tt.lines == nil {
+				if err := os.Remove(filepath.Join(root, "main.go")); err != nil {
+					t.Fatal(err)
+				}
+			} else {
+				writeLines(t, root, tt.lines)
+			}
+
+			res, err := Check(root, a)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if res.Status != tt.status || res.Anchor.StartLine != tt.start || res.Anchor.EndLine != tt.end {
+				t.Errorf("Check() = %s at %d-%d, want %s at %d-%d", res.Status, res.Anchor.StartLine, res.Anchor.EndLine, tt.status, tt.start, tt.end)
+			}
+			if res.Status == StatusDrifted && (res.Similarity < MinSimilarity || res.Similarity >= 1) {
+				t.Errorf("similarity = %.2f, want between %.2f and 1", res.Similarity, MinSimilarity)
+			}
+			if res.Status == StatusMoved || res.Status == StatusDrifted {
+				// A relocated anchor matches the code it now points at
+				again, err := Check(root, res.Anchor)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if again.Status != StatusOK {
+					t.Errorf("relocated anchor status = %s, want ok", again.Status)
+				}
+			}
+		})
+	}
+}
+
+func writeLines(t *testing.T, root string, lines []string) {
+	t.Helper()
+	if err := os.WriteFile(filepath.Join(root, "main.go"), []byte(strings.Join(lines, "\n")+"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// replace returns a copy of lines with the given one-based line replaced
+func replace(lines []string, n int, line string) []string {
+	out := append([]string(nil), lines...)
+	out[n-1] = line
+	return out
+}
+
+// insert returns a copy of lines with more lines added after line n
+func insert(lines []string, n int, more ...string) []string {
+	out := append([]string(nil), lines[:n]...)
+	out = append(out, more...)
+	return append(out, lines[n:]...)
+}
@@ -0,0 +1,454 @@
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/tyroneavnit/keel/internal/git"
+	"github.com/tyroneavnit/keel/internal/id"
+	"github.com/tyroneavnit/keel/internal/policy"
+	"github.com/tyroneavnit/keel/internal/store"
+	"github.com/tyroneavnit/keel/internal/types"
+	"gopkg.in/yaml.v3"
+)
+
+const ConfigFile = "config.yaml"
+
+// TypeConfig describes a decision type
+type TypeConfig struct {
+	Description string               `yaml:"description,omitempty" json:"description,omitempty"`
+	Color       string               `yaml:"color,omitempty" json:"color,omitempty"`
+	Required    []string             `yaml:"required,omitempty" json:"required,omitempty"`
+	Fields      map[string]FieldRule `yaml:"fields,omitempty" json:"fields,omitempty"`
+}
+
+// IDConfig controls the format of generated decision IDs
+type IDConfig struct {
+	Prefix string `yaml:"prefix,omitempty" json:"prefix,omitempty"`
+	Length int    `yaml:"length,omitempty" json:"length,omitempty"`
+}
+
+// RefsConfig restricts the external references decisions may carry
+type RefsConfig struct {
+	Patterns []string `yaml:"patterns,omitempty" json:"patterns,omitempty"` // regular expressions; empty = any ref
+}
+
+// OutputConfig controls how commands print results
+type OutputConfig struct {
+	Format string `yaml:"format,omitempty" json:"format,omitempty"` // text | json
+	Color  string `yaml:"color,omitempty" json:"color,omitempty"`   // auto | always | never
+}
+
+// PolicyConfig holds the approval rules and governance toggles
+//
+//	policy:
+//	  approvals:
+//	    - types: [constraint]
+//	      required: 1
+//	    - paths: ["src/billing/**"]
+//	      roles: [agent, human]
+//	      required: 2
+type PolicyConfig struct {
+	Approvals         []policy.ApprovalRule `yaml:"approvals,omitempty" json:"approvals,omitempty"`
+	RequireApprovals  *bool                 `yaml:"require_approvals,omitempty" json:"require_approvals,omitempty"`
+	AllowSelfApproval *bool                 `yaml:"allow_self_approval,omitempty" json:"allow_self_approval,omitempty"`
+}
+
+// IndexConfig controls the derived SQLite index
+type IndexConfig struct {
+	Shared *bool `yaml:"shared,omitempty" json:"shared,omitempty"` // share built indexes across git worktrees
+}
+
+// DefaultsConfig holds the filters search, curate and context use when
+// run without the matching flag
+type DefaultsConfig struct {
+	Type   string `yaml:"type,omitempty" json:"type,omitempty"`     // search, curate
+	Status string `yaml:"status,omitempty" json:"status,omitempty"` // search: active, superseded or all
+	Author string `yaml:"author,omitempty" json:"author,omitempty"` // search, curate, context
+	Limit  int    `yaml:"limit,omitempty" json:"limit,omitempty"`   // search; 0 = no limit
+	Budget int    `yaml:"budget,omitempty" json:"budget,omitempty"` // context --format prompt; 0 = unlimited
+}
+
+// UpstreamConfig names a ledger in another repository whose decisions are
+// indexed read-only here, such as org-wide constraints in a platform repo
+//
+//	upstream:
+//	  platform:
+//	    url: git@github.com:acme/platform.git   # or a local path
+//	    ref: main
+type UpstreamConfig struct {
+	URL  string `yaml:"url" json:"url"`                       // local path or git remote
+	Ref  string `yaml:"ref,omitempty" json:"ref,omitempty"`   // branch, tag or commit; default HEAD
+	Path string `yaml:"path,omitempty" json:"path,omitempty"` // ledger in that repository; default .keel/decisions.jsonl
+}
+
+// Config is the repository configuration stored in .keel/config.yaml
+type Config struct {
+	ID     IDConfig              `yaml:"id,omitempty" json:"id,omitempty"`
+	Types  map[string]TypeConfig `yaml:"types,omitempty" json:"types,omitempty"`
+	Refs   RefsConfig            `yaml:"refs,omitempty" json:"refs,omitempty"`
+	Output OutputConfig          `yaml:"output,omitempty" json:"output,omitempty"`
+	Policy PolicyConfig          `yaml:"policy,omitempty" json:"policy,omitempty"`
+	Index  IndexConfig           `yaml:"index,omitempty" json:"index,omitempty"`
+
+	Defaults DefaultsConfig `yaml:"defaults,omitempty" json:"defaults,omitempty"`
+
+	Upstream map[string]UpstreamConfig `yaml:"upstream,omitempty" json:"upstream,omitempty"`
+
+	refPatterns   []*regexp.Regexp
+	fieldPatterns map[string]*regexp.Regexp // keyed by "<type>.<field>"
+}
+
+// builtinTypes describes the decision types every repository supports
+var builtinTypes = map[types.DecisionType]TypeConfig{
+	types.TypeProduct:    {Description: "Business logic decisions (e.g., \"Free plan = 5 users\")", Color: "blue"},
+	types.TypeProcess:    {Description: "How-to-work decisions (e.g., \"Use functional style\")", Color: "magenta"},
+	types.TypeConstraint: {Description: "Hard limits and requirements (e.g., \"Must support IE11\")", Color: "red"},
+	types.TypeLearning:   {Description: "Failed approaches and discoveries (e.g., \"Redis cache caused OOM\")"},
+}
+
+var colorCodes = map[string]string{
+	"black":   "30",
+	"red":     "31",
+	"green":   "32",
+	"yellow":  "33",
+	"blue":    "34",
+	"magenta": "35",
+	"cyan":    "36",
+	"white":   "37",
+}
+
+// GetConfigPath returns the path to the config file
+func GetConfigPath(repoRoot string) string {
+	return filepath.Join(store.GetKeelDir(repoRoot), ConfigFile)
+}
+
+// Default returns the configuration used when no config file exists
+func Default() *Config {
+	cfg := &Config{}
+	cfg.applyDefaults()
+	return cfg
+}
+
+// Load reads the repository config, filling in defaults for anything unset.
+// A missing file yields the default config.
+func Load(repoRoot string) (*Config, error) {
+	cfg := &Config{}
+
+	data, err := os.ReadFile(GetConfigPath(repoRoot))
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to read config: %w", err)
+	}
+	if err == nil {
+		if err := decode(data, cfg); err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", ConfigFile, err)
+		}
+	}
+
+	cfg.applyDefaults()
+	if err := cfg.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid %s: %w", ConfigFile, err)
+	}
+	return cfg, nil
+}
+
+// decode parses config YAML, rejecting unknown keys so typos surface early
+func decode(data []byte, cfg *Config) error {
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(cfg); err != nil && err != io.EOF {
+		return err
+	}
+	return nil
+}
+
+func (c *Config) applyDefaults() {
+	if c.ID.Prefix == "" {
+		c.ID.Prefix = id.IDPrefix
+	}
+	if c.ID.Length == 0 {
+		c.ID.Length = id.DefaultLength
+	}
+	if c.Output.Format == "" {
+		c.Output.Format = "text"
+	}
+	if c.Output.Color == "" {
+		c.Output.Color = "auto"
+	}
+	if c.Types == nil {
+		c.Types = make(map[string]TypeConfig)
+	}
+	for t, builtin := range builtinTypes {
+		tc := c.Types[string(t)]
+		if tc.Description == "" {
+			tc.Description = builtin.Description
+		}
+		if tc.Color == "" {
+			tc.Color = builtin.Color
+		}
+		c.Types[string(t)] = tc
+	}
+}
+
+// Validate checks the config for values commands cannot act on
+func (c *Config) Validate() error {
+	switch c.Output.Format {
+	case "text", "json":
+	default:
+		return fmt.Errorf("output.format must be text or json, got %q", c.Output.Format)
+	}
+	switch c.Output.Color {
+	case "auto", "always", "never":
+	default:
+		return fmt.Errorf("output.color must be auto, always or never, got %q", c.Output.Color)
+	}
+
+	for name, tc := range c.Types {
+		if !typeNamePattern.MatchString(name) {
+			return fmt.Errorf("invalid type name %q: use lowercase letters, digits and dashes", name)
+		}
+		if tc.Color != "" && ColorCode(tc.Color) == "" {
+			return fmt.Errorf("types.%s.color: unknown color %q", name, tc.Color)
+		}
+		for _, field := range tc.Required {
+			if !IsRequirableField(field) {
+				return fmt.Errorf("types.%s.required: unknown field %q", name, field)
+			}
+		}
+	}
+	if err := c.compileFieldRules(); err != nil {
+		return err
+	}
+	if err := c.validateDefaults(); err != nil {
+		return err
+	}
+
+	if err := c.validateApprovals(); err != nil {
+		return err
+	}
+
+	for name, u := range c.Upstream {
+		if !id.NamespacePattern.MatchString(name) {
+			return fmt.Errorf("invalid upstream name %q: use lowercase letters, digits and dashes", name)
+		}
+		if u.URL == "" {
+			return fmt.Errorf("upstream.%s.url is required", name)
+		}
+		// Both reach git as arguments; a leading dash would be read as an option
+		if strings.HasPrefix(u.URL, "-") {
+			return fmt.Errorf("upstream.%s.url must not start with '-', got %q", name, u.URL)
+		}
+		if u.Ref != "" && !git.CheckRefFormat(u.Ref) {
+			return fmt.Errorf("upstream.%s.ref: %q is not a valid branch, tag or commit", name, u.Ref)
+		}
+	}
+
+	c.refPatterns = nil
+	for _, pattern := range c.Refs.Patterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return fmt.Errorf("refs.patterns: %w", err)
+		}
+		c.refPatterns = append(c.refPatterns, re)
+	}
+
+	return id.ValidateFormat(c.ID.Prefix, c.ID.Length)
+}
+
+var (
+	typeNamePattern = regexp.MustCompile(`^[a-z][a-z0-9-]*$`)
+	rawColorPattern = regexp.MustCompile(`^[0-9;]+$`)
+)
+
+// IDFormat returns the format of the repository's decision IDs
+func (c *Config) IDFormat() id.Format {
+	f, err := id.NewFormat(c.ID.Prefix, c.ID.Length)
+	if err != nil {
+		// Load rejects an invalid format; one set by hand falls back
+		return id.DefaultFormat
+	}
+	return f
+}
+
+// HasType reports whether a decision type is built in or configured
+func (c *Config) HasType(name string) bool {
+	if _, ok := builtinTypes[types.DecisionType(name)]; ok {
+		return true
+	}
+	_, ok := c.Types[name]
+	return ok
+}
+
+// TypeNames returns all configured decision types, built-ins first
+func (c *Config) TypeNames() []string {
+	var names []string
+	for _, t := range types.BuiltinTypes() {
+		names = append(names, string(t))
+	}
+	var custom []string
+	for name := range c.Types {
+		if _, ok := builtinTypes[types.DecisionType(name)]; !ok {
+			custom = append(custom, name)
+		}
+	}
+	sort.Strings(custom)
+	return append(names, custom...)
+}
+
+// Type returns the configuration for a decision type
+func (c *Config) Type(t types.DecisionType) TypeConfig {
+	return c.Types[string(t)]
+}
+
+// ColorEnabled reports whether ANSI colors should be printed. In auto mode
+// that is only when output goes to a terminal and NO_COLOR is unset.
+func (c *Config) ColorEnabled(terminal bool) bool {
+	switch c.Output.Color {
+	case "always":
+		return true
+	case "never":
+		return false
+	}
+	return terminal && os.Getenv("NO_COLOR") == ""
+}
+
+// RequireApprovals reports whether policy approval rules are enforced
+func (c *Config) RequireApprovals() bool {
+	return c.Policy.RequireApprovals == nil || *c.Policy.RequireApprovals
+}
+
+// AllowSelfApproval reports whether authors may approve their own decisions
+func (c *Config) AllowSelfApproval() bool {
+	return c.Policy.AllowSelfApproval != nil && *c.Policy.AllowSelfApproval
+}
+
+// SharedIndex reports whether worktrees share built indexes through the
+// common git directory
+func (c *Config) SharedIndex() bool {
+	return c.Index.Shared != nil && *c.Index.Shared
+}
+
+// validateApprovals checks that approval rules name known types and roles
+// and valid path patterns, and ask for at least one approval
+func (c *Config) validateApprovals() error {
+	for i, rule := range c.Policy.Approvals {
+		if rule.Required < 1 {
+			return fmt.Errorf("policy.approvals[%d].required must be at least 1, got %d", i, rule.Required)
+		}
+		for _, t := range rule.Types {
+			if !c.HasType(string(t)) {
+				return fmt.Errorf("policy.approvals[%d].types: unknown type %q", i, t)
+			}
+		}
+		for _, role := range rule.Roles {
+			if role != "agent" && role != "human" {
+				return fmt.Errorf("policy.approvals[%d].roles must be agent or human, got %q", i, role)
+			}
+		}
+		for _, pattern := range rule.Paths {
+			if _, err := path.Match(pattern, ""); err != nil {
+				return fmt.Errorf("policy.approvals[%d].paths: invalid pattern %q", i, pattern)
+			}
+		}
+	}
+	return nil
+}
+
+// Approvals returns the approval policy, which is empty when
+// policy.require_approvals is turned off
+func (c *Config) Approvals() *policy.Policy {
+	if !c.RequireApprovals() {
+		return &policy.Policy{}
+	}
+	return &policy.Policy{Approvals: c.Policy.Approvals}
+}
+
+// validateDefaults checks the default filters the way the commands would
+func (c *Config) validateDefaults() error {
+	d := c.Defaults
+	if d.Type != "" && !c.HasType(d.Type) {
+		return fmt.Errorf("defaults.type: unknown type %q", d.Type)
+	}
+	switch d.Status {
+	case "", "active", "superseded", "all":
+	default:
+		return fmt.Errorf("defaults.status must be active, superseded or all, got %q", d.Status)
+	}
+	if d.Limit < 0 {
+		return fmt.Errorf("defaults.limit must not be negative, got %d", d.Limit)
+	}
+	if d.Budget < 0 {
+		return fmt.Errorf("defaults.budget must not be negative, got %d", d.Budget)
+	}
+	return nil
+}
+
+// Flags returns the configured defaults keyed by the flag they fill in
+func (d DefaultsConfig) Flags() map[string]string {
+	flags := make(map[string]string)
+	if d.Type != "" {
+		flags["type"] = d.Type
+	}
+	if d.Status != "" {
+		flags["status"] = d.Status
+	}
+	if d.Author != "" {
+		flags["author"] = d.Author
+	}
+	if d.Limit > 0 {
+		flags["limit"] = strconv.Itoa(d.Limit)
+	}
+	if d.Budget > 0 {
+		flags["budget"] = strconv.Itoa(d.Budget)
+	}
+	return flags
+}
+
+// UpstreamNames returns the configured upstream names, sorted
+func (c *Config) UpstreamNames() []string {
+	names := make([]string, 0, len(c.Upstream))
+	for name := range c.Upstream {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ValidateRefs checks refs against the configured ref patterns
+func (c *Config) ValidateRefs(refs []string) error {
+	if len(c.refPatterns) == 0 {
+		return nil
+	}
+	for _, ref := range refs {
+		matched := false
+		for _, re := range c.refPatterns {
+			if re.MatchString(ref) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return fmt.Errorf("ref %q does not match any configured pattern: %s", ref, strings.Join(c.Refs.Patterns, ", "))
+		}
+	}
+	return nil
+}
+
+// ColorCode returns the ANSI code for a color name or raw numeric code
+func ColorCode(color string) string {
+	if code, ok := colorCodes[strings.ToLower(color)]; ok {
+		return code
+	}
+	if rawColorPattern.MatchString(color) {
+		return color
+	}
+	return ""
+}
@@ -0,0 +1,160 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/tyroneavnit/keel/internal/types"
+)
+
+// writeConfig creates a repository whose config file holds content, or
+// none when content is empty
+func writeConfig(t *testing.T, content string) string {
+	t.Helper()
+	root := t.TempDir()
+	if content == "" {
+		return root
+	}
+	if err := os.MkdirAll(filepath.Dir(GetConfigPath(root)), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(GetConfigPath(root), []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return root
+}
+
+func TestLoad(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		wantErr string
+		check   func(t *testing.T, c *Config)
+	}{
+		{
+			name: "no config file",
+			check: func(t *testing.T, c *Config) {
+				if c.ID.Prefix != "DEC" || c.ID.Length != 4 {
+					t.Errorf("id = %+v, want DEC and 4", c.ID)
+				}
+				if c.Output.Format != "text" || c.Output.Color != "auto" {
+					t.Errorf("output = %+v, want text and auto", c.Output)
+				}
+				if !c.RequireApprovals() || c.AllowSelfApproval() || c.SharedIndex() {
+					t.Errorf("policy = %+v, index = %+v", c.Policy, c.Index)
+				}
+			},
+		},
+		{
+			name:    "custom ID format and type",
+			content: "id:\n  prefix: ADR\n  length: 6\ntypes:\n  security:\n    description: Security requirements\n    color: cyan\n",
+			check: func(t *testing.T, c *Config) {
+				if f := c.IDFormat(); f.Prefix != "ADR" || f.Length != 6 {
+					t.Errorf("IDFormat() = %+v, want ADR and 6", f)
+				}
+				want := []string{"product", "process", "constraint", "learning", "security"}
+				if got := c.TypeNames(); !reflect.DeepEqual(got, want) {
+					t.Errorf("TypeNames() = %v, want %v", got, want)
+				}
+				if c.Types["product"].Color != "blue" {
+					t.Errorf("built-in product color = %q, want blue", c.Types["product"].Color)
+				}
+			},
+		},
+		{
+			name:    "defaults",
+			content: "types:\n  security: {}\ndefaults:\n  type: security\n  status: all\n  limit: 20\n",
+			check: func(t *testing.T, c *Config) {
+				want := map[string]string{"type": "security", "status": "all", "limit": "20"}
+				if got := c.Defaults.Flags(); !reflect.DeepEqual(got, want) {
+					t.Errorf("Defaults.Flags() = %v, want %v", got, want)
+				}
+			},
+		},
+		{
+			name:    "approval rules",
+			content: "types:\n  security: {}\npolicy:\n  approvals:\n    - types: [security]\n      required: 1\n    - paths: [\"billing/**\"]\n      roles: [agent, human]\n      required: 2\n",
+			check: func(t *testing.T, c *Config) {
+				d := &types.Decision{Type: "security", Anchors: []types.Anchor{{File: "billing/tax.go", StartLine: 1, EndLine: 4}}, DecidedBy: types.DecidedBy{Role: "human"}}
+				if got := c.Approvals().RequiredApprovals(d); got != 2 {
+					t.Errorf("RequiredApprovals() = %d, want 2", got)
+				}
+				off := false
+				c.Policy.RequireApprovals = &off
+				if got := c.Approvals().RequiredApprovals(d); got != 0 {
+					t.Errorf("RequiredApprovals() with approvals off = %d, want 0", got)
+				}
+			},
+		},
+		{name: "unknown key", content: "ouptut:\n  format: json\n", wantErr: "field ouptut not found"},
+		{name: "output format", content: "output:\n  format: xml\n", wantErr: "output.format"},
+		{name: "type color", content: "types:\n  security:\n    color: mauve\n", wantErr: "unknown color"},
+		{name: "type name", content: "types:\n  Security: {}\n", wantErr: "invalid type name"},
+		{name: "required field", content: "types:\n  security:\n    required: [owner]\n", wantErr: `unknown field "owner"`},
+		{name: "field no input sets", content: "types:\n  experiment:\n    required: [hypothesis]\n", wantErr: `unknown field "hypothesis"`},
+		{name: "ref pattern", content: "refs:\n  patterns: [\"(\"]\n", wantErr: "refs.patterns"},
+		{name: "ID length", content: "id:\n  length: 12\n", wantErr: "length"},
+		{name: "upstream URL", content: "upstream:\n  platform: {ref: main}\n", wantErr: "upstream.platform.url is required"},
+		{name: "upstream URL option", content: "upstream:\n  platform: {url: --upload-pack=evil}\n", wantErr: "upstream.platform.url must not start with '-'"},
+		{name: "upstream ref option", content: "upstream:\n  platform: {url: ../platform, ref: \"--upload-pack=touch /tmp/x; git-upload-pack\"}\n", wantErr: "upstream.platform.ref"},
+		{name: "upstream ref format", content: "upstream:\n  platform: {url: ../platform, ref: \"main..v2\"}\n", wantErr: "upstream.platform.ref"},
+		{name: "approval count", content: "policy:\n  approvals:\n    - types: [constraint]\n", wantErr: "policy.approvals[0].required"},
+		{name: "approval type", content: "policy:\n  approvals:\n    - {types: [security], required: 1}\n", wantErr: `unknown type "security"`},
+		{name: "approval role", content: "policy:\n  approvals:\n    - {roles: [bot], required: 1}\n", wantErr: "policy.approvals[0].roles"},
+		{name: "approval path", content: "policy:\n  approvals:\n    - {paths: [\"[\"], required: 1}\n", wantErr: "policy.approvals[0].paths"},
+		{name: "default type", content: "defaults:\n  type: security\n", wantErr: "defaults.type"},
+		{name: "default status", content: "defaults:\n  status: open\n", wantErr: "defaults.status"},
+		{name: "default limit", content: "defaults:\n  limit: -1\n", wantErr: "defaults.limit"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := Load(writeConfig(t, tt.content))
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Load() err = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			tt.check(t, c)
+		})
+	}
+}
+
+func TestSet(t *testing.T) {
+	root := writeConfig(t, "# Team settings\noutput:\n  format: text\n")
+
+	if err := Set(root, "output.format", "json"); err != nil {
+		t.Fatal(err)
+	}
+	if err := Set(root, "types.security.required", "[rationale, refs]"); err != nil {
+		t.Fatal(err)
+	}
+	if err := Set(root, "output.color", "sometimes"); err == nil {
+		t.Error("Set(output.color, sometimes) succeeded, want an error")
+	}
+
+	data, err := os.ReadFile(GetConfigPath(root))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(string(data), "# Team settings\n") {
+		t.Errorf("comment not preserved:\n%s", data)
+	}
+
+	c, err := Load(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Output.Format != "json" || c.Output.Color != "auto" {
+		t.Errorf("output = %+v, want json and auto", c.Output)
+	}
+	if got := c.Types["security"].Required; !reflect.DeepEqual(got, []string{"rationale", "refs"}) {
+		t.Errorf("types.security.required = %v", got)
+	}
+}
@@ -0,0 +1,119 @@
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Get returns the value at a dotted key (e.g. "output.format") in the
+// effective config. An empty key returns the whole config.
+func (c *Config) Get(key string) (interface{}, error) {
+	data, err := yaml.Marshal(c)
+	if err != nil {
+		return nil, err
+	}
+	var value interface{}
+	if err := yaml.Unmarshal(data, &value); err != nil {
+		return nil, err
+	}
+
+	if key == "" {
+		return value, nil
+	}
+	for _, part := range strings.Split(key, ".") {
+		m, ok := value.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("unknown config key: %s", key)
+		}
+		if value, ok = m[part]; !ok {
+			return nil, fmt.Errorf("unknown config key: %s", key)
+		}
+	}
+	return value, nil
+}
+
+// Set writes a value at a dotted key to the repository config file.
+// The value is parsed as YAML, so "true", "6" and "[a, b]" keep their types.
+// Comments and ordering in the existing file are preserved.
+func Set(repoRoot, key, value string) error {
+	if key == "" {
+		return fmt.Errorf("config key is required")
+	}
+
+	path := GetConfigPath(repoRoot)
+	var doc yaml.Node
+	data, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to read config: %w", err)
+	}
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return fmt.Errorf("failed to parse %s: %w", ConfigFile, err)
+	}
+	if doc.Kind == 0 {
+		doc = yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{{Kind: yaml.MappingNode}}}
+	}
+
+	var valueNode yaml.Node
+	if err := yaml.Unmarshal([]byte(value), &valueNode); err != nil {
+		return fmt.Errorf("invalid value: %w", err)
+	}
+	newValue := &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!null", Value: ""}
+	if len(valueNode.Content) > 0 {
+		newValue = valueNode.Content[0]
+	}
+
+	node := doc.Content[0]
+	parts := strings.Split(key, ".")
+	for i, part := range parts {
+		if node.Kind != yaml.MappingNode {
+			return fmt.Errorf("cannot set %s: %s is not a mapping", key, strings.Join(parts[:i], "."))
+		}
+		child := mappingValue(node, part)
+		if i == len(parts)-1 {
+			if child != nil {
+				*child = *newValue
+			} else {
+				node.Content = append(node.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: part}, newValue)
+			}
+			break
+		}
+		if child == nil {
+			child = &yaml.Node{Kind: yaml.MappingNode}
+			node.Content = append(node.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: part}, child)
+		}
+		node = child
+	}
+
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(&doc); err != nil {
+		return err
+	}
+	out := buf.Bytes()
+
+	// Reject changes that would leave the config unloadable
+	var check Config
+	if err := decode(out, &check); err != nil {
+		return fmt.Errorf("invalid value for %s: %w", key, err)
+	}
+	check.applyDefaults()
+	if err := check.Validate(); err != nil {
+		return err
+	}
+
+	return os.WriteFile(path, out, 0644)
+}
+
+func mappingValue(node *yaml.Node, key string) *yaml.Node {
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i+1]
+		}
+	}
+	return nil
+}
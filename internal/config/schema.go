@@ -0,0 +1,155 @@
+package config
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+
+	"github.com/tyroneavnit/keel/internal/types"
+)
+
+// FieldRule constrains one field of decisions of a given type.
+// A rule makes the field required: it must carry at least Min values
+// (default 1), counting only values that match Pattern when one is set.
+//
+//	types:
+//	  security:
+//	    fields:
+//	      refs: {pattern: "^THREAT-"}   # must cite a threat model entry
+//	  api-contract:
+//	    fields:
+//	      symbols: {min: 1}
+type FieldRule struct {
+	Min     int    `yaml:"min,omitempty" json:"min,omitempty"`
+	Pattern string `yaml:"pattern,omitempty" json:"pattern,omitempty"`
+}
+
+// requirableFields are the decision fields a type may require: those keel
+// decide and the APIs can set. Anchored files count as files.
+var requirableFields = []string{"rationale", "files", "symbols", "refs", "review_by", "expires_at"}
+
+// IsRequirableField reports whether a field name can be used in a type schema
+func IsRequirableField(field string) bool {
+	for _, f := range requirableFields {
+		if f == field {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *Config) compileFieldRules() error {
+	c.fieldPatterns = make(map[string]*regexp.Regexp)
+	for name, tc := range c.Types {
+		for field, rule := range tc.Fields {
+			if !IsRequirableField(field) {
+				return fmt.Errorf("types.%s.fields: unknown field %q", name, field)
+			}
+			if rule.Min < 0 {
+				return fmt.Errorf("types.%s.fields.%s.min must not be negative", name, field)
+			}
+			if rule.Pattern == "" {
+				continue
+			}
+			re, err := regexp.Compile(rule.Pattern)
+			if err != nil {
+				return fmt.Errorf("types.%s.fields.%s.pattern: %w", name, field, err)
+			}
+			c.fieldPatterns[name+"."+field] = re
+		}
+	}
+	return nil
+}
+
+// CheckDecision returns every way a decision breaks the repository config:
+// an undeclared type, missing required fields, unmet field rules, or refs
+// outside the configured patterns. An empty result means the decision is valid.
+func (c *Config) CheckDecision(d *types.Decision) []string {
+	var problems []string
+
+	tc, ok := c.Types[string(d.Type)]
+	if !ok {
+		problems = append(problems, fmt.Sprintf("unknown type %q", d.Type))
+	}
+
+	for _, field := range tc.Required {
+		if len(fieldValues(d, field)) == 0 {
+			problems = append(problems, fmt.Sprintf("%s is required", field))
+		}
+	}
+
+	for _, field := range sortedKeys(tc.Fields) {
+		rule := tc.Fields[field]
+		min := rule.Min
+		if min == 0 {
+			min = 1
+		}
+		re := c.fieldPatterns[string(d.Type)+"."+field]
+
+		count := 0
+		for _, v := range fieldValues(d, field) {
+			if re == nil || re.MatchString(v) {
+				count++
+			}
+		}
+		if count >= min {
+			continue
+		}
+		if re != nil {
+			problems = append(problems, fmt.Sprintf("%s needs at least %d value(s) matching %s", field, min, rule.Pattern))
+		} else {
+			problems = append(problems, fmt.Sprintf("%s needs at least %d value(s)", field, min))
+		}
+	}
+
+	if err := c.ValidateRefs(d.Refs); err != nil {
+		problems = append(problems, err.Error())
+	}
+
+	return problems
+}
+
+// fieldValues returns the non-empty values a decision carries for a field
+func fieldValues(d *types.Decision, field string) []string {
+	var values []string
+	addString := func(s *string) {
+		if s != nil && *s != "" {
+			values = append(values, *s)
+		}
+	}
+
+	switch field {
+	case "rationale":
+		addString(d.Rationale)
+	case "files":
+		seen := make(map[string]bool)
+		for _, f := range d.Files {
+			seen[f] = true
+		}
+		values = append(values, d.Files...)
+		for _, a := range d.Anchors {
+			if !seen[a.File] {
+				seen[a.File] = true
+				values = append(values, a.File)
+			}
+		}
+	case "symbols":
+		values = append(values, d.Symbols...)
+	case "refs":
+		values = append(values, d.Refs...)
+	case "review_by":
+		addString(d.ReviewBy)
+	case "expires_at":
+		addString(d.ExpiresAt)
+	}
+	return values
+}
+
+func sortedKeys(m map[string]FieldRule) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
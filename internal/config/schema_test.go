@@ -0,0 +1,86 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/tyroneavnit/keel/internal/types"
+)
+
+func TestCheckDecision(t *testing.T) {
+	c, err := Load(writeConfig(t, `types:
+  security:
+    required: [rationale]
+    fields:
+      refs: {pattern: "^THREAT-"}
+  api-contract:
+    fields:
+      symbols: {min: 2}
+  data-retention:
+    fields:
+      files: {min: 2}
+refs:
+  patterns: ["^(THREAT|JIRA)-[0-9]+$"]
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	rationale := "Attackers replay tokens"
+
+	tests := []struct {
+		name string
+		d    types.Decision
+		want []string
+	}{
+		{
+			name: "built-in type",
+			d:    types.Decision{Type: types.TypeProduct, Refs: []string{"JIRA-1"}},
+		},
+		{
+			name: "unknown type",
+			d:    types.Decision{Type: "experiment"},
+			want: []string{`unknown type "experiment"`},
+		},
+		{
+			name: "valid security decision",
+			d:    types.Decision{Type: "security", Rationale: &rationale, Refs: []string{"JIRA-1", "THREAT-7"}},
+		},
+		{
+			name: "missing required field and pattern",
+			d:    types.Decision{Type: "security", Refs: []string{"JIRA-1"}},
+			want: []string{"rationale is required", "refs needs at least 1 value(s) matching ^THREAT-"},
+		},
+		{
+			name: "minimum count",
+			d:    types.Decision{Type: "api-contract", Symbols: []string{"api.Handler"}},
+			want: []string{"symbols needs at least 2 value(s)"},
+		},
+		{
+			name: "anchored files count as files",
+			d: types.Decision{Type: "data-retention", Files: []string{"store/ttl.go"}, Anchors: []types.Anchor{
+				{File: "store/ttl.go", StartLine: 1, EndLine: 9},
+				{File: "jobs/purge.go", StartLine: 4, EndLine: 20},
+			}},
+		},
+		{
+			name: "one file anchored twice",
+			d: types.Decision{Type: "data-retention", Anchors: []types.Anchor{
+				{File: "store/ttl.go", StartLine: 1, EndLine: 9},
+				{File: "store/ttl.go", StartLine: 30, EndLine: 40},
+			}},
+			want: []string{"files needs at least 2 value(s)"},
+		},
+		{
+			name: "ref outside patterns",
+			d:    types.Decision{Type: types.TypeProduct, Refs: []string{"gh-12"}},
+			want: []string{`ref "gh-12" does not match any configured pattern: ^(THREAT|JIRA)-[0-9]+$`},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := c.CheckDecision(&tt.d); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("CheckDecision() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
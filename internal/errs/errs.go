@@ -0,0 +1,89 @@
+// Package errs defines the error kinds keel reports to callers. Each kind
+// has a stable exit code and a machine-readable code for JSON output, so
+// agents can branch on failures without parsing messages.
+package errs
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Kind classifies an error
+type Kind string
+
+const (
+	Internal         Kind = "error"             // anything not classified below
+	Usage            Kind = "usage"             // bad flags or arguments
+	NotInitialized   Kind = "not_initialized"   // no .keel/decisions.jsonl
+	NotFound         Kind = "not_found"         // no decision with the given ID
+	InvalidID        Kind = "invalid_id"        // malformed decision ID
+	Conflict         Kind = "conflict"          // the ledger's state doesn't allow the change
+	ValidationFailed Kind = "validation_failed" // input or ledger failed a check
+)
+
+// exitCodes are part of keel's interface; never renumber them
+var exitCodes = map[Kind]int{
+	Internal:         1,
+	Usage:            2,
+	NotInitialized:   3,
+	NotFound:         4,
+	InvalidID:        5,
+	Conflict:         6,
+	ValidationFailed: 7,
+}
+
+// Error is an error with a kind
+type Error struct {
+	Kind    Kind
+	Message string
+	Err     error
+
+	// Reported means the command already showed the failure, e.g. a list of
+	// validation issues, so only the exit code (and JSON error) remain
+	Reported bool
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// New returns an error of the given kind
+func New(kind Kind, format string, args ...interface{}) *Error {
+	err := fmt.Errorf(format, args...)
+	return &Error{Kind: kind, Message: err.Error(), Err: errors.Unwrap(err)}
+}
+
+// Reported returns an error of the given kind for a failure the command has
+// already shown to the user
+func Reported(kind Kind, format string, args ...interface{}) *Error {
+	e := New(kind, format, args...)
+	e.Reported = true
+	return e
+}
+
+// KindOf returns the kind of the first typed error in err's chain, or Internal
+func KindOf(err error) Kind {
+	var e *Error
+	if errors.As(err, &e) {
+		return e.Kind
+	}
+	return Internal
+}
+
+// IsReported reports whether the command already showed the failure
+func IsReported(err error) bool {
+	var e *Error
+	return errors.As(err, &e) && e.Reported
+}
+
+// ExitCode returns the process exit code for err, or 0 for nil
+func ExitCode(err error) int {
+	if err == nil {
+		return 0
+	}
+	return exitCodes[KindOf(err)]
+}
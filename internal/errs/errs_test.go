@@ -0,0 +1,52 @@
+package errs
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"testing"
+)
+
+func TestKinds(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		kind     Kind
+		exit     int
+		reported bool
+	}{
+		{name: "nil", err: nil, kind: Internal, exit: 0},
+		{name: "plain error", err: errors.New("disk full"), kind: Internal, exit: 1},
+		{name: "usage", err: New(Usage, "unknown flag"), kind: Usage, exit: 2},
+		{name: "not initialized", err: New(NotInitialized, "run keel init"), kind: NotInitialized, exit: 3},
+		{name: "not found", err: New(NotFound, "no decision DEC-0001"), kind: NotFound, exit: 4},
+		{name: "invalid ID", err: New(InvalidID, "bad ID"), kind: InvalidID, exit: 5},
+		{name: "conflict", err: New(Conflict, "already superseded"), kind: Conflict, exit: 6},
+		{name: "validation", err: New(ValidationFailed, "rationale is required"), kind: ValidationFailed, exit: 7},
+		{name: "wrapped", err: fmt.Errorf("amend: %w", New(NotFound, "no decision DEC-0001")), kind: NotFound, exit: 4},
+		{name: "reported", err: Reported(ValidationFailed, "3 issues"), kind: ValidationFailed, exit: 7, reported: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := KindOf(tt.err); got != tt.kind {
+				t.Errorf("KindOf() = %s, want %s", got, tt.kind)
+			}
+			if got := ExitCode(tt.err); got != tt.exit {
+				t.Errorf("ExitCode() = %d, want %d", got, tt.exit)
+			}
+			if got := IsReported(tt.err); got != tt.reported {
+				t.Errorf("IsReported() = %v, want %v", got, tt.reported)
+			}
+		})
+	}
+}
+
+func TestNewWraps(t *testing.T) {
+	err := New(NotInitialized, "failed to open ledger: %w", os.ErrNotExist)
+	if err.Error() != "failed to open ledger: file does not exist" {
+		t.Errorf("Error() = %q", err.Error())
+	}
+	if !errors.Is(err, os.ErrNotExist) {
+		t.Error("errors.Is(err, os.ErrNotExist) = false, want true")
+	}
+}
@@ -0,0 +1,206 @@
+package git
+
+import (
+	"bytes"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Run executes git in dir and returns its trimmed standard output.
+// On failure the error carries git's own message.
+func Run(dir string, args ...string) (string, error) {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", fmt.Errorf("git %s: %s", args[0], msg)
+		}
+		return "", fmt.Errorf("git %s: %w", args[0], err)
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// StagedFiles lists files staged for commit, relative to dir
+func StagedFiles(dir string) ([]string, error) {
+	return diffNames(dir, "--cached")
+}
+
+// DiffFiles lists files changed in a revision range (e.g. main...HEAD), relative to dir
+func DiffFiles(dir, rng string) ([]string, error) {
+	return diffNames(dir, rng)
+}
+
+func diffNames(dir string, args ...string) ([]string, error) {
+	out, err := Run(dir, append([]string{"diff", "--name-only", "--relative"}, args...)...)
+	if err != nil {
+		return nil, err
+	}
+	return lines(out), nil
+}
+
+func lines(out string) []string {
+	var result []string
+	for _, line := range strings.Split(out, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			result = append(result, line)
+		}
+	}
+	return result
+}
+
+// IsRepo reports whether dir is inside a git work tree. Unlike checking for
+// a .git directory, this also holds in linked worktrees and submodules,
+// where .git is a file pointing elsewhere.
+func IsRepo(dir string) bool {
+	out, err := Run(dir, "rev-parse", "--is-inside-work-tree")
+	return err == nil && out == "true"
+}
+
+// CommonDir returns the git directory shared by every worktree of the
+// repository containing dir. A submodule has its own.
+func CommonDir(dir string) (string, error) {
+	out, err := Run(dir, "rev-parse", "--git-common-dir")
+	if err != nil {
+		return "", err
+	}
+	path := filepath.FromSlash(out)
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(dir, path)
+	}
+	return path, nil
+}
+
+// ResolveCommit returns the full hash of the commit a revision names, or
+// "" when it names none
+func ResolveCommit(dir, rev string) string {
+	out, err := Run(dir, "rev-parse", "--verify", "--quiet", rev+"^{commit}")
+	if err != nil {
+		return ""
+	}
+	return out
+}
+
+// CheckRefFormat reports whether name is acceptable as a branch or tag
+// name, such as main, v1.2 or refs/heads/main. Commit hashes pass too.
+func CheckRefFormat(name string) bool {
+	if strings.HasPrefix(name, "-") {
+		return false
+	}
+	_, err := Run("", "check-ref-format", "--allow-onelevel", name)
+	return err == nil
+}
+
+// TreeFiles lists every file in a commit, relative to the repository root
+func TreeFiles(dir, commit string) ([]string, error) {
+	out, err := Run(dir, "ls-tree", "-r", "--name-only", "--full-tree", commit)
+	if err != nil {
+		return nil, err
+	}
+	return lines(out), nil
+}
+
+// Show returns a file's content at a commit. The path is relative to the
+// repository root.
+func Show(dir, commit, path string) ([]byte, error) {
+	cmd := exec.Command("git", "show", commit+":"+path)
+	cmd.Dir = dir
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("git show: %s", msg)
+		}
+		return nil, fmt.Errorf("git show: %w", err)
+	}
+	return out, nil
+}
+
+// BlameLine is the commit that last changed one line of a file
+type BlameLine struct {
+	Commit  string
+	Author  string
+	Email   string
+	Time    time.Time // author time
+	Summary string    // first line of the commit message
+}
+
+// Committed reports whether the line is in a commit rather than only in
+// the working tree
+func (b BlameLine) Committed() bool {
+	return strings.Trim(b.Commit, "0") != ""
+}
+
+// Blame returns the commit that last changed each line of a file, indexed
+// by line number from zero. With rev empty the working tree copy is blamed,
+// and uncommitted lines carry a zero commit.
+func Blame(dir, rev, path string) ([]BlameLine, error) {
+	args := []string{"blame", "--line-porcelain"}
+	if rev != "" {
+		args = append(args, rev)
+	}
+	out, err := Run(dir, append(args, "--", path)...)
+	if err != nil {
+		return nil, err
+	}
+
+	var result []BlameLine
+	var current BlameLine
+	header := true
+	for _, line := range strings.Split(out, "\n") {
+		if header {
+			// Each entry starts "<commit> <orig line> <final line> [<count>]"
+			current = BlameLine{Commit: strings.SplitN(line, " ", 2)[0]}
+			header = false
+			continue
+		}
+		if strings.HasPrefix(line, "\t") {
+			result = append(result, current)
+			header = true
+			continue
+		}
+		key, value, _ := strings.Cut(line, " ")
+		switch key {
+		case "author":
+			current.Author = value
+		case "author-mail":
+			current.Email = strings.Trim(value, "<>")
+		case "author-time":
+			if secs, err := strconv.ParseInt(value, 10, 64); err == nil {
+				current.Time = time.Unix(secs, 0).UTC()
+			}
+		case "summary":
+			current.Summary = value
+		}
+	}
+	return result, nil
+}
+
+// MergeBase returns the best common ancestor of two revisions
+func MergeBase(dir, a, b string) (string, error) {
+	return Run(dir, "merge-base", a, b)
+}
+
+// MergedBy returns the message of the merge commit that brought a commit
+// into HEAD, or "" when it was committed to HEAD's own line of history
+func MergedBy(dir, commit string) (string, error) {
+	// The oldest commit on HEAD's first-parent line that contains commit
+	// either merged it or simply follows it on that line
+	out, err := Run(dir, "rev-list", "--first-parent", "--ancestry-path", "--reverse", commit+"..HEAD")
+	if err != nil || out == "" {
+		return "", err
+	}
+	merge, _, _ := strings.Cut(out, "\n")
+	base, err := MergeBase(dir, merge+"^1", commit)
+	if err != nil || base == commit {
+		return "", err
+	}
+	return Run(dir, "log", "-1", "--format=%B", merge)
+}
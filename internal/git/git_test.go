@@ -0,0 +1,187 @@
+package git
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// testRepo creates a repository in a temporary directory. Commits made
+// through commit get fixed authors and dates.
+type testRepo struct {
+	t   *testing.T
+	dir string
+}
+
+func newTestRepo(t *testing.T) *testRepo {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	dir, err := filepath.EvalSymlinks(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := &testRepo{t: t, dir: dir}
+	r.git("init", "--quiet", "--initial-branch", "main")
+	return r
+}
+
+func (r *testRepo) git(args ...string) string {
+	r.t.Helper()
+	out, err := Run(r.dir, append([]string{"-c", "user.name=Ana", "-c", "user.email=ana@example.com"}, args...)...)
+	if err != nil {
+		r.t.Fatal(err)
+	}
+	return out
+}
+
+func (r *testRepo) commit(file, content, message, date string) string {
+	r.t.Helper()
+	if err := os.WriteFile(filepath.Join(r.dir, file), []byte(content), 0644); err != nil {
+		r.t.Fatal(err)
+	}
+	r.git("add", file)
+	r.t.Setenv("GIT_AUTHOR_DATE", date)
+	r.t.Setenv("GIT_COMMITTER_DATE", date)
+	r.git("commit", "--quiet", "-m", message)
+	return r.git("rev-parse", "HEAD")
+}
+
+func TestBlame(t *testing.T) {
+	r := newTestRepo(t)
+	first := r.commit("ledger.jsonl", "one\ntwo\n", "Record two decisions", "2026-01-01T10:00:00Z")
+	second := r.commit("ledger.jsonl", "one\ntwo\nthree\n", "Record a third\n\nWith a body", "2026-02-01T10:00:00Z")
+	if err := os.WriteFile(filepath.Join(r.dir, "ledger.jsonl"), []byte("one\ntwo\nthree\nfour\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		rev     string
+		commits []string // "" for an uncommitted line
+	}{
+		{name: "working tree", commits: []string{first, first, second, ""}},
+		{name: "at HEAD", rev: "HEAD", commits: []string{first, first, second}},
+		{name: "at first commit", rev: first, commits: []string{first, first}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lines, err := Blame(r.dir, tt.rev, "ledger.jsonl")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(lines) != len(tt.commits) {
+				t.Fatalf("Blame() returned %d lines, want %d", len(lines), len(tt.commits))
+			}
+			for i, want := range tt.commits {
+				got := lines[i]
+				if want == "" {
+					if got.Committed() {
+						t.Errorf("line %d committed in %s, want uncommitted", i+1, got.Commit)
+					}
+					continue
+				}
+				if got.Commit != want || !got.Committed() {
+					t.Errorf("line %d commit = %s, want %s", i+1, got.Commit, want)
+				}
+				if got.Author != "Ana" || got.Email != "ana@example.com" {
+					t.Errorf("line %d author = %s <%s>", i+1, got.Author, got.Email)
+				}
+			}
+		})
+	}
+
+	lines, err := Blame(r.dir, "HEAD", "ledger.jsonl")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := time.Date(2026, 2, 1, 10, 0, 0, 0, time.UTC); !lines[2].Time.Equal(want) || lines[2].Summary != "Record a third" {
+		t.Errorf("line 3 = %s %q, want %s %q", lines[2].Time, lines[2].Summary, want, "Record a third")
+	}
+}
+
+func TestMergedBy(t *testing.T) {
+	r := newTestRepo(t)
+	base := r.commit("a.txt", "a\n", "Start", "2026-01-01T10:00:00Z")
+	r.git("checkout", "--quiet", "-b", "feature")
+	feature := r.commit("b.txt", "b\n", "Add b", "2026-01-02T10:00:00Z")
+	r.git("checkout", "--quiet", "main")
+	direct := r.commit("c.txt", "c\n", "Add c", "2026-01-03T10:00:00Z")
+	r.git("merge", "--quiet", "--no-ff", "-m", "Merge pull request #42 from acme/feature", "feature")
+	head := r.commit("d.txt", "d\n", "Add d", "2026-01-04T10:00:00Z")
+
+	tests := []struct {
+		name   string
+		commit string
+		want   string
+	}{
+		{name: "merged from a branch", commit: feature, want: "Merge pull request #42 from acme/feature"},
+		{name: "committed on main", commit: direct},
+		{name: "before the branch", commit: base},
+		{name: "HEAD itself", commit: head},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := MergedBy(r.dir, tt.commit)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("MergedBy() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWorktrees(t *testing.T) {
+	r := newTestRepo(t)
+	r.commit("a.txt", "a\n", "Start", "2026-01-01T10:00:00Z")
+	if err := os.Mkdir(filepath.Join(r.dir, "sub"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	linked := filepath.Join(t.TempDir(), "linked")
+	r.git("worktree", "add", "--quiet", "-b", "other", linked)
+	shared := filepath.Join(r.dir, ".git")
+
+	tests := []struct {
+		name   string
+		dir    string
+		isRepo bool
+		common string
+	}{
+		{name: "main worktree", dir: r.dir, isRepo: true, common: shared},
+		{name: "subdirectory", dir: filepath.Join(r.dir, "sub"), isRepo: true, common: shared},
+		{name: "linked worktree", dir: linked, isRepo: true, common: shared},
+		{name: "outside git", dir: t.TempDir()},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsRepo(tt.dir); got != tt.isRepo {
+				t.Errorf("IsRepo() = %v, want %v", got, tt.isRepo)
+			}
+			got, err := CommonDir(tt.dir)
+			if tt.common == "" {
+				if err == nil {
+					t.Errorf("CommonDir() = %s, want error", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if resolved, _ := filepath.EvalSymlinks(got); resolved != tt.common {
+				t.Errorf("CommonDir() = %s, want %s", got, tt.common)
+			}
+		})
+	}
+
+	if got := ResolveCommit(linked, "HEAD"); got == "" || got != ResolveCommit(r.dir, "main") {
+		t.Errorf("ResolveCommit(linked, HEAD) = %q, want main's commit", got)
+	}
+	if got := ResolveCommit(r.dir, "no-such-branch"); got != "" {
+		t.Errorf("ResolveCommit(no-such-branch) = %q, want \"\"", got)
+	}
+}
@@ -0,0 +1,214 @@
+package history
+
+import (
+	"reflect"
+	"sort"
+
+	"github.com/tyroneavnit/keel/internal/types"
+)
+
+// Kinds of change between two ledger states, in the order they are reported
+const (
+	ChangeAdded      = "added"
+	ChangeSuperseded = "superseded"
+	ChangeStatus     = "status"
+	ChangeAmended    = "amended"
+	ChangeRemoved    = "removed"
+)
+
+var changeOrder = map[string]int{
+	ChangeAdded:      0,
+	ChangeSuperseded: 1,
+	ChangeStatus:     2,
+	ChangeAmended:    3,
+	ChangeRemoved:    4,
+}
+
+// Change is how one decision differs between two ledger states
+type Change struct {
+	Kind         string          `json:"kind"`
+	ID           string          `json:"id"`
+	Ledger       string          `json:"ledger"`
+	Decision     *types.Decision `json:"decision"` // as it is after, or before for a removed decision
+	Fields       []FieldChange   `json:"fields,omitempty"`
+	RemovedLinks []Link          `json:"removed_links,omitempty"`
+}
+
+// FieldChange is one field's value before and after. Absent values are nil.
+type FieldChange struct {
+	Field  string      `json:"field"`
+	Before interface{} `json:"before"`
+	After  interface{} `json:"after"`
+}
+
+// Link is a file, symbol or ref a decision is linked to
+type Link struct {
+	Kind   string `json:"kind"` // file, symbol or ref
+	Target string `json:"target"`
+}
+
+// Diff compares two ledger states, keyed by ledger directory and then
+// decision ID as State returns them
+func Diff(before, after map[string]map[string]*types.Decision) []Change {
+	old := flatten(before)
+	var changes []Change
+	for ledger, state := range after {
+		for id, d := range state {
+			d.Ledger = ledger
+			prev, ok := old[id]
+			delete(old, id)
+			if !ok {
+				changes = append(changes, Change{Kind: ChangeAdded, ID: id, Ledger: ledger, Decision: d})
+				continue
+			}
+
+			c := Change{ID: id, Ledger: ledger, Decision: d, RemovedLinks: removedLinks(prev, d)}
+			for _, f := range CompareFields(prev, d) {
+				switch f.Field {
+				case "status":
+					c.Kind = ChangeStatus
+				case "superseded_by":
+				default:
+					c.Fields = append(c.Fields, f)
+				}
+			}
+			switch {
+			case d.Status == types.StatusSuperseded && prev.Status != types.StatusSuperseded:
+				c.Kind = ChangeSuperseded
+			case c.Kind == "" && (len(c.Fields) > 0 || len(c.RemovedLinks) > 0):
+				c.Kind = ChangeAmended
+			}
+			if c.Kind != "" {
+				changes = append(changes, c)
+			}
+		}
+	}
+	for id, d := range old {
+		changes = append(changes, Change{Kind: ChangeRemoved, ID: id, Ledger: d.Ledger, Decision: d})
+	}
+
+	sort.Slice(changes, func(i, j int) bool {
+		a, b := changes[i], changes[j]
+		if a.Kind != b.Kind {
+			return changeOrder[a.Kind] < changeOrder[b.Kind]
+		}
+		if a.Ledger != b.Ledger {
+			return a.Ledger < b.Ledger
+		}
+		return a.ID < b.ID
+	})
+	return changes
+}
+
+// flatten merges ledger states into one map by decision ID, recording
+// each decision's ledger
+func flatten(states map[string]map[string]*types.Decision) map[string]*types.Decision {
+	all := make(map[string]*types.Decision)
+	for ledger, state := range states {
+		for id, d := range state {
+			d.Ledger = ledger
+			all[id] = d
+		}
+	}
+	return all
+}
+
+// CompareFields lists the fields that differ between two versions of a
+// decision, in the order they appear in the ledger
+func CompareFields(before, after *types.Decision) []FieldChange {
+	var changes []FieldChange
+	compare := func(field string, b, a interface{}) {
+		if !reflect.DeepEqual(b, a) {
+			changes = append(changes, FieldChange{Field: field, Before: b, After: a})
+		}
+	}
+	compare("type", string(before.Type), string(after.Type))
+	compare("problem", before.Problem, after.Problem)
+	compare("choice", before.Choice, after.Choice)
+	compare("rationale", value(before.Rationale), value(after.Rationale))
+	compare("tradeoffs", list(before.Tradeoffs), list(after.Tradeoffs))
+	compare("files", list(before.Files), list(after.Files))
+	compare("anchors", anchors(before.Anchors), anchors(after.Anchors))
+	compare("symbols", list(before.Symbols), list(after.Symbols))
+	compare("refs", list(before.Refs), list(after.Refs))
+	compare("status", string(before.Status), string(after.Status))
+	compare("supersedes", value(before.Supersedes), value(after.Supersedes))
+	compare("superseded_by", value(before.SupersededBy), value(after.SupersededBy))
+	compare("review_by", value(before.ReviewBy), value(after.ReviewBy))
+	compare("expires_at", value(before.ExpiresAt), value(after.ExpiresAt))
+	compare("approvals", approvers(before.Approvals), approvers(after.Approvals))
+	return changes
+}
+
+// removedLinks lists the files, symbols and refs a decision lost. A file
+// still covered by an anchor is still linked.
+func removedLinks(before, after *types.Decision) []Link {
+	var removed []Link
+	drop := func(kind string, b, a []string) {
+		kept := make(map[string]bool, len(a))
+		for _, target := range a {
+			kept[target] = true
+		}
+		for _, target := range b {
+			if !kept[target] {
+				removed = append(removed, Link{Kind: kind, Target: target})
+			}
+		}
+	}
+	drop("file", linkedFiles(before), linkedFiles(after))
+	drop("symbol", before.Symbols, after.Symbols)
+	drop("ref", before.Refs, after.Refs)
+	return removed
+}
+
+// linkedFiles returns a decision's files and the files of its anchors
+func linkedFiles(d *types.Decision) []string {
+	files := append([]string(nil), d.Files...)
+	seen := make(map[string]bool, len(files))
+	for _, f := range files {
+		seen[f] = true
+	}
+	for _, a := range d.Anchors {
+		if !seen[a.File] {
+			seen[a.File] = true
+			files = append(files, a.File)
+		}
+	}
+	return files
+}
+
+func value(s *string) interface{} {
+	if s == nil || *s == "" {
+		return nil
+	}
+	return *s
+}
+
+func list(s []string) interface{} {
+	if len(s) == 0 {
+		return nil
+	}
+	return s
+}
+
+func anchors(as []types.Anchor) interface{} {
+	if len(as) == 0 {
+		return nil
+	}
+	ranges := make([]string, len(as))
+	for i, a := range as {
+		ranges[i] = a.String()
+	}
+	return ranges
+}
+
+func approvers(as []types.Approval) interface{} {
+	if len(as) == 0 {
+		return nil
+	}
+	by := make([]string, len(as))
+	for i, a := range as {
+		by[i] = a.By
+	}
+	return by
+}
@@ -0,0 +1,155 @@
+package history
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/tyroneavnit/keel/internal/types"
+)
+
+func strPtr(s string) *string { return &s }
+
+// base returns an active decision; modify adjusts a copy of it
+func base(id string, modify func(d *types.Decision)) *types.Decision {
+	d := &types.Decision{
+		ID:        id,
+		CreatedAt: "2026-01-01T00:00:00Z",
+		Type:      types.TypeProduct,
+		Problem:   "Where retries live",
+		Choice:    "In the client",
+		Files:     []string{"client.go", "retry.go"},
+		Refs:      []string{"JIRA-1"},
+		Status:    types.StatusActive,
+	}
+	if modify != nil {
+		modify(d)
+	}
+	return d
+}
+
+type state = map[string]map[string]*types.Decision
+
+func TestDiff(t *testing.T) {
+	tests := []struct {
+		name          string
+		before, after state
+		want          []Change // Decision is not compared
+	}{
+		{
+			name:   "unchanged",
+			before: state{".": {"DEC-0001": base("DEC-0001", nil)}},
+			after:  state{".": {"DEC-0001": base("DEC-0001", nil)}},
+		},
+		{
+			name:   "added and removed",
+			before: state{".": {"DEC-0001": base("DEC-0001", nil)}},
+			after:  state{"services/billing": {"DEC-0002": base("DEC-0002", nil)}},
+			want: []Change{
+				{Kind: ChangeAdded, ID: "DEC-0002", Ledger: "services/billing"},
+				{Kind: ChangeRemoved, ID: "DEC-0001", Ledger: "."},
+			},
+		},
+		{
+			name:   "amended fields",
+			before: state{".": {"DEC-0001": base("DEC-0001", nil)}},
+			after: state{".": {"DEC-0001": base("DEC-0001", func(d *types.Decision) {
+				d.Rationale = strPtr("Fewer hops")
+				d.Refs = []string{"JIRA-1", "JIRA-2"}
+			})}},
+			want: []Change{{
+				Kind: ChangeAmended, ID: "DEC-0001", Ledger: ".",
+				Fields: []FieldChange{
+					{Field: "rationale", Before: nil, After: "Fewer hops"},
+					{Field: "refs", Before: []string{"JIRA-1"}, After: []string{"JIRA-1", "JIRA-2"}},
+				},
+			}},
+		},
+		{
+			name:   "removed links",
+			before: state{".": {"DEC-0001": base("DEC-0001", nil)}},
+			after: state{".": {"DEC-0001": base("DEC-0001", func(d *types.Decision) {
+				d.Files = nil
+				d.Anchors = []types.Anchor{{File: "retry.go", StartLine: 3, EndLine: 9}}
+				d.Refs = nil
+			})}},
+			want: []Change{{
+				Kind: ChangeAmended, ID: "DEC-0001", Ledger: ".",
+				Fields: []FieldChange{
+					{Field: "files", Before: []string{"client.go", "retry.go"}, After: nil},
+					{Field: "anchors", Before: nil, After: []string{"retry.go:3-9"}},
+					{Field: "refs", Before: []string{"JIRA-1"}, After: nil},
+				},
+				RemovedLinks: []Link{{Kind: "file", Target: "client.go"}, {Kind: "ref", Target: "JIRA-1"}},
+			}},
+		},
+		{
+			name: "superseded",
+			before: state{".": {
+				"DEC-0001": base("DEC-0001", nil),
+			}},
+			after: state{".": {
+				"DEC-0001": base("DEC-0001", func(d *types.Decision) {
+					d.Status = types.StatusSuperseded
+					d.SupersededBy = strPtr("DEC-0002")
+				}),
+				"DEC-0002": base("DEC-0002", func(d *types.Decision) { d.Supersedes = strPtr("DEC-0001") }),
+			}},
+			want: []Change{
+				{Kind: ChangeAdded, ID: "DEC-0002", Ledger: "."},
+				{Kind: ChangeSuperseded, ID: "DEC-0001", Ledger: "."},
+			},
+		},
+		{
+			name: "reinstated",
+			before: state{".": {"DEC-0001": base("DEC-0001", func(d *types.Decision) {
+				d.Status = types.StatusSuperseded
+				d.SupersededBy = strPtr("DEC-0002")
+			})}},
+			after: state{".": {"DEC-0001": base("DEC-0001", func(d *types.Decision) {
+				d.Approvals = []types.Approval{{By: "ana@example.com"}}
+			})}},
+			want: []Change{{
+				Kind: ChangeStatus, ID: "DEC-0001", Ledger: ".",
+				Fields: []FieldChange{{Field: "approvals", Before: nil, After: []string{"ana@example.com"}}},
+			}},
+		},
+		{
+			name:   "moved to another ledger",
+			before: state{".": {"DEC-0001": base("DEC-0001", nil)}},
+			after:  state{"services/billing": {"DEC-0001": base("DEC-0001", nil)}},
+		},
+		{
+			name: "reported in kind order",
+			before: state{".": {
+				"DEC-0001": base("DEC-0001", nil),
+				"DEC-0002": base("DEC-0002", nil),
+				"DEC-0003": base("DEC-0003", nil),
+			}},
+			after: state{".": {
+				"DEC-0001": base("DEC-0001", func(d *types.Decision) { d.Choice = "In a queue" }),
+				"DEC-0002": base("DEC-0002", func(d *types.Decision) { d.Status = types.StatusSuperseded }),
+				"DEC-0004": base("DEC-0004", nil),
+			}},
+			want: []Change{
+				{Kind: ChangeAdded, ID: "DEC-0004", Ledger: "."},
+				{Kind: ChangeSuperseded, ID: "DEC-0002", Ledger: "."},
+				{Kind: ChangeAmended, ID: "DEC-0001", Ledger: ".", Fields: []FieldChange{{Field: "choice", Before: "In the client", After: "In a queue"}}},
+				{Kind: ChangeRemoved, ID: "DEC-0003", Ledger: "."},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Diff(tt.before, tt.after)
+			for i := range got {
+				if got[i].Decision == nil || got[i].Decision.ID != got[i].ID {
+					t.Errorf("change %d carries decision %v, want %s", i, got[i].Decision, got[i].ID)
+				}
+				got[i].Decision = nil
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Diff() =\n%+v\nwant\n%+v", got, tt.want)
+			}
+		})
+	}
+}
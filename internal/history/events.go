@@ -0,0 +1,175 @@
+package history
+
+import (
+	"os"
+	"path"
+	"regexp"
+	"time"
+
+	"github.com/tyroneavnit/keel/internal/git"
+	"github.com/tyroneavnit/keel/internal/store"
+	"github.com/tyroneavnit/keel/internal/types"
+)
+
+// Kinds of event in a decision's history
+const (
+	EventCreated    = "created"
+	EventSuperseded = "superseded"
+	EventApproved   = "approved"
+	EventRelocated  = "relocated" // anchors moved with the code
+	EventAmended    = "amended"
+	EventRecorded   = "recorded" // a line that changed nothing
+)
+
+// Event is one ledger line for a decision, with the commit that added it
+type Event struct {
+	Kind     string          `json:"kind"`
+	Line     int             `json:"line"`             // line number in the ledger
+	At       string          `json:"at,omitempty"`     // when it happened, from the line's own timestamps
+	Commit   *Commit         `json:"commit"`           // nil until the line is committed
+	Fields   []FieldChange   `json:"fields,omitempty"` // what the line changed
+	Decision *types.Decision `json:"decision"`         // the decision after this event
+}
+
+// Commit is where a ledger line entered the repository's history
+type Commit struct {
+	Hash    string `json:"hash"`
+	Author  string `json:"author"`
+	Email   string `json:"email"`
+	Time    string `json:"time"`
+	Summary string `json:"summary"`
+	PR      string `json:"pr,omitempty"` // pull request number, from its merge or squash commit
+}
+
+// Events returns every ledger line for a decision in a ledger, oldest
+// first, with what each changed and the commit that added it. Outside git
+// no line has a commit.
+func Events(repoRoot, ledger, decisionID string) ([]Event, error) {
+	file := path.Join(ledger, store.KeelDir, store.DecisionsFile)
+	f, err := os.Open(store.GetDecisionsPath(store.LedgerDir(repoRoot, ledger)))
+	if err != nil {
+		return nil, err
+	}
+	entries, err := store.ReadEntries(f)
+	f.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	var blame []git.BlameLine
+	if git.IsRepo(repoRoot) {
+		blame, _ = git.Blame(repoRoot, "", file)
+	}
+	commits := make(map[string]*Commit)
+
+	var events []Event
+	var state *types.Decision
+	for _, e := range entries {
+		if e.Decision.ID != decisionID {
+			continue
+		}
+		ev := Event{Line: e.Line}
+		if state == nil {
+			ev.Kind = EventCreated
+			state = e.Decision
+			ev.At = state.CreatedAt
+		} else {
+			next := store.Merge(state, e.Decision)
+			ev.Fields = CompareFields(state, next)
+			ev.Kind, ev.At = eventKind(ev.Fields, next, entries)
+			state = next
+		}
+		ev.Decision = state
+
+		if e.Line-1 < len(blame) && blame[e.Line-1].Committed() {
+			b := blame[e.Line-1]
+			c, ok := commits[b.Commit]
+			if !ok {
+				c = &Commit{
+					Hash:    b.Commit,
+					Author:  b.Author,
+					Email:   b.Email,
+					Time:    b.Time.Format(time.RFC3339),
+					Summary: b.Summary,
+					PR:      pullRequest(repoRoot, b),
+				}
+				commits[b.Commit] = c
+			}
+			ev.Commit = c
+		}
+		events = append(events, ev)
+	}
+	return events, nil
+}
+
+// eventKind classifies an update by the fields it changed, and returns
+// the time the line records for it, if any
+func eventKind(fields []FieldChange, d *types.Decision, entries []store.Entry) (string, string) {
+	if len(fields) == 0 {
+		return EventRecorded, ""
+	}
+	only := func(names ...string) bool {
+		for _, f := range fields {
+			match := false
+			for _, name := range names {
+				match = match || f.Field == name
+			}
+			if !match {
+				return false
+			}
+		}
+		return true
+	}
+
+	switch {
+	case d.Status == types.StatusSuperseded && only("status", "superseded_by"):
+		at := ""
+		if d.SupersededBy != nil {
+			for _, e := range entries {
+				if e.Decision.ID == *d.SupersededBy {
+					at = e.Decision.CreatedAt
+					break
+				}
+			}
+		}
+		return EventSuperseded, at
+	case only("approvals"):
+		at := ""
+		for _, a := range d.Approvals {
+			if a.ApprovedAt > at {
+				at = a.ApprovedAt
+			}
+		}
+		return EventApproved, at
+	case only("anchors"):
+		return EventRelocated, ""
+	default:
+		return EventAmended, ""
+	}
+}
+
+// Pull request numbers in squash commit subjects ("Fix retries (#123)") and
+// merge commit messages from GitHub ("Merge pull request #123 from ...")
+// and GitLab ("See merge request group/repo!123")
+var (
+	squashPR = regexp.MustCompile(`\(#(\d+)\)\s*$`)
+	mergePR  = regexp.MustCompile(`^Merge pull request #(\d+)|See merge request \S*!(\d+)`)
+)
+
+// pullRequest finds the pull request a commit arrived in, or ""
+func pullRequest(repoRoot string, b git.BlameLine) string {
+	if m := squashPR.FindStringSubmatch(b.Summary); m != nil {
+		return m[1]
+	}
+	message, err := git.MergedBy(repoRoot, b.Commit)
+	if err != nil {
+		return ""
+	}
+	if m := mergePR.FindStringSubmatch(message); m != nil {
+		if m[1] != "" {
+			return m[1]
+		}
+		return m[2]
+	}
+	return ""
+}
@@ -0,0 +1,90 @@
+package history
+
+import (
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/tyroneavnit/keel/internal/store"
+)
+
+func TestEvents(t *testing.T) {
+	root := t.TempDir()
+	if err := store.EnsureKeelDir(root); err != nil {
+		t.Fatal(err)
+	}
+	ledger := strings.Join([]string{
+		`{"id":"DEC-0001","created_at":"2026-01-01T00:00:00Z","type":"product","problem":"Where retries live","choice":"In the client","files":["client.go"],"decided_by":{"role":"human"},"status":"active"}`,
+		`{"id":"DEC-0001","rationale":"Fewer hops","files":["client.go","retry.go"]}`,
+		`{"id":"DEC-0002","created_at":"2026-02-01T00:00:00Z","type":"product","problem":"Where retries live","choice":"In the server","supersedes":"DEC-0001","decided_by":{"role":"human"},"status":"active"}`,
+		`{"id":"DEC-0001","status":"superseded","superseded_by":"DEC-0002"}`,
+		`{"id":"DEC-0002","approvals":[{"by":"bob@example.com","approved_at":"2026-02-02T00:00:00Z"}]}`,
+		`{"id":"DEC-0002","anchors":[{"file":"server.go","start_line":3,"end_line":9}]}`,
+		`{"id":"DEC-0002","rationale":"Fewer hops"}`,
+		`{"id":"DEC-0002","rationale":"Fewer hops"}`,
+	}, "\n") + "\n"
+	if err := os.WriteFile(store.GetDecisionsPath(root), []byte(ledger), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	type event struct {
+		Kind   string
+		Line   int
+		At     string
+		Fields []FieldChange
+	}
+	tests := []struct {
+		id   string
+		want []event
+	}{
+		{
+			id: "DEC-0001",
+			want: []event{
+				{Kind: EventCreated, Line: 1, At: "2026-01-01T00:00:00Z"},
+				{Kind: EventAmended, Line: 2, Fields: []FieldChange{
+					{Field: "rationale", Before: nil, After: "Fewer hops"},
+					{Field: "files", Before: []string{"client.go"}, After: []string{"client.go", "retry.go"}},
+				}},
+				{Kind: EventSuperseded, Line: 4, At: "2026-02-01T00:00:00Z", Fields: []FieldChange{
+					{Field: "status", Before: "active", After: "superseded"},
+					{Field: "superseded_by", Before: nil, After: "DEC-0002"},
+				}},
+			},
+		},
+		{
+			id: "DEC-0002",
+			want: []event{
+				{Kind: EventCreated, Line: 3, At: "2026-02-01T00:00:00Z"},
+				{Kind: EventApproved, Line: 5, At: "2026-02-02T00:00:00Z", Fields: []FieldChange{
+					{Field: "approvals", Before: nil, After: []string{"bob@example.com"}},
+				}},
+				{Kind: EventRelocated, Line: 6, Fields: []FieldChange{
+					{Field: "anchors", Before: nil, After: []string{"server.go:3-9"}},
+				}},
+				{Kind: EventAmended, Line: 7, Fields: []FieldChange{
+					{Field: "rationale", Before: nil, After: "Fewer hops"},
+				}},
+				{Kind: EventRecorded, Line: 8},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.id, func(t *testing.T) {
+			events, err := Events(root, store.RootLedger, tt.id)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []event
+			for _, e := range events {
+				if e.Commit != nil {
+					t.Errorf("line %d has a commit outside git", e.Line)
+				}
+				got = append(got, event{Kind: e.Kind, Line: e.Line, At: e.At, Fields: e.Fields})
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Events() =\n%+v\nwant\n%+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,231 @@
+// Package history reads the ledgers as they were at an earlier point: a
+// git revision, whose committed ledgers are read with git show, or a
+// moment in time, up to which the current ledgers are replayed.
+package history
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/tyroneavnit/keel/internal/errs"
+	"github.com/tyroneavnit/keel/internal/git"
+	"github.com/tyroneavnit/keel/internal/store"
+	"github.com/tyroneavnit/keel/internal/types"
+)
+
+// Point is a revision or a moment to read the ledgers at
+type Point struct {
+	Spec   string    // as given, e.g. v1.2.0 or 2026-03-01T00:00:00Z
+	Commit string    // the revision's commit; empty for a moment
+	Time   time.Time // the moment; zero for a revision
+}
+
+// Resolve parses an RFC 3339 time or a git revision
+func Resolve(repoRoot, spec string) (*Point, error) {
+	if t, err := time.Parse(time.RFC3339, spec); err == nil {
+		return &Point{Spec: spec, Time: t}, nil
+	}
+	if !git.IsRepo(repoRoot) {
+		return nil, errs.New(errs.Usage, "%s is not an RFC 3339 time, and revisions need a git repository", spec)
+	}
+	commit := git.ResolveCommit(repoRoot, spec)
+	if commit == "" {
+		return nil, errs.New(errs.Usage, "%s is neither a git revision nor an RFC 3339 time", spec)
+	}
+	return &Point{Spec: spec, Commit: commit}, nil
+}
+
+// IsRevision reports whether the point is a git revision
+func (p *Point) IsRevision() bool {
+	return p.Commit != ""
+}
+
+// Key names the point's state for caching. A revision's ledgers never
+// change; a moment's replay depends on the current ledger content too.
+func (p *Point) Key(repoRoot string) (string, error) {
+	if p.IsRevision() {
+		return "rev-" + p.Commit, nil
+	}
+	ledgers, err := store.Ledgers(repoRoot)
+	if err != nil {
+		return "", err
+	}
+	h := sha256.New()
+	for _, ledger := range ledgers {
+		content, err := os.ReadFile(store.GetDecisionsPath(store.LedgerDir(repoRoot, ledger)))
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return "", err
+		}
+		fmt.Fprintf(h, "%s\x00", ledger)
+		h.Write(content)
+	}
+	return fmt.Sprintf("at-%d-%s", p.Time.Unix(), hex.EncodeToString(h.Sum(nil))[:16]), nil
+}
+
+// State returns the state of every ledger at the point, keyed by ledger
+// directory and then decision ID
+func (p *Point) State(repoRoot string) (map[string]map[string]*types.Decision, error) {
+	if p.IsRevision() {
+		return revisionState(repoRoot, p.Commit)
+	}
+	return stateAt(repoRoot, p.Time)
+}
+
+// revisionState reads every ledger committed at a revision
+func revisionState(repoRoot, commit string) (map[string]map[string]*types.Decision, error) {
+	files, err := git.TreeFiles(repoRoot, commit)
+	if err != nil {
+		return nil, err
+	}
+	ledgerFile := path.Join(store.KeelDir, store.DecisionsFile)
+
+	states := make(map[string]map[string]*types.Decision)
+	for _, file := range files {
+		if file != ledgerFile && !strings.HasSuffix(file, "/"+ledgerFile) {
+			continue
+		}
+		content, err := git.Show(repoRoot, commit, file)
+		if err != nil {
+			return nil, err
+		}
+		entries, err := store.ReadEntries(bytes.NewReader(content))
+		if err != nil {
+			return nil, err
+		}
+		states[path.Dir(path.Dir(file))] = store.Reduce(store.Decisions(entries))
+	}
+	return states, nil
+}
+
+// stateAt replays every current ledger up to a moment
+func stateAt(repoRoot string, at time.Time) (map[string]map[string]*types.Decision, error) {
+	ledgers, err := store.Ledgers(repoRoot)
+	if err != nil {
+		return nil, err
+	}
+
+	states := make(map[string]map[string]*types.Decision)
+	for _, ledger := range ledgers {
+		file := store.GetDecisionsPath(store.LedgerDir(repoRoot, ledger))
+		f, err := os.Open(file)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		entries, err := store.ReadEntries(f)
+		f.Close()
+		if err != nil {
+			return nil, err
+		}
+
+		// Lines that carry no time of their own are dated by their commit.
+		// Outside git, or for an untracked ledger, there is none to go by.
+		blame, _ := git.Blame(repoRoot, "", path.Join(ledger, store.KeelDir, store.DecisionsFile))
+		states[ledger] = store.Reduce(Until(entries, blame, at))
+	}
+	return states, nil
+}
+
+// Until returns the ledger lines recorded at or before a moment. Each line
+// is dated by the time it carries: a new decision's created_at, an
+// approval's approved_at, or for a supersession the replacement's
+// created_at. Other updates, such as amendments, are dated by the commit
+// that added them, or with no blame by the line before them. Once one line
+// of a decision falls after the moment, so do the rest.
+func Until(entries []store.Entry, blame []git.BlameLine, at time.Time) []*types.Decision {
+	created := make(map[string]time.Time)
+	for _, e := range entries {
+		if _, ok := created[e.Decision.ID]; !ok {
+			created[e.Decision.ID] = parseTime(e.Decision.CreatedAt)
+		}
+	}
+
+	type seen struct {
+		approvals  map[string]bool
+		superseded bool
+		last       time.Time
+		cut        bool
+	}
+	decisions := make(map[string]*seen)
+
+	var kept []*types.Decision
+	for _, e := range entries {
+		d := e.Decision
+		s, ok := decisions[d.ID]
+		if !ok {
+			s = &seen{approvals: make(map[string]bool)}
+			decisions[d.ID] = s
+		}
+		if s.cut {
+			continue
+		}
+
+		var when time.Time
+		switch {
+		case !ok:
+			when = created[d.ID]
+		case d.SupersededBy != nil && !s.superseded:
+			when = created[*d.SupersededBy]
+		case hasNewApproval(d, s.approvals):
+			for _, a := range d.Approvals {
+				if t := parseTime(a.ApprovedAt); !s.approvals[approvalKey(a)] && t.After(when) {
+					when = t
+				}
+			}
+		}
+		if when.IsZero() {
+			when = s.last
+			if e.Line-1 < len(blame) {
+				// An uncommitted line is as recent as it gets
+				if when = blame[e.Line-1].Time; !blame[e.Line-1].Committed() {
+					when = time.Now()
+				}
+			}
+		}
+
+		if when.After(at) {
+			s.cut = true
+			continue
+		}
+		kept = append(kept, d)
+		s.last = when
+		s.superseded = s.superseded || d.SupersededBy != nil
+		for _, a := range d.Approvals {
+			s.approvals[approvalKey(a)] = true
+		}
+	}
+	return kept
+}
+
+func hasNewApproval(d *types.Decision, seen map[string]bool) bool {
+	for _, a := range d.Approvals {
+		if !seen[approvalKey(a)] {
+			return true
+		}
+	}
+	return false
+}
+
+func approvalKey(a types.Approval) string {
+	return a.By + "\x00" + a.ApprovedAt
+}
+
+// parseTime reads a ledger timestamp, or returns the zero time
+func parseTime(s string) time.Time {
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return time.Time{}
+	}
+	return t
+}
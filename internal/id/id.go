@@ -7,17 +7,73 @@ import (
 	"strconv"
 	"strings"
 	"time"
+
+	"github.com/tyroneavnit/keel/internal/errs"
 )
 
 const IDPrefix = "DEC"
 
-var validIDPattern = regexp.MustCompile(`^DEC-[a-f0-9]{4}$`)
-var hexSuffixPattern = regexp.MustCompile(`^[a-fA-F0-9]{4}$`)
+const (
+	DefaultLength = 4
+	MinLength     = 4
+	MaxLength     = 8
+)
+
+var hexSuffixPattern = regexp.MustCompile(`^[a-fA-F0-9]{4,8}$`)
+var prefixPattern = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9]*$`)
+
+// NamespacePattern matches the upstream name that qualifies a federated ID
+var NamespacePattern = regexp.MustCompile(`^[a-z][a-z0-9-]*$`)
+
+// canonicalSuffix and canonicalUpstreamID match IDs as Normalize writes them
+var (
+	canonicalSuffix     = regexp.MustCompile(`^[a-f0-9]{4,8}$`)
+	canonicalUpstreamID = regexp.MustCompile(`^[A-Z][A-Z0-9]*-[a-f0-9]{4,8}$`)
+)
+
+// upstreamIDPattern matches an upstream's own IDs, whatever its prefix
+var upstreamIDPattern = regexp.MustCompile(`^([A-Za-z][A-Za-z0-9]*)-([a-fA-F0-9]{4,8})$`)
+
+// Format is a repository's decision ID format: a prefix and the number of
+// hex characters after it, as set in .keel/config.yaml
+type Format struct {
+	Prefix string
+	Length int
+}
+
+// DefaultFormat is the format of a repository that configures none
+var DefaultFormat = Format{Prefix: IDPrefix, Length: DefaultLength}
+
+// NewFormat checks an ID prefix and length and returns their format. Empty
+// values take the defaults.
+func NewFormat(p string, n int) (Format, error) {
+	if p == "" {
+		p = IDPrefix
+	}
+	if n == 0 {
+		n = DefaultLength
+	}
+	if err := ValidateFormat(p, n); err != nil {
+		return Format{}, err
+	}
+	return Format{Prefix: strings.ToUpper(p), Length: n}, nil
+}
+
+// ValidateFormat checks an ID prefix and length without applying them
+func ValidateFormat(p string, n int) error {
+	if !prefixPattern.MatchString(p) {
+		return fmt.Errorf("invalid ID prefix: %s. Must be alphanumeric", p)
+	}
+	if n < MinLength || n > MaxLength {
+		return fmt.Errorf("invalid ID length: %d. Must be between %d and %d", n, MinLength, MaxLength)
+	}
+	return nil
+}
 
 // Generate creates a hash-based decision ID.
 // Uses content hashing to prevent collisions in multi-agent workflows.
-// Format: DEC-xxxx (4 hex characters from content hash + entropy)
-func Generate(problem, choice string) string {
+// Format: DEC-xxxx (hex characters from content hash + entropy)
+func (f Format) Generate(problem, choice string) string {
 	timestamp := strconv.FormatInt(time.Now().UnixMilli(), 36)
 	random := randomString(4)
 	content := fmt.Sprintf("%s:%s:%s:%s", problem, choice, timestamp, random)
@@ -28,36 +84,89 @@ func Generate(problem, choice string) string {
 		hash = ((hash << 5) + hash) ^ uint32(content[i])
 	}
 
-	// Convert to 4-character hex suffix
-	suffix := fmt.Sprintf("%04x", hash&0xFFFF)
-	return fmt.Sprintf("%s-%s", IDPrefix, suffix)
+	// Convert to hex suffix of the configured length
+	suffix := fmt.Sprintf("%08x", hash)[8-f.Length:]
+	return fmt.Sprintf("%s-%s", f.Prefix, suffix)
 }
 
-// IsValid checks if a string is a valid decision ID format.
-func IsValid(id string) bool {
-	return validIDPattern.MatchString(strings.ToLower(id))
+// IsValid checks if a string is a decision ID in canonical form: the
+// format's prefix and length, the default DEC format of existing ledgers,
+// or an upstream's ID qualified with its name. Spellings Normalize would
+// correct, such as dec-A1B2 or a bare suffix, are not valid.
+func (f Format) IsValid(id string) bool {
+	if namespace, upstreamID, ok := strings.Cut(id, ":"); ok {
+		return NamespacePattern.MatchString(namespace) && canonicalUpstreamID.MatchString(upstreamID)
+	}
+	prefix, suffix, ok := strings.Cut(id, "-")
+	if !ok || !canonicalSuffix.MatchString(suffix) {
+		return false
+	}
+	return (prefix == f.Prefix && len(suffix) == f.Length) ||
+		(prefix == IDPrefix && len(suffix) == DefaultLength)
 }
 
 // Normalize normalizes a decision ID input.
-// Accepts: "DEC-a1b2", "dec-a1b2", "a1b2"
+// Accepts: "DEC-a1b2", "dec-a1b2", "a1b2", and "platform:DEC-a1b2" for a
+// decision from an upstream ledger.
 // Always returns lowercase suffix for consistency.
-func Normalize(input string) (string, error) {
+func (f Format) Normalize(input string) (string, error) {
 	trimmed := strings.TrimSpace(input)
 
-	if strings.HasPrefix(strings.ToUpper(trimmed), "DEC-") {
-		suffix := strings.ToLower(trimmed[4:])
-		if !hexSuffixPattern.MatchString(suffix) {
-			return "", fmt.Errorf("invalid decision ID: %s. Expected format: DEC-xxxx (4 hex chars)", input)
+	if namespace, upstreamID, ok := strings.Cut(trimmed, ":"); ok {
+		namespace = strings.ToLower(namespace)
+		m := upstreamIDPattern.FindStringSubmatch(upstreamID)
+		if !NamespacePattern.MatchString(namespace) || m == nil {
+			return "", f.invalidIDError(input)
+		}
+		return Qualify(namespace, strings.ToUpper(m[1])+"-"+strings.ToLower(m[2])), nil
+	}
+
+	for _, p := range f.knownPrefixes() {
+		if strings.HasPrefix(strings.ToUpper(trimmed), p+"-") {
+			suffix := strings.ToLower(trimmed[len(p)+1:])
+			if !hexSuffixPattern.MatchString(suffix) {
+				return "", f.invalidIDError(input)
+			}
+			return fmt.Sprintf("%s-%s", p, suffix), nil
 		}
-		return fmt.Sprintf("DEC-%s", suffix), nil
 	}
 
 	// Assume it's just the suffix
 	if hexSuffixPattern.MatchString(trimmed) {
-		return fmt.Sprintf("DEC-%s", strings.ToLower(trimmed)), nil
+		return fmt.Sprintf("%s-%s", f.Prefix, strings.ToLower(trimmed)), nil
 	}
 
-	return "", fmt.Errorf("invalid decision ID: %s. Expected format: DEC-xxxx (4 hex chars)", input)
+	return "", f.invalidIDError(input)
+}
+
+// Qualify returns the ID an upstream decision has in this repository: its
+// own ID under the upstream's name, as in platform:DEC-a1b2
+func Qualify(namespace, id string) string {
+	return namespace + ":" + id
+}
+
+// Pattern matches decision IDs in free text, such as DEC-a1b2 in a comment,
+// for the format's prefix and the default one
+func (f Format) Pattern() *regexp.Regexp {
+	prefixes := f.knownPrefixes()
+	for i, p := range prefixes {
+		prefixes[i] = regexp.QuoteMeta(p)
+	}
+	return regexp.MustCompile(`(?i)\b(?:` + strings.Join(prefixes, "|") + `)-[a-f0-9]{4,8}\b`)
+}
+
+// knownPrefixes are the prefixes IDs are accepted with. IDs in the default
+// DEC format stay valid so existing ledgers keep working.
+func (f Format) knownPrefixes() []string {
+	if f.Prefix == IDPrefix {
+		return []string{IDPrefix}
+	}
+	return []string{f.Prefix, IDPrefix}
+}
+
+func (f Format) invalidIDError(input string) error {
+	return errs.New(errs.InvalidID, "invalid decision ID: %s. Expected format: %s-%s (%d hex chars)",
+		input, f.Prefix, strings.Repeat("x", f.Length), f.Length)
 }
 
 func randomString(n int) string {
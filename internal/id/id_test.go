@@ -0,0 +1,71 @@
+package id
+
+import "testing"
+
+func TestIsValid(t *testing.T) {
+	custom := Format{Prefix: "ADR", Length: 6}
+
+	tests := []struct {
+		format Format
+		id     string
+		want   bool
+	}{
+		{DefaultFormat, "DEC-a1b2", true},
+		{DefaultFormat, "DEC-A1B2", false},
+		{DefaultFormat, "dec-a1b2", false},
+		{DefaultFormat, "a1b2", false},
+		{DefaultFormat, " DEC-a1b2", false},
+		{DefaultFormat, "DEC-a1b2c3", false},
+		{DefaultFormat, "DEC-g1b2", false},
+		{DefaultFormat, "platform:DEC-a1b2", true},
+		{DefaultFormat, "platform:dec-a1b2", false},
+		{DefaultFormat, "Platform:DEC-a1b2", false},
+		{custom, "ADR-a1b2c3", true},
+		{custom, "ADR-a1b2", false},
+		{custom, "DEC-a1b2", true},
+		{custom, "adr-a1b2c3", false},
+	}
+	for _, tt := range tests {
+		if got := tt.format.IsValid(tt.id); got != tt.want {
+			t.Errorf("%s.IsValid(%q) = %v, want %v", tt.format.Prefix, tt.id, got, tt.want)
+		}
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	custom := Format{Prefix: "ADR", Length: 6}
+
+	tests := []struct {
+		format  Format
+		input   string
+		want    string
+		wantErr bool
+	}{
+		{DefaultFormat, "DEC-a1b2", "DEC-a1b2", false},
+		{DefaultFormat, "dec-A1B2", "DEC-a1b2", false},
+		{DefaultFormat, " a1b2 ", "DEC-a1b2", false},
+		{DefaultFormat, "Platform:dec-A1B2", "platform:DEC-a1b2", false},
+		{DefaultFormat, "DEC-xyz1", "", true},
+		{DefaultFormat, "ADR-a1b2", "", true},
+		{custom, "adr-a1b2c3", "ADR-a1b2c3", false},
+		{custom, "a1b2c3", "ADR-a1b2c3", false},
+		{custom, "DEC-a1b2", "DEC-a1b2", false},
+	}
+	for _, tt := range tests {
+		got, err := tt.format.Normalize(tt.input)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("%s.Normalize(%q) = %q, %v; want %q, error %v", tt.format.Prefix, tt.input, got, err, tt.want, tt.wantErr)
+		}
+		if err == nil && !tt.format.IsValid(got) {
+			t.Errorf("%s.IsValid(%q) = false for a normalized ID", tt.format.Prefix, got)
+		}
+	}
+}
+
+func TestGenerate(t *testing.T) {
+	for _, f := range []Format{DefaultFormat, {Prefix: "ADR", Length: 8}} {
+		if got := f.Generate("problem", "choice"); !f.IsValid(got) {
+			t.Errorf("%s.Generate() = %q, not valid", f.Prefix, got)
+		}
+	}
+}
@@ -0,0 +1,62 @@
+package identity
+
+import (
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/tyroneavnit/keel/internal/types"
+)
+
+const (
+	// EnvAgent names the agent recording decisions (e.g. "claude", "codex")
+	EnvAgent = "KEEL_AGENT"
+	// EnvSession identifies the agent session or run that recorded a decision
+	EnvSession = "KEEL_SESSION"
+)
+
+// Resolve builds the decided_by record for a new decision. A set
+// KEEL_AGENT means an agent is recording, so the role becomes agent even
+// when the caller asked for a human one.
+//
+// The identifier is taken from, in order of precedence:
+//  1. the explicit override (the --as flag)
+//  2. KEEL_AGENT, for agent decisions
+//  3. git config user.email in the repository
+//
+// The session is taken from KEEL_SESSION when set.
+func Resolve(repoRoot, role, override string) types.DecidedBy {
+	agent := strings.TrimSpace(os.Getenv(EnvAgent))
+	if agent != "" {
+		role = "agent"
+	}
+	decidedBy := types.DecidedBy{Role: role}
+
+	identifier := strings.TrimSpace(override)
+	if identifier == "" && role == "agent" {
+		identifier = agent
+	}
+	if identifier == "" {
+		identifier = GitEmail(repoRoot)
+	}
+	if identifier != "" {
+		decidedBy.Identifier = &identifier
+	}
+
+	if session := strings.TrimSpace(os.Getenv(EnvSession)); session != "" {
+		decidedBy.Session = &session
+	}
+
+	return decidedBy
+}
+
+// GitEmail returns the configured git user.email, or "" if unavailable
+func GitEmail(repoRoot string) string {
+	cmd := exec.Command("git", "config", "user.email")
+	cmd.Dir = repoRoot
+	out, err := cmd.Output()
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(out))
+}
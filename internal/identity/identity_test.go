@@ -0,0 +1,75 @@
+package identity
+
+import (
+	"os/exec"
+	"testing"
+)
+
+func TestResolve(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	// Keep the user's own git config out of the lookup
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("XDG_CONFIG_HOME", "")
+	t.Setenv("GIT_CONFIG_NOSYSTEM", "1")
+
+	repo := t.TempDir()
+	for _, args := range [][]string{{"init", "--quiet"}, {"config", "user.email", "dev@example.com"}} {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = repo
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, out)
+		}
+	}
+
+	tests := []struct {
+		name     string
+		dir      string
+		role     string
+		override string
+		wantRole string
+		agent    string
+		session  string
+		wantID   string // "" for no identifier
+	}{
+		{name: "override", dir: repo, role: "agent", override: " reviewer@example.com ", agent: "claude", wantID: "reviewer@example.com"},
+		{name: "agent name", dir: repo, role: "agent", agent: "codex", wantID: "codex"},
+		{name: "agent name implies agent", dir: repo, role: "human", agent: "codex", wantRole: "agent", wantID: "codex"},
+		{name: "human", dir: repo, role: "human", wantID: "dev@example.com"},
+		{name: "git email", dir: repo, role: "agent", wantID: "dev@example.com"},
+		{name: "session", dir: repo, role: "agent", agent: "claude", session: "run-42", wantID: "claude"},
+		{name: "no identity", dir: t.TempDir(), role: "human"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv(EnvAgent, tt.agent)
+			t.Setenv(EnvSession, tt.session)
+
+			wantRole := tt.wantRole
+			if wantRole == "" {
+				wantRole = tt.role
+			}
+			got := Resolve(tt.dir, tt.role, tt.override)
+			if got.Role != wantRole {
+				t.Errorf("role = %s, want %s", got.Role, wantRole)
+			}
+			if id := deref(got.Identifier); id != tt.wantID {
+				t.Errorf("identifier = %q, want %q", id, tt.wantID)
+			}
+			if session := deref(got.Session); session != tt.session {
+				t.Errorf("session = %q, want %q", session, tt.session)
+			}
+			if tt.wantID == "" && got.Identifier != nil {
+				t.Errorf("identifier set to empty string, want nil")
+			}
+		})
+	}
+}
+
+func deref(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
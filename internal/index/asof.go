@@ -0,0 +1,89 @@
+package index
+
+import (
+	"database/sql"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/tyroneavnit/keel/internal/store"
+	"github.com/tyroneavnit/keel/internal/types"
+)
+
+// A historical index holds the ledgers as they were at an earlier point.
+// It is built once into .keel/cache/, named by a key for that point, and
+// never refreshed: its ledgers don't change. Upstream ledgers are cached
+// without history, so a historical index holds only this repository's.
+
+// CacheDir is the directory under .keel holding historical indexes
+const CacheDir = "cache"
+
+// OpenFrozen opens the historical index for key, building it from the
+// ledger states load returns, keyed by ledger directory, if it isn't cached
+func OpenFrozen(repoRoot, key string, load func() (map[string]map[string]*types.Decision, error)) (*DB, error) {
+	if err := store.RequireInit(repoRoot); err != nil {
+		return nil, err
+	}
+
+	dir := filepath.Join(store.GetKeelDir(repoRoot), CacheDir)
+	path := filepath.Join(dir, fmt.Sprintf("index-v%s-%s.sqlite", schemaVersion, key))
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		states, err := load()
+		if err != nil {
+			return nil, err
+		}
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return nil, err
+		}
+		if err := buildFrozen(repoRoot, path, states); err != nil {
+			return nil, err
+		}
+	}
+
+	db, err := sql.Open("sqlite3", path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database: %w", err)
+	}
+	return &DB{DB: db, repoRoot: repoRoot, frozen: true}, nil
+}
+
+// buildFrozen writes an index of the given ledger states to path. It is
+// built under a temporary name so a cached index is always complete.
+func buildFrozen(repoRoot, path string, states map[string]map[string]*types.Decision) error {
+	tmp := fmt.Sprintf("%s.%d.tmp", path, os.Getpid())
+	defer os.Remove(tmp)
+
+	conn, err := sql.Open("sqlite3", tmp)
+	if err != nil {
+		return fmt.Errorf("failed to open database: %w", err)
+	}
+	db := &DB{DB: conn, repoRoot: repoRoot}
+	if err := db.migrate(); err != nil {
+		conn.Close()
+		return err
+	}
+	if err := db.createSchema(); err != nil {
+		conn.Close()
+		return err
+	}
+
+	ledgers := make([]string, 0, len(states))
+	for ledger := range states {
+		ledgers = append(ledgers, ledger)
+	}
+	sort.Strings(ledgers)
+	for _, ledger := range ledgers {
+		for _, d := range states[ledger] {
+			d.Ledger = ledger
+			if err := db.insertDecision(d); err != nil {
+				conn.Close()
+				return err
+			}
+		}
+	}
+	if err := conn.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
@@ -0,0 +1,86 @@
+package index
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/tyroneavnit/keel/internal/store"
+	"github.com/tyroneavnit/keel/internal/types"
+)
+
+func TestOpenFrozen(t *testing.T) {
+	root := t.TempDir()
+	if err := os.MkdirAll(store.GetKeelDir(root), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(store.GetDecisionsPath(root), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	decision := func(id string) *types.Decision {
+		return &types.Decision{ID: id, CreatedAt: "2026-01-01T00:00:00Z", Type: types.TypeProduct,
+			Problem: "p", Choice: "c", DecidedBy: types.DecidedBy{Role: "human"}, Status: types.StatusActive}
+	}
+
+	loads := 0
+	load := func() (map[string]map[string]*types.Decision, error) {
+		loads++
+		return map[string]map[string]*types.Decision{
+			".":                {"DEC-0001": decision("DEC-0001")},
+			"services/billing": {"DEC-0002": decision("DEC-0002")},
+		}, nil
+	}
+
+	tests := []struct {
+		name      string
+		key       string
+		wantLoads int
+	}{
+		{name: "first open builds", key: "abc123", wantLoads: 1},
+		{name: "same key is cached", key: "abc123", wantLoads: 1},
+		{name: "new key builds", key: "def456", wantLoads: 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, err := OpenFrozen(root, tt.key, load)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer db.Close()
+			if loads != tt.wantLoads {
+				t.Errorf("load called %d times, want %d", loads, tt.wantLoads)
+			}
+			if !db.Frozen() {
+				t.Error("Frozen() = false, want true")
+			}
+			if err := db.IndexDecision(decision("DEC-0003")); err == nil {
+				t.Error("IndexDecision() on a historical index succeeded")
+			}
+
+			rows, err := db.Query("SELECT id, ledger FROM decisions ORDER BY id")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer rows.Close()
+			var got []string
+			for rows.Next() {
+				var id, ledger string
+				if err := rows.Scan(&id, &ledger); err != nil {
+					t.Fatal(err)
+				}
+				got = append(got, id+" "+ledger)
+			}
+			if want := []string{"DEC-0001 .", "DEC-0002 services/billing"}; !reflect.DeepEqual(got, want) {
+				t.Errorf("decisions = %v, want %v", got, want)
+			}
+		})
+	}
+
+	if _, err := os.Stat(filepath.Join(store.GetKeelDir(root), CacheDir, "index-v"+schemaVersion+"-abc123.sqlite")); err != nil {
+		t.Errorf("cached index: %v", err)
+	}
+	if _, err := OpenFrozen(t.TempDir(), "abc123", load); err == nil {
+		t.Error("OpenFrozen() outside an initialized repository succeeded")
+	}
+}
@@ -0,0 +1,199 @@
+package index
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/tyroneavnit/keel/internal/git"
+	"github.com/tyroneavnit/keel/internal/store"
+	"github.com/tyroneavnit/keel/internal/upstream"
+)
+
+// Each git worktree has its own .keel/index.sqlite. With sharing on, an
+// index rebuilt from the ledgers is also published as a snapshot in the
+// common git directory, named by a hash of the ledgers it was built from.
+// A worktree whose ledgers have the same content copies the snapshot's
+// tables instead of replaying the JSONL, so parallel agents on one
+// repository build each ledger state once.
+
+// snapshotDir is the directory under the common git dir holding snapshots
+const snapshotDir = "keel"
+
+// keepSnapshots is how many snapshots are kept; older ones are removed
+const keepSnapshots = 8
+
+// ledgerTables are the tables derived from the ledger, in insert order.
+// Annotations come from scanning a worktree and are never shared.
+var ledgerTables = []string{"decisions", "decision_files", "decision_anchors", "decision_symbols", "decision_refs"}
+
+// snapshotPath returns where the snapshot for the current content of the
+// ledgers lives, or "" when sharing is off or the repository is not under git
+func (db *DB) snapshotPath(ledgers []string) (string, error) {
+	if !db.shared {
+		return "", nil
+	}
+	common, err := git.CommonDir(db.repoRoot)
+	if err != nil {
+		return "", nil
+	}
+	hash, err := ledgerHash(db.repoRoot, ledgers)
+	if err != nil || hash == "" {
+		return "", err
+	}
+	name := fmt.Sprintf("index-v%s-%s.sqlite", schemaVersion, hash)
+	return filepath.Join(common, snapshotDir, name), nil
+}
+
+// ledgerHash returns a short hash of the content of every ledger and
+// cached upstream, or "" when there are none
+func ledgerHash(repoRoot string, ledgers []string) (string, error) {
+	names, err := upstream.Names(repoRoot)
+	if err != nil {
+		return "", err
+	}
+	files := make(map[string]string)
+	var keys []string
+	for _, ledger := range ledgers {
+		keys = append(keys, ledger)
+		files[ledger] = store.GetDecisionsPath(store.LedgerDir(repoRoot, ledger))
+	}
+	for _, name := range names {
+		key := upstream.DirName + ":" + name
+		keys = append(keys, key)
+		files[key] = upstream.LedgerPath(repoRoot, name)
+	}
+
+	h := sha256.New()
+	found := false
+	for _, key := range keys {
+		f, err := os.Open(files[key])
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return "", err
+		}
+		fmt.Fprintf(h, "%s\x00", key)
+		_, err = io.Copy(h, f)
+		f.Close()
+		if err != nil {
+			return "", err
+		}
+		found = true
+	}
+	if !found {
+		return "", nil
+	}
+	return hex.EncodeToString(h.Sum(nil))[:16], nil
+}
+
+// restoreSnapshot replaces the ledger tables with a snapshot's. It reports
+// false when no snapshot exists for this ledger content.
+func (db *DB) restoreSnapshot(path string) (bool, error) {
+	if _, err := os.Stat(path); err != nil {
+		return false, nil
+	}
+
+	// ATTACH applies to a single connection, so hold one for the copy
+	ctx := context.Background()
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer conn.Close()
+
+	if _, err := conn.ExecContext(ctx, "ATTACH DATABASE ? AS snapshot", path); err != nil {
+		return false, fmt.Errorf("failed to open index snapshot: %w", err)
+	}
+	defer conn.ExecContext(ctx, "DETACH DATABASE snapshot")
+
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	for i := len(ledgerTables) - 1; i >= 0; i-- {
+		if _, err := tx.Exec("DELETE FROM main." + ledgerTables[i]); err != nil {
+			return false, err
+		}
+	}
+	for _, table := range ledgerTables {
+		if _, err := tx.Exec("INSERT INTO main." + table + " SELECT * FROM snapshot." + table); err != nil {
+			return false, fmt.Errorf("failed to copy index snapshot: %w", err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// publishSnapshot writes the ledger tables to path for other worktrees.
+// The copy is built beside it and renamed into place, so readers never see
+// a partial snapshot.
+func (db *DB) publishSnapshot(path string) error {
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	tmp := fmt.Sprintf("%s.%d.tmp", path, os.Getpid())
+	os.Remove(tmp)
+	if _, err := db.Exec("VACUUM INTO ?", tmp); err != nil {
+		return err
+	}
+
+	snap, err := sql.Open("sqlite3", tmp)
+	if err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	_, err = snap.Exec("DELETE FROM decision_annotations")
+	snap.Close()
+	if err != nil {
+		os.Remove(tmp)
+		return err
+	}
+
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return pruneSnapshots(dir)
+}
+
+// pruneSnapshots removes all but the most recently written snapshots
+func pruneSnapshots(dir string) error {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+	type snapshot struct {
+		path  string
+		mtime int64
+	}
+	var snapshots []snapshot
+	for _, e := range entries {
+		if !strings.HasPrefix(e.Name(), "index-") || !strings.HasSuffix(e.Name(), ".sqlite") {
+			continue
+		}
+		info, err := e.Info()
+		if err != nil {
+			continue
+		}
+		snapshots = append(snapshots, snapshot{filepath.Join(dir, e.Name()), info.ModTime().UnixNano()})
+	}
+	sort.Slice(snapshots, func(i, j int) bool { return snapshots[i].mtime > snapshots[j].mtime })
+	for i := keepSnapshots; i < len(snapshots); i++ {
+		os.Remove(snapshots[i].path)
+	}
+	return nil
+}
@@ -0,0 +1,101 @@
+package index
+
+import (
+	"database/sql"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/tyroneavnit/keel/internal/store"
+)
+
+const testDecision = `{"id":"DEC-0001","created_at":"2026-01-01T00:00:00Z","type":"product","problem":"p","choice":"from the ledger","decided_by":{"role":"human"},"refs":["JIRA-1"],"status":"active"}` + "\n"
+
+func gitCmd(t *testing.T, dir string, args ...string) {
+	t.Helper()
+	cmd := exec.Command("git", append([]string{"-c", "user.name=Ana", "-c", "user.email=ana@example.com"}, args...)...)
+	cmd.Dir = dir
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("git %v: %v\n%s", args, err, out)
+	}
+}
+
+// choice reads DEC-0001's choice from the index of root
+func choice(t *testing.T, root string, shared bool) string {
+	t.Helper()
+	db, err := Open(root, shared)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	var got string
+	if err := db.QueryRow("SELECT choice FROM decisions WHERE id = 'DEC-0001'").Scan(&got); err != nil {
+		t.Fatal(err)
+	}
+	return got
+}
+
+// TestSharedSnapshot builds the index in one worktree, edits the published
+// snapshot, and checks which worktrees read the edit back
+func TestSharedSnapshot(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+
+	tests := []struct {
+		name   string
+		shared bool
+		ledger string // content of the linked worktree's ledger, when changed
+		want   string
+	}{
+		{name: "same ledger", shared: true, want: "from the snapshot"},
+		{name: "sharing off", shared: false, want: "from the ledger"},
+		{name: "changed ledger", shared: true, ledger: testDecision + "\n", want: "from the ledger"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root, err := filepath.EvalSymlinks(t.TempDir())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := os.MkdirAll(store.GetKeelDir(root), 0755); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.WriteFile(store.GetDecisionsPath(root), []byte(testDecision), 0644); err != nil {
+				t.Fatal(err)
+			}
+			gitCmd(t, root, "init", "--quiet")
+			gitCmd(t, root, "add", store.KeelDir+"/decisions.jsonl")
+			gitCmd(t, root, "commit", "--quiet", "-m", "Record a decision")
+			linked := filepath.Join(t.TempDir(), "linked")
+			gitCmd(t, root, "worktree", "add", "--quiet", linked)
+
+			if got := choice(t, root, true); got != "from the ledger" {
+				t.Fatalf("main worktree choice = %q", got)
+			}
+			snapshots, err := filepath.Glob(filepath.Join(root, ".git", snapshotDir, "index-*.sqlite"))
+			if err != nil || len(snapshots) != 1 {
+				t.Fatalf("snapshots = %v, %v, want one", snapshots, err)
+			}
+			snap, err := sql.Open("sqlite3", snapshots[0])
+			if err != nil {
+				t.Fatal(err)
+			}
+			_, err = snap.Exec("UPDATE decisions SET choice = 'from the snapshot'")
+			snap.Close()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if tt.ledger != "" {
+				if err := os.WriteFile(store.GetDecisionsPath(linked), []byte(tt.ledger), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if got := choice(t, linked, tt.shared); got != tt.want {
+				t.Errorf("linked worktree choice = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
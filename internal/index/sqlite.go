@@ -6,9 +6,13 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 
+	"github.com/tyroneavnit/keel/internal/git"
+	"github.com/tyroneavnit/keel/internal/id"
 	"github.com/tyroneavnit/keel/internal/store"
 	"github.com/tyroneavnit/keel/internal/types"
+	"github.com/tyroneavnit/keel/internal/upstream"
 
 	_ "github.com/ncruces/go-sqlite3/driver"
 	_ "github.com/ncruces/go-sqlite3/embed"
@@ -16,10 +20,17 @@ import (
 
 const IndexFile = "index.sqlite"
 
+// schemaVersion is bumped whenever the index layout changes.
+// The index is derived data, so a mismatch simply drops and rebuilds it.
+const schemaVersion = "9"
+
 // DB wraps a SQLite database connection
 type DB struct {
 	*sql.DB
 	repoRoot string
+	frozen   bool    // a historical index, which is never rebuilt or written
+	shared   bool    // rebuilds are shared across git worktrees
+	gitIndex *string // the git index file, once looked up; "" outside git
 }
 
 // GetIndexPath returns the path to the SQLite index file
@@ -27,8 +38,9 @@ func GetIndexPath(repoRoot string) string {
 	return filepath.Join(store.GetKeelDir(repoRoot), IndexFile)
 }
 
-// Open opens or creates the SQLite index
-func Open(repoRoot string) (*DB, error) {
+// Open opens or creates the SQLite index of an initialized repository.
+// With shared, an index built in one git worktree is reused by the others.
+func Open(repoRoot string, shared bool) (*DB, error) {
 	if repoRoot == "" {
 		var err error
 		repoRoot, err = os.Getwd()
@@ -37,8 +49,9 @@ func Open(repoRoot string) (*DB, error) {
 		}
 	}
 
-	// Ensure .keel directory exists
-	if err := store.EnsureKeelDir(repoRoot); err != nil {
+	// The index lives beside the ledger; never create a .keel directory
+	// for it, which would start a second ledger wherever keel happened to run
+	if err := store.RequireInit(repoRoot); err != nil {
 		return nil, err
 	}
 
@@ -48,7 +61,12 @@ func Open(repoRoot string) (*DB, error) {
 		return nil, fmt.Errorf("failed to open database: %w", err)
 	}
 
-	idx := &DB{DB: db, repoRoot: repoRoot}
+	idx := &DB{DB: db, repoRoot: repoRoot, shared: shared}
+
+	if err := idx.migrate(); err != nil {
+		db.Close()
+		return nil, err
+	}
 
 	if err := idx.createSchema(); err != nil {
 		db.Close()
@@ -65,6 +83,38 @@ func Open(repoRoot string) (*DB, error) {
 	return idx, nil
 }
 
+// migrate drops all derived tables when the stored schema version differs
+func (db *DB) migrate() error {
+	if _, err := db.Exec(`CREATE TABLE IF NOT EXISTS metadata (
+		key TEXT PRIMARY KEY,
+		value TEXT NOT NULL
+	)`); err != nil {
+		return fmt.Errorf("failed to create metadata table: %w", err)
+	}
+
+	var stored string
+	err := db.QueryRow("SELECT value FROM metadata WHERE key = ?", "schema_version").Scan(&stored)
+	if err == nil && stored == schemaVersion {
+		return nil
+	}
+
+	tables := []string{"decisions_fts", "decision_files", "decision_anchors", "decision_annotations", "decision_symbols", "decision_refs", "decisions"}
+	for _, table := range tables {
+		if _, err := db.Exec("DROP TABLE IF EXISTS " + table); err != nil {
+			return fmt.Errorf("failed to drop %s: %w", table, err)
+		}
+	}
+	if _, err := db.Exec("DROP VIEW IF EXISTS decision_heads"); err != nil {
+		return fmt.Errorf("failed to drop decision_heads: %w", err)
+	}
+	if _, err := db.Exec("DELETE FROM metadata"); err != nil {
+		return err
+	}
+
+	_, err = db.Exec("INSERT INTO metadata (key, value) VALUES (?, ?)", "schema_version", schemaVersion)
+	return err
+}
+
 func (db *DB) createSchema() error {
 	schema := []string{
 		`CREATE TABLE IF NOT EXISTS decisions (
@@ -76,17 +126,40 @@ func (db *DB) createSchema() error {
 			rationale TEXT,
 			decided_by_role TEXT NOT NULL,
 			decided_by_identifier TEXT,
+			decided_by_session TEXT,
 			status TEXT NOT NULL,
 			supersedes TEXT,
 			superseded_by TEXT,
+			review_by TEXT,
+			expires_at TEXT,
+			ledger TEXT NOT NULL,
+			upstream TEXT,
 			raw_json TEXT NOT NULL
 		)`,
+		`CREATE INDEX IF NOT EXISTS idx_decisions_identifier ON decisions(decided_by_identifier)`,
+		`CREATE INDEX IF NOT EXISTS idx_decisions_ledger ON decisions(ledger)`,
 		`CREATE TABLE IF NOT EXISTS decision_files (
 			decision_id TEXT NOT NULL,
 			file_path TEXT NOT NULL,
 			PRIMARY KEY (decision_id, file_path)
 		)`,
 		`CREATE INDEX IF NOT EXISTS idx_files_path ON decision_files(file_path)`,
+		`CREATE TABLE IF NOT EXISTS decision_anchors (
+			decision_id TEXT NOT NULL,
+			file_path TEXT NOT NULL,
+			start_line INTEGER NOT NULL,
+			end_line INTEGER NOT NULL,
+			fingerprint TEXT NOT NULL,
+			PRIMARY KEY (decision_id, file_path, start_line)
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_anchors_path ON decision_anchors(file_path)`,
+		`CREATE TABLE IF NOT EXISTS decision_annotations (
+			decision_id TEXT NOT NULL,
+			file_path TEXT NOT NULL,
+			line INTEGER NOT NULL,
+			PRIMARY KEY (decision_id, file_path, line)
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_annotations_path ON decision_annotations(file_path)`,
 		`CREATE TABLE IF NOT EXISTS decision_symbols (
 			decision_id TEXT NOT NULL,
 			symbol TEXT NOT NULL,
@@ -125,6 +198,27 @@ func (db *DB) createSchema() error {
 			key TEXT PRIMARY KEY,
 			value TEXT NOT NULL
 		)`,
+		// Each decision and the head of its supersession chain, the decision
+		// in force: superseded_by is followed while it names a known
+		// decision. path lists the IDs visited so a chain that loops stops
+		// at the first repeat; SQLite takes the other columns from the row
+		// with the greatest depth. As with keel resolve, a chain has no head
+		// when it loops or ends in a link to an unknown decision, so head_id
+		// is NULL.
+		`CREATE VIEW IF NOT EXISTS decision_heads AS
+			WITH RECURSIVE walk(id, head, depth, path, looped) AS (
+				SELECT id, id, 0, ',' || id || ',', 0 FROM decisions
+				UNION ALL
+				SELECT walk.id, next.id, walk.depth + 1, walk.path || next.id || ',',
+					instr(walk.path, ',' || next.id || ',') > 0
+				FROM walk
+				JOIN decisions cur ON cur.id = walk.head
+				JOIN decisions next ON next.id = cur.superseded_by
+				WHERE NOT walk.looped
+			)
+			SELECT walk.id, CASE WHEN head.superseded_by IS NULL THEN head.id END AS head_id, max(walk.depth) AS hops
+			FROM walk JOIN decisions head ON head.id = walk.head
+			GROUP BY walk.id`,
 	}
 
 	for _, stmt := range schema {
@@ -136,156 +230,411 @@ func (db *DB) createSchema() error {
 	return nil
 }
 
+// insertDecision writes a decision and its links, replacing any earlier
+// record of it. An amended or relocated decision can drop files, symbols
+// and refs, so its old links are cleared first.
 func (db *DB) insertDecision(d *types.Decision) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := writeDecision(tx, d); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func writeDecision(tx *sql.Tx, d *types.Decision) error {
+	if d.Ledger == "" {
+		d.Ledger = store.RootLedger
+	}
 	rawJSON, err := json.Marshal(d)
 	if err != nil {
 		return err
 	}
 
-	var rationale, identifier, supersedes, supersededBy interface{}
+	var rationale, identifier, session, supersedes, supersededBy, reviewBy, expiresAt, upstream interface{}
 	if d.Rationale != nil {
 		rationale = *d.Rationale
 	}
 	if d.DecidedBy.Identifier != nil {
 		identifier = *d.DecidedBy.Identifier
 	}
+	if d.DecidedBy.Session != nil {
+		session = *d.DecidedBy.Session
+	}
 	if d.Supersedes != nil {
 		supersedes = *d.Supersedes
 	}
 	if d.SupersededBy != nil {
 		supersededBy = *d.SupersededBy
 	}
+	if d.ReviewBy != nil {
+		reviewBy = *d.ReviewBy
+	}
+	if d.ExpiresAt != nil {
+		expiresAt = *d.ExpiresAt
+	}
+	if d.Upstream != "" {
+		upstream = d.Upstream
+	}
 
-	_, err = db.Exec(`
+	for _, table := range []string{"decision_files", "decision_anchors", "decision_symbols", "decision_refs"} {
+		if _, err := tx.Exec("DELETE FROM "+table+" WHERE decision_id = ?", d.ID); err != nil {
+			return err
+		}
+	}
+
+	_, err = tx.Exec(`
 		INSERT OR REPLACE INTO decisions (
 			id, created_at, type, problem, choice, rationale,
-			decided_by_role, decided_by_identifier, status,
-			supersedes, superseded_by, raw_json
-		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+			decided_by_role, decided_by_identifier, decided_by_session, status,
+			supersedes, superseded_by, review_by, expires_at, ledger, upstream, raw_json
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
 		d.ID, d.CreatedAt, d.Type, d.Problem, d.Choice, rationale,
-		d.DecidedBy.Role, identifier, d.Status,
-		supersedes, supersededBy, string(rawJSON),
+		d.DecidedBy.Role, identifier, session, d.Status,
+		supersedes, supersededBy, reviewBy, expiresAt, d.Ledger, upstream, string(rawJSON),
 	)
 	if err != nil {
 		return err
 	}
 
+	// An upstream decision's files and symbols are another repository's;
+	// only its refs mean the same thing here
+	if d.Upstream != "" {
+		return insertRefs(tx, d)
+	}
+
 	// Insert file associations
 	for _, file := range d.Files {
-		_, err = db.Exec(`INSERT OR IGNORE INTO decision_files (decision_id, file_path) VALUES (?, ?)`,
+		_, err = tx.Exec(`INSERT OR IGNORE INTO decision_files (decision_id, file_path) VALUES (?, ?)`,
 			d.ID, file)
 		if err != nil {
 			return err
 		}
 	}
 
+	// Insert line-range anchors. An anchor's file is also a file
+	// association so file-level lookups still find the decision.
+	for _, a := range d.Anchors {
+		_, err = tx.Exec(`INSERT OR REPLACE INTO decision_anchors (decision_id, file_path, start_line, end_line, fingerprint) VALUES (?, ?, ?, ?, ?)`,
+			d.ID, a.File, a.StartLine, a.EndLine, a.Fingerprint)
+		if err != nil {
+			return err
+		}
+		_, err = tx.Exec(`INSERT OR IGNORE INTO decision_files (decision_id, file_path) VALUES (?, ?)`,
+			d.ID, a.File)
+		if err != nil {
+			return err
+		}
+	}
+
 	// Insert symbol associations
 	for _, symbol := range d.Symbols {
-		_, err = db.Exec(`INSERT OR IGNORE INTO decision_symbols (decision_id, symbol) VALUES (?, ?)`,
+		_, err = tx.Exec(`INSERT OR IGNORE INTO decision_symbols (decision_id, symbol) VALUES (?, ?)`,
 			d.ID, symbol)
 		if err != nil {
 			return err
 		}
 	}
 
-	// Insert ref associations
+	return insertRefs(tx, d)
+}
+
+// insertRefs adds a decision's ref associations
+func insertRefs(tx *sql.Tx, d *types.Decision) error {
 	for _, ref := range d.Refs {
-		_, err = db.Exec(`INSERT OR IGNORE INTO decision_refs (decision_id, ref_id) VALUES (?, ?)`,
+		_, err := tx.Exec(`INSERT OR IGNORE INTO decision_refs (decision_id, ref_id) VALUES (?, ?)`,
 			d.ID, ref)
 		if err != nil {
 			return err
 		}
 	}
-
 	return nil
 }
 
 func (db *DB) needsRebuild() bool {
-	decisionsPath := store.GetDecisionsPath(db.repoRoot)
+	if db.frozen {
+		return false
+	}
+	current, err := db.ledgerMtimes()
+	if err != nil {
+		return true
+	}
+
+	var stored string
+	err = db.QueryRow("SELECT value FROM metadata WHERE key = ?", "ledger_mtimes").Scan(&stored)
+	if err != nil {
+		return true // No mtimes recorded
+	}
+	return current != stored
+}
+
+// ledgerMtimes describes every ledger and when it last changed, so adding,
+// removing or writing to any ledger triggers a rebuild
+func (db *DB) ledgerMtimes() (string, error) {
+	ledgers, err := db.ledgers(false)
+	if err != nil {
+		return "", err
+	}
+	var parts []string
+	for _, ledger := range ledgers {
+		info, err := os.Stat(store.GetDecisionsPath(store.LedgerDir(db.repoRoot, ledger)))
+		if err != nil {
+			continue
+		}
+		parts = append(parts, fmt.Sprintf("%s:%d", ledger, info.ModTime().UnixNano()))
+	}
+	names, err := upstream.Names(db.repoRoot)
+	if err != nil {
+		return "", err
+	}
+	for _, name := range names {
+		if info, err := os.Stat(upstream.LedgerPath(db.repoRoot, name)); err == nil {
+			parts = append(parts, fmt.Sprintf("%s:%s:%d", upstream.DirName, name, info.ModTime().UnixNano()))
+		}
+	}
+	return strings.Join(parts, " "), nil
+}
 
-	info, err := os.Stat(decisionsPath)
-	if os.IsNotExist(err) {
-		return false // No source file
+// listLedgers finds every ledger in a repository; tests count its calls
+var listLedgers = store.Ledgers
+
+// ledgers returns the repo-relative directories of every ledger. Listing
+// them walks the tree through git, so the list is kept in the metadata table
+// with a stamp of the git index and of the directories that hold ledgers,
+// and listed again only when rescan is set or the stamp has changed. A new
+// ledger that is neither added to git nor beside a known one is found at the
+// next rebuild.
+func (db *DB) ledgers(rescan bool) ([]string, error) {
+	if !rescan {
+		var list, stamp string
+		err := db.QueryRow(`SELECT l.value, s.value FROM metadata l, metadata s
+			WHERE l.key = 'ledgers' AND s.key = 'ledgers_stamp'`).Scan(&list, &stamp)
+		var ledgers []string
+		if err == nil && json.Unmarshal([]byte(list), &ledgers) == nil && db.ledgersStamp(ledgers) == stamp {
+			return ledgers, nil
+		}
 	}
 
-	var storedMtime string
-	err = db.QueryRow("SELECT value FROM metadata WHERE key = ?", "jsonl_mtime").Scan(&storedMtime)
+	// Stat the git index first, so a ledger added during the walk still
+	// changes the stamp
+	gitIndex := db.gitIndexStamp()
+	ledgers, err := listLedgers(db.repoRoot)
+	if err != nil {
+		return nil, err
+	}
+	list, err := json.Marshal(ledgers)
 	if err != nil {
-		return true // No mtime recorded
+		return nil, err
 	}
+	stamp := gitIndex + " " + db.dirsStamp(ledgers)
+	_, err = db.Exec("INSERT OR REPLACE INTO metadata (key, value) VALUES (?, ?), (?, ?)",
+		"ledgers", string(list), "ledgers_stamp", stamp)
+	return ledgers, err
+}
+
+// ledgersStamp describes when the git index and the directories holding
+// ledgers last changed
+func (db *DB) ledgersStamp(ledgers []string) string {
+	return db.gitIndexStamp() + " " + db.dirsStamp(ledgers)
+}
 
-	currentMtime := fmt.Sprintf("%d", info.ModTime().UnixNano())
-	return currentMtime != storedMtime
+func (db *DB) gitIndexStamp() string {
+	if db.gitIndex == nil {
+		path, err := git.Run(db.repoRoot, "rev-parse", "--git-path", "index")
+		if err == nil {
+			path = filepath.FromSlash(path)
+			if !filepath.IsAbs(path) {
+				path = filepath.Join(db.repoRoot, path)
+			}
+		}
+		db.gitIndex = &path
+	}
+	return mtime(*db.gitIndex)
+}
+
+// dirsStamp covers the repository root, where new top-level directories
+// appear, and each ledger's directory and whether its ledger file still
+// exists. The .keel directories themselves change with every index write
+// and lock, so they are left out.
+func (db *DB) dirsStamp(ledgers []string) string {
+	parts := []string{mtime(db.repoRoot)}
+	for _, ledger := range ledgers {
+		dir := store.LedgerDir(db.repoRoot, ledger)
+		_, err := os.Stat(store.GetDecisionsPath(dir))
+		parts = append(parts, fmt.Sprintf("%s:%s:%t", ledger, mtime(dir), err == nil))
+	}
+	return strings.Join(parts, " ")
+}
+
+// mtime returns when path last changed, or "-" when it doesn't exist
+func mtime(path string) string {
+	if path == "" {
+		return "-"
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return "-"
+	}
+	return fmt.Sprint(info.ModTime().UnixNano())
 }
 
 func (db *DB) rebuild() error {
+	// The ledger set may have changed with the ledgers themselves
+	ledgers, err := db.ledgers(true)
+	if err != nil {
+		return err
+	}
+
+	// A worktree may already have built this ledger state
+	snapshot, err := db.snapshotPath(ledgers)
+	if err != nil {
+		return err
+	}
+	if snapshot != "" {
+		if ok, err := db.restoreSnapshot(snapshot); err == nil && ok {
+			return db.recordMtime()
+		}
+	}
+
 	// Clear existing data
-	tables := []string{"decision_files", "decision_symbols", "decision_refs", "decisions"}
+	tables := []string{"decision_files", "decision_anchors", "decision_symbols", "decision_refs", "decisions"}
 	for _, table := range tables {
 		if _, err := db.Exec("DELETE FROM " + table); err != nil {
 			return err
 		}
 	}
 
-	// Read all decisions from JSONL
-	decisions, err := store.ReadAllDecisions(db.repoRoot)
-	if err != nil {
+	// Build latest state from the JSONL of every ledger
+	for _, ledger := range ledgers {
+		state, err := store.GetLatestState(store.LedgerDir(db.repoRoot, ledger))
+		if err != nil {
+			return err
+		}
+		for _, d := range state {
+			d.Ledger = ledger
+			if err := db.insertDecision(d); err != nil {
+				return err
+			}
+		}
+	}
+	if err := db.insertUpstreams(); err != nil {
 		return err
 	}
 
-	// Build latest state
-	state := make(map[string]*types.Decision)
-	for _, d := range decisions {
-		if existing, ok := state[d.ID]; ok {
-			// Merge
-			if d.Status != "" {
-				existing.Status = d.Status
-			}
-			if d.SupersededBy != nil {
-				existing.SupersededBy = d.SupersededBy
-			}
-		} else {
-			state[d.ID] = d
-		}
+	if err := db.recordMtime(); err != nil {
+		return err
 	}
 
-	// Insert all decisions
-	for _, d := range state {
-		if err := db.insertDecision(d); err != nil {
-			return err
+	// Publish only if the ledgers did not change while they were read. The
+	// snapshot is a cache, so failing to write one is not an error.
+	if snapshot != "" {
+		if current, err := db.snapshotPath(ledgers); err == nil && current == snapshot {
+			db.publishSnapshot(snapshot)
 		}
 	}
+	return nil
+}
 
-	// Store mtime
-	decisionsPath := store.GetDecisionsPath(db.repoRoot)
-	if info, err := os.Stat(decisionsPath); err == nil {
-		mtime := fmt.Sprintf("%d", info.ModTime().UnixNano())
-		_, err = db.Exec("INSERT OR REPLACE INTO metadata (key, value) VALUES (?, ?)",
-			"jsonl_mtime", mtime)
+// insertUpstreams indexes the cached upstream ledgers read-only, each
+// decision's ID and links qualified with its upstream's name
+func (db *DB) insertUpstreams() error {
+	names, err := upstream.Names(db.repoRoot)
+	if err != nil {
+		return err
+	}
+	for _, name := range names {
+		state, err := store.GetLatestStateFile(upstream.LedgerPath(db.repoRoot, name))
 		if err != nil {
-			return err
+			return fmt.Errorf("upstream %s: %w", name, err)
+		}
+		for _, d := range state {
+			d.ID = id.Qualify(name, d.ID)
+			if d.Supersedes != nil {
+				qualified := id.Qualify(name, *d.Supersedes)
+				d.Supersedes = &qualified
+			}
+			if d.SupersededBy != nil {
+				qualified := id.Qualify(name, *d.SupersededBy)
+				d.SupersededBy = &qualified
+			}
+			d.Ledger = store.RootLedger
+			d.Upstream = name
+			if err := db.insertDecision(d); err != nil {
+				return err
+			}
 		}
 	}
+	return nil
+}
 
+// recordMtime stores the ledgers' modification times, which needsRebuild
+// compares against
+func (db *DB) recordMtime() error {
+	mtimes, err := db.ledgerMtimes()
+	if err != nil {
+		return err
+	}
+	_, err = db.Exec("INSERT OR REPLACE INTO metadata (key, value) VALUES (?, ?)",
+		"ledger_mtimes", mtimes)
+	return err
+}
+
+// Refresh rebuilds the index if the ledger changed since it was last read.
+// Long-running processes call it before each request.
+func (db *DB) Refresh() error {
+	if db.needsRebuild() {
+		return db.rebuild()
+	}
 	return nil
 }
 
+// Frozen reports whether this is a historical index opened with OpenFrozen
+func (db *DB) Frozen() bool {
+	return db.frozen
+}
+
+// RepoRoot returns the repository the index was opened for
+func (db *DB) RepoRoot() string {
+	return db.repoRoot
+}
+
 // IndexDecision adds a decision to the index
 func (db *DB) IndexDecision(d *types.Decision) error {
+	if db.frozen {
+		return fmt.Errorf("historical index is read-only")
+	}
 	if err := db.insertDecision(d); err != nil {
 		return err
 	}
+	return db.recordMtime()
+}
+
+// ReplaceAnnotations stores the keel: markers found by a source scan,
+// replacing those from the previous scan. Annotations come from the working
+// tree rather than the ledger, so rebuilding from JSONL leaves them in place.
+func (db *DB) ReplaceAnnotations(annotations []types.Annotation) error {
+	if db.frozen {
+		return fmt.Errorf("historical index is read-only")
+	}
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
 
-	// Update mtime
-	decisionsPath := store.GetDecisionsPath(db.repoRoot)
-	if info, err := os.Stat(decisionsPath); err == nil {
-		mtime := fmt.Sprintf("%d", info.ModTime().UnixNano())
-		_, err = db.Exec("INSERT OR REPLACE INTO metadata (key, value) VALUES (?, ?)",
-			"jsonl_mtime", mtime)
+	if _, err := tx.Exec("DELETE FROM decision_annotations"); err != nil {
+		return err
+	}
+	for _, a := range annotations {
+		_, err := tx.Exec(`INSERT OR IGNORE INTO decision_annotations (decision_id, file_path, line) VALUES (?, ?, ?)`,
+			a.DecisionID, a.File, a.Line)
 		if err != nil {
 			return err
 		}
 	}
-
-	return nil
+	return tx.Commit()
 }
@@ -0,0 +1,113 @@
+package index
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/tyroneavnit/keel/internal/store"
+)
+
+// TestLedgersCache checks that the ledger list is only walked again when
+// the git index or a ledger directory changes
+func TestLedgersCache(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	root, err := filepath.EvalSymlinks(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	nested := filepath.Join(root, "svc")
+	for _, dir := range []string{store.GetKeelDir(root), nested} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.WriteFile(store.GetDecisionsPath(root), []byte(testDecision), 0644); err != nil {
+		t.Fatal(err)
+	}
+	gitCmd(t, root, "init", "--quiet")
+	gitCmd(t, root, "add", ".keel/decisions.jsonl")
+
+	scans := 0
+	listLedgers = func(root string) ([]string, error) {
+		scans++
+		return store.Ledgers(root)
+	}
+	defer func() { listLedgers = store.Ledgers }()
+
+	db, err := Open(root, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	nestedLedger := store.GetDecisionsPath(nested)
+	tests := []struct {
+		name   string
+		change func()
+		scan   bool
+		want   []string
+	}{
+		{name: "unchanged", change: func() {}, want: []string{"."}},
+		{
+			name: "untracked ledger in an unwatched directory",
+			change: func() {
+				if err := os.MkdirAll(store.GetKeelDir(nested), 0755); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.WriteFile(nestedLedger, nil, 0644); err != nil {
+					t.Fatal(err)
+				}
+			},
+			want: []string{"."},
+		},
+		{
+			name: "root touched, as keel init does",
+			change: func() {
+				now := time.Now()
+				if err := os.Chtimes(root, now, now); err != nil {
+					t.Fatal(err)
+				}
+			},
+			scan: true,
+			want: []string{".", "svc"},
+		},
+		{name: "ledger added to git", change: func() { gitCmd(t, root, "add", "svc") }, scan: true, want: []string{".", "svc"}},
+		{name: "unchanged after adding", change: func() {}, want: []string{".", "svc"}},
+		{
+			name: "ledger removed",
+			change: func() {
+				if err := os.Remove(nestedLedger); err != nil {
+					t.Fatal(err)
+				}
+			},
+			scan: true,
+			want: []string{".", "svc"}, // still in the git index
+		},
+		{name: "removed from git", change: func() { gitCmd(t, root, "rm", "--cached", "--quiet", "svc/.keel/decisions.jsonl") }, scan: true, want: []string{"."}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.change()
+			scans = 0
+			if err := db.Refresh(); err != nil {
+				t.Fatal(err)
+			}
+			got, err := db.ledgers(false)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if (scans > 0) != tt.scan {
+				t.Errorf("listed ledgers %d times, want a scan: %t", scans, tt.scan)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ledgers = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
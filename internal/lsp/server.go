@@ -0,0 +1,421 @@
+package lsp
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/textproto"
+	"net/url"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// JSON-RPC error codes
+const (
+	codeParseError     = -32700
+	codeInvalidRequest = -32600
+	codeMethodNotFound = -32601
+	codeInvalidParams  = -32602
+	codeInternalError  = -32603
+)
+
+// Diagnostic severities
+const (
+	SeverityError       = 1
+	SeverityWarning     = 2
+	SeverityInformation = 3
+	SeverityHint        = 4
+)
+
+// Position is a zero-based line and UTF-16 character offset
+type Position struct {
+	Line      int `json:"line"`
+	Character int `json:"character"`
+}
+
+// Range is a half-open span between two positions
+type Range struct {
+	Start Position `json:"start"`
+	End   Position `json:"end"`
+}
+
+// Location is a range in a document
+type Location struct {
+	URI   string `json:"uri"`
+	Range Range  `json:"range"`
+}
+
+// Command is shown as the title of a code lens. Clients that don't know the
+// command still display the title.
+type Command struct {
+	Title     string        `json:"title"`
+	Command   string        `json:"command"`
+	Arguments []interface{} `json:"arguments,omitempty"`
+}
+
+// CodeLens is an annotation shown above a line
+type CodeLens struct {
+	Range   Range    `json:"range"`
+	Command *Command `json:"command,omitempty"`
+}
+
+// Diagnostic is a problem reported in a document
+type Diagnostic struct {
+	Range    Range  `json:"range"`
+	Severity int    `json:"severity"`
+	Source   string `json:"source,omitempty"`
+	Message  string `json:"message"`
+}
+
+// Document is an open text document as last sent by the client
+type Document struct {
+	URI     string
+	Version int
+	Text    string
+}
+
+// Path returns the filesystem path of a file:// document, or "" for other schemes
+func (d *Document) Path() string {
+	return URIToPath(d.URI)
+}
+
+// Line returns the text of a zero-based line without its line ending
+func (d *Document) Line(n int) string {
+	lines := strings.Split(d.Text, "\n")
+	if n < 0 || n >= len(lines) {
+		return ""
+	}
+	return strings.TrimSuffix(lines[n], "\r")
+}
+
+// Server speaks the Language Server Protocol over stdio. Handlers may be nil
+// to leave a feature out. Requests are handled one at a time, in order.
+type Server struct {
+	Name    string
+	Version string
+
+	// Hover returns Markdown for a position, or "" for no hover
+	Hover func(doc *Document, pos Position) (string, error)
+	// Definition returns where the token at a position is defined, or nil
+	Definition func(doc *Document, pos Position) (*Location, error)
+	// CodeLenses returns the lenses for a document
+	CodeLenses func(doc *Document) ([]CodeLens, error)
+	// Diagnostics is run when a document is opened, changed or saved
+	Diagnostics func(doc *Document) ([]Diagnostic, error)
+
+	// BeforeRequest runs before each request or document change, e.g. to
+	// refresh state that may have changed since the last one
+	BeforeRequest func() error
+
+	docs map[string]*Document
+	out  io.Writer
+}
+
+type message struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params,omitempty"`
+}
+
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+type textDocumentParams struct {
+	TextDocument struct {
+		URI     string `json:"uri"`
+		Version int    `json:"version"`
+		Text    string `json:"text"`
+	} `json:"textDocument"`
+	Position       Position `json:"position"`
+	ContentChanges []struct {
+		Text string `json:"text"`
+	} `json:"contentChanges"`
+}
+
+// Serve reads Content-Length framed messages from in and writes responses
+// and notifications to out. It returns when the client sends exit or closes in.
+func (s *Server) Serve(in io.Reader, out io.Writer) error {
+	s.docs = make(map[string]*Document)
+	s.out = out
+	reader := textproto.NewReader(bufio.NewReader(in))
+
+	for {
+		header, err := reader.ReadMIMEHeader()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read message header: %w", err)
+		}
+		length, err := strconv.Atoi(header.Get("Content-Length"))
+		if err != nil || length < 0 {
+			return fmt.Errorf("invalid Content-Length: %q", header.Get("Content-Length"))
+		}
+		body := make([]byte, length)
+		if _, err := io.ReadFull(reader.R, body); err != nil {
+			return fmt.Errorf("failed to read message body: %w", err)
+		}
+
+		var msg message
+		if err := json.Unmarshal(body, &msg); err != nil {
+			if err := s.respond(json.RawMessage("null"), nil, &rpcError{codeParseError, "parse error"}); err != nil {
+				return err
+			}
+			continue
+		}
+		if msg.Method == "exit" {
+			return nil
+		}
+
+		result, rerr := s.handle(msg)
+
+		// Notifications carry no ID and get no response
+		if len(msg.ID) == 0 {
+			continue
+		}
+		if err := s.respond(msg.ID, result, rerr); err != nil {
+			return err
+		}
+	}
+}
+
+func (s *Server) handle(msg message) (interface{}, *rpcError) {
+	if msg.JSONRPC != "2.0" {
+		return nil, &rpcError{codeInvalidRequest, "jsonrpc must be 2.0"}
+	}
+
+	switch msg.Method {
+	case "initialize":
+		capabilities := map[string]interface{}{
+			"textDocumentSync": map[string]interface{}{
+				"openClose": true,
+				"change":    1, // full text on every change
+				"save":      map[string]bool{"includeText": false},
+			},
+			"hoverProvider":      s.Hover != nil,
+			"definitionProvider": s.Definition != nil,
+		}
+		if s.CodeLenses != nil {
+			capabilities["codeLensProvider"] = map[string]bool{"resolveProvider": false}
+		}
+		return map[string]interface{}{
+			"capabilities": capabilities,
+			"serverInfo":   map[string]string{"name": s.Name, "version": s.Version},
+		}, nil
+
+	case "shutdown":
+		return nil, nil
+
+	case "textDocument/didOpen", "textDocument/didChange", "textDocument/didSave", "textDocument/didClose":
+		var params textDocumentParams
+		if err := json.Unmarshal(msg.Params, &params); err != nil {
+			return nil, nil
+		}
+		s.sync(msg.Method, params)
+		return nil, nil
+
+	case "textDocument/hover", "textDocument/definition", "textDocument/codeLens":
+		var params textDocumentParams
+		if err := json.Unmarshal(msg.Params, &params); err != nil {
+			return nil, &rpcError{codeInvalidParams, "invalid params"}
+		}
+		doc := s.docs[params.TextDocument.URI]
+		if doc == nil {
+			return nil, &rpcError{codeInvalidParams, fmt.Sprintf("document not open: %s", params.TextDocument.URI)}
+		}
+		if err := s.before(); err != nil {
+			return nil, &rpcError{codeInternalError, err.Error()}
+		}
+		return s.query(msg.Method, doc, params.Position)
+	}
+
+	if len(msg.ID) == 0 || strings.HasPrefix(msg.Method, "$/") {
+		// Unknown notifications, like initialized and $/cancelRequest, are ignored
+		return nil, nil
+	}
+	return nil, &rpcError{codeMethodNotFound, fmt.Sprintf("method not found: %s", msg.Method)}
+}
+
+func (s *Server) query(method string, doc *Document, pos Position) (interface{}, *rpcError) {
+	switch method {
+	case "textDocument/hover":
+		if s.Hover == nil {
+			return nil, nil
+		}
+		text, err := s.Hover(doc, pos)
+		if err != nil {
+			return nil, &rpcError{codeInternalError, err.Error()}
+		}
+		if text == "" {
+			return nil, nil
+		}
+		return map[string]interface{}{
+			"contents": map[string]string{"kind": "markdown", "value": text},
+		}, nil
+
+	case "textDocument/definition":
+		if s.Definition == nil {
+			return nil, nil
+		}
+		loc, err := s.Definition(doc, pos)
+		if err != nil {
+			return nil, &rpcError{codeInternalError, err.Error()}
+		}
+		if loc == nil {
+			return nil, nil
+		}
+		return loc, nil
+
+	case "textDocument/codeLens":
+		lenses := []CodeLens{}
+		if s.CodeLenses != nil {
+			found, err := s.CodeLenses(doc)
+			if err != nil {
+				return nil, &rpcError{codeInternalError, err.Error()}
+			}
+			lenses = append(lenses, found...)
+		}
+		return lenses, nil
+	}
+	return nil, nil
+}
+
+// sync tracks open documents and publishes their diagnostics
+func (s *Server) sync(method string, params textDocumentParams) {
+	uri := params.TextDocument.URI
+	switch method {
+	case "textDocument/didOpen":
+		s.docs[uri] = &Document{URI: uri, Version: params.TextDocument.Version, Text: params.TextDocument.Text}
+	case "textDocument/didChange":
+		doc := s.docs[uri]
+		if doc == nil || len(params.ContentChanges) == 0 {
+			return
+		}
+		doc.Version = params.TextDocument.Version
+		doc.Text = params.ContentChanges[len(params.ContentChanges)-1].Text
+	case "textDocument/didClose":
+		delete(s.docs, uri)
+		s.publish(uri, []Diagnostic{})
+		return
+	}
+
+	doc := s.docs[uri]
+	if doc == nil || s.Diagnostics == nil {
+		return
+	}
+	if err := s.before(); err != nil {
+		s.logMessage(err.Error())
+		return
+	}
+	diagnostics, err := s.Diagnostics(doc)
+	if err != nil {
+		s.logMessage(err.Error())
+		return
+	}
+	if diagnostics == nil {
+		diagnostics = []Diagnostic{}
+	}
+	s.publish(uri, diagnostics)
+}
+
+func (s *Server) publish(uri string, diagnostics []Diagnostic) {
+	s.notify("textDocument/publishDiagnostics", map[string]interface{}{
+		"uri":         uri,
+		"diagnostics": diagnostics,
+	})
+}
+
+func (s *Server) logMessage(text string) {
+	s.notify("window/logMessage", map[string]interface{}{"type": 1, "message": text})
+}
+
+func (s *Server) before() error {
+	if s.BeforeRequest == nil {
+		return nil
+	}
+	return s.BeforeRequest()
+}
+
+func (s *Server) respond(id json.RawMessage, result interface{}, rerr *rpcError) error {
+	msg := map[string]interface{}{"jsonrpc": "2.0", "id": id}
+	if rerr != nil {
+		msg["error"] = rerr
+	} else {
+		msg["result"] = result
+	}
+	return s.write(msg)
+}
+
+func (s *Server) notify(method string, params interface{}) {
+	s.write(map[string]interface{}{"jsonrpc": "2.0", "method": method, "params": params})
+}
+
+func (s *Server) write(msg interface{}) error {
+	body, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	if _, err := fmt.Fprintf(s.out, "Content-Length: %d\r\n\r\n", len(body)); err != nil {
+		return err
+	}
+	_, err = s.out.Write(body)
+	return err
+}
+
+// URIToPath converts a file:// URI to a filesystem path, or returns "" for other schemes
+func URIToPath(uri string) string {
+	u, err := url.Parse(uri)
+	if err != nil || u.Scheme != "file" {
+		return ""
+	}
+	path := u.Path
+	// file:///C:/dir on Windows
+	if len(path) >= 3 && path[0] == '/' && path[2] == ':' {
+		path = path[1:]
+	}
+	return filepath.FromSlash(path)
+}
+
+// PathToURI converts an absolute filesystem path to a file:// URI
+func PathToURI(path string) string {
+	path = filepath.ToSlash(path)
+	if !strings.HasPrefix(path, "/") {
+		path = "/" + path
+	}
+	return (&url.URL{Scheme: "file", Path: path}).String()
+}
+
+// ByteOffset converts a UTF-16 character offset within a line to a byte offset
+func ByteOffset(line string, character int) int {
+	units := 0
+	for i, r := range line {
+		if units >= character {
+			return i
+		}
+		units += len(utf16.Encode([]rune{r}))
+	}
+	return len(line)
+}
+
+// Character converts a byte offset within a line to a UTF-16 character offset
+func Character(line string, offset int) int {
+	if offset > len(line) {
+		offset = len(line)
+	}
+	units := 0
+	for _, r := range line[:offset] {
+		if r == utf8.RuneError {
+			units++
+			continue
+		}
+		units += len(utf16.Encode([]rune{r}))
+	}
+	return units
+}
@@ -0,0 +1,248 @@
+package lsp
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/textproto"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func testServer() *Server {
+	return &Server{
+		Name:    "keel",
+		Version: "test",
+		Hover: func(doc *Document, pos Position) (string, error) {
+			if !strings.Contains(doc.Line(pos.Line), "DEC-") {
+				return "", nil
+			}
+			return fmt.Sprintf("decision on line %d", pos.Line+1), nil
+		},
+		Definition: func(doc *Document, pos Position) (*Location, error) {
+			at := Position{Line: 3}
+			return &Location{URI: "file:///repo/.keel/decisions.jsonl", Range: Range{Start: at, End: at}}, nil
+		},
+		CodeLenses: func(doc *Document) ([]CodeLens, error) {
+			return []CodeLens{{Command: &Command{Title: "1 decision", Command: "keel.showDecisions"}}}, nil
+		},
+		Diagnostics: func(doc *Document) ([]Diagnostic, error) {
+			var diagnostics []Diagnostic
+			for n, line := range strings.Split(doc.Text, "\n") {
+				if i := strings.Index(line, "DEC-0001"); i >= 0 {
+					diagnostics = append(diagnostics, Diagnostic{
+						Range:    Range{Start: Position{n, Character(line, i)}, End: Position{n, Character(line, i+8)}},
+						Severity: SeverityWarning,
+						Message:  "DEC-0001 is superseded",
+					})
+				}
+			}
+			return diagnostics, nil
+		},
+	}
+}
+
+// TestServe drives the server as an editor would. Each step sends one
+// message and lists the messages the server must send back, in order.
+func TestServe(t *testing.T) {
+	const uri = "file:///repo/main.go"
+	steps := []struct {
+		name    string
+		message string
+		want    []string
+	}{
+		{
+			name:    "initialize",
+			message: `{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"capabilities":{}}}`,
+			want: []string{`{"jsonrpc":"2.0","id":1,"result":{"capabilities":{
+				"textDocumentSync":{"openClose":true,"change":1,"save":{"includeText":false}},
+				"hoverProvider":true,"definitionProvider":true,"codeLensProvider":{"resolveProvider":false}},
+				"serverInfo":{"name":"keel","version":"test"}}}`},
+		},
+		{
+			name:    "initialized",
+			message: `{"jsonrpc":"2.0","method":"initialized","params":{}}`,
+		},
+		{
+			name:    "open",
+			message: `{"jsonrpc":"2.0","method":"textDocument/didOpen","params":{"textDocument":{"uri":"` + uri + `","version":1,"text":"x := 1\n// é DEC-0001\n"}}}`,
+			want: []string{`{"jsonrpc":"2.0","method":"textDocument/publishDiagnostics","params":{"uri":"` + uri + `","diagnostics":[
+				{"range":{"start":{"line":1,"character":5},"end":{"line":1,"character":13}},"severity":2,"message":"DEC-0001 is superseded"}]}}`},
+		},
+		{
+			name:    "hover",
+			message: `{"jsonrpc":"2.0","id":2,"method":"textDocument/hover","params":{"textDocument":{"uri":"` + uri + `"},"position":{"line":1,"character":6}}}`,
+			want:    []string{`{"jsonrpc":"2.0","id":2,"result":{"contents":{"kind":"markdown","value":"decision on line 2"}}}`},
+		},
+		{
+			name:    "no hover",
+			message: `{"jsonrpc":"2.0","id":3,"method":"textDocument/hover","params":{"textDocument":{"uri":"` + uri + `"},"position":{"line":0,"character":0}}}`,
+			want:    []string{`{"jsonrpc":"2.0","id":3,"result":null}`},
+		},
+		{
+			name:    "definition",
+			message: `{"jsonrpc":"2.0","id":4,"method":"textDocument/definition","params":{"textDocument":{"uri":"` + uri + `"},"position":{"line":1,"character":6}}}`,
+			want: []string{`{"jsonrpc":"2.0","id":4,"result":{"uri":"file:///repo/.keel/decisions.jsonl",
+				"range":{"start":{"line":3,"character":0},"end":{"line":3,"character":0}}}}`},
+		},
+		{
+			name:    "code lens",
+			message: `{"jsonrpc":"2.0","id":5,"method":"textDocument/codeLens","params":{"textDocument":{"uri":"` + uri + `"}}}`,
+			want: []string{`{"jsonrpc":"2.0","id":5,"result":[{"range":{"start":{"line":0,"character":0},"end":{"line":0,"character":0}},
+				"command":{"title":"1 decision","command":"keel.showDecisions"}}]}`},
+		},
+		{
+			name:    "change",
+			message: `{"jsonrpc":"2.0","method":"textDocument/didChange","params":{"textDocument":{"uri":"` + uri + `","version":2},"contentChanges":[{"text":"x := 2\n"}]}}`,
+			want:    []string{`{"jsonrpc":"2.0","method":"textDocument/publishDiagnostics","params":{"uri":"` + uri + `","diagnostics":[]}}`},
+		},
+		{
+			name:    "changed text",
+			message: `{"jsonrpc":"2.0","id":6,"method":"textDocument/hover","params":{"textDocument":{"uri":"` + uri + `"},"position":{"line":1,"character":6}}}`,
+			want:    []string{`{"jsonrpc":"2.0","id":6,"result":null}`},
+		},
+		{
+			name:    "document not open",
+			message: `{"jsonrpc":"2.0","id":7,"method":"textDocument/hover","params":{"textDocument":{"uri":"file:///repo/other.go"},"position":{"line":0,"character":0}}}`,
+			want:    []string{`{"jsonrpc":"2.0","id":7,"error":{"code":-32602,"message":"document not open: file:///repo/other.go"}}`},
+		},
+		{
+			name:    "unknown method",
+			message: `{"jsonrpc":"2.0","id":8,"method":"textDocument/rename","params":{}}`,
+			want:    []string{`{"jsonrpc":"2.0","id":8,"error":{"code":-32601,"message":"method not found: textDocument/rename"}}`},
+		},
+		{
+			name:    "cancel",
+			message: `{"jsonrpc":"2.0","method":"$/cancelRequest","params":{"id":8}}`,
+		},
+		{
+			name:    "parse error",
+			message: `{"jsonrpc"`,
+			want:    []string{`{"jsonrpc":"2.0","id":null,"error":{"code":-32700,"message":"parse error"}}`},
+		},
+		{
+			name:    "close",
+			message: `{"jsonrpc":"2.0","method":"textDocument/didClose","params":{"textDocument":{"uri":"` + uri + `"}}}`,
+			want:    []string{`{"jsonrpc":"2.0","method":"textDocument/publishDiagnostics","params":{"uri":"` + uri + `","diagnostics":[]}}`},
+		},
+		{
+			name:    "shutdown",
+			message: `{"jsonrpc":"2.0","id":9,"method":"shutdown"}`,
+			want:    []string{`{"jsonrpc":"2.0","id":9,"result":null}`},
+		},
+		{
+			name:    "exit",
+			message: `{"jsonrpc":"2.0","method":"exit"}`,
+		},
+	}
+
+	clientIn, serverOut := io.Pipe()
+	serverIn, clientOut := io.Pipe()
+	done := make(chan error, 1)
+	go func() {
+		done <- testServer().Serve(serverIn, serverOut)
+		serverOut.Close()
+	}()
+
+	replies := textproto.NewReader(bufio.NewReader(clientIn))
+	for _, step := range steps {
+		if _, err := fmt.Fprintf(clientOut, "Content-Length: %d\r\n\r\n%s", len(step.message), step.message); err != nil {
+			t.Fatalf("%s: %v", step.name, err)
+		}
+		for _, want := range step.want {
+			got, err := readMessage(replies)
+			if err != nil {
+				t.Fatalf("%s: %v", step.name, err)
+			}
+			if !sameJSON(t, got, want) {
+				t.Errorf("%s: got %s, want %s", step.name, got, want)
+			}
+		}
+	}
+
+	if err := <-done; err != nil {
+		t.Errorf("Serve() = %v", err)
+	}
+	if extra, err := io.ReadAll(replies.R); err != nil || len(extra) > 0 {
+		t.Errorf("unexpected output after exit: %q, %v", extra, err)
+	}
+	clientOut.Close()
+}
+
+func readMessage(r *textproto.Reader) ([]byte, error) {
+	header, err := r.ReadMIMEHeader()
+	if err != nil {
+		return nil, err
+	}
+	length, err := strconv.Atoi(header.Get("Content-Length"))
+	if err != nil {
+		return nil, err
+	}
+	body := make([]byte, length)
+	_, err = io.ReadFull(r.R, body)
+	return body, err
+}
+
+func sameJSON(t *testing.T, got []byte, want string) bool {
+	t.Helper()
+	var g, w interface{}
+	if err := json.Unmarshal(got, &g); err != nil {
+		t.Fatalf("invalid message %s: %v", got, err)
+	}
+	if err := json.Unmarshal([]byte(want), &w); err != nil {
+		t.Fatal(err)
+	}
+	return reflect.DeepEqual(g, w)
+}
+
+func TestOffsets(t *testing.T) {
+	tests := []struct {
+		name      string
+		line      string
+		character int // UTF-16 units
+		offset    int // bytes
+	}{
+		{"ascii", "DEC-0001", 4, 4},
+		{"accent", "é DEC-0001", 2, 3},
+		{"astral", "🙂 DEC-0001", 3, 5},
+		{"end of line", "abc", 3, 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ByteOffset(tt.line, tt.character); got != tt.offset {
+				t.Errorf("ByteOffset(%q, %d) = %d, want %d", tt.line, tt.character, got, tt.offset)
+			}
+			if got := Character(tt.line, tt.offset); got != tt.character {
+				t.Errorf("Character(%q, %d) = %d, want %d", tt.line, tt.offset, got, tt.character)
+			}
+		})
+	}
+	if got := ByteOffset("abc", 10); got != 3 {
+		t.Errorf("ByteOffset past the end = %d, want 3", got)
+	}
+}
+
+func TestURIs(t *testing.T) {
+	tests := []struct {
+		uri, path string
+	}{
+		{"file:///repo/main.go", "/repo/main.go"},
+		{"file:///repo/with%20space.go", "/repo/with space.go"},
+		{"untitled:Untitled-1", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.uri, func(t *testing.T) {
+			if got := URIToPath(tt.uri); got != tt.path {
+				t.Errorf("URIToPath(%q) = %q, want %q", tt.uri, got, tt.path)
+			}
+			if tt.path != "" {
+				if got := PathToURI(tt.path); got != tt.uri {
+					t.Errorf("PathToURI(%q) = %q, want %q", tt.path, got, tt.uri)
+				}
+			}
+		})
+	}
+}
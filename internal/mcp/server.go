@@ -0,0 +1,227 @@
+package mcp
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// ProtocolVersion is the MCP revision the server implements
+const ProtocolVersion = "2024-11-05"
+
+// JSON-RPC error codes
+const (
+	codeParseError     = -32700
+	codeInvalidRequest = -32600
+	codeMethodNotFound = -32601
+	codeInvalidParams  = -32602
+	codeInternalError  = -32603
+)
+
+// Tool is a callable tool. The handler receives the raw arguments object and
+// returns text for the model; a returned error is reported as a tool error.
+type Tool struct {
+	Name        string
+	Description string
+	InputSchema map[string]interface{}
+	Handler     func(args json.RawMessage) (string, error)
+}
+
+// Resource describes a readable resource
+type Resource struct {
+	URI         string `json:"uri"`
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	MimeType    string `json:"mimeType,omitempty"`
+}
+
+// Server speaks MCP over newline-delimited JSON-RPC. Requests are handled
+// one at a time, in order.
+type Server struct {
+	Name    string
+	Version string
+	Tools   []Tool
+
+	// ListResources and ReadResource serve resources; both may be nil
+	ListResources func() ([]Resource, error)
+	ReadResource  func(uri string) (mimeType, text string, err error)
+
+	// BeforeRequest runs before each tool call or resource read, e.g. to
+	// refresh state that may have changed since the last request
+	BeforeRequest func() error
+}
+
+type request struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params,omitempty"`
+}
+
+type response struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Result  interface{}     `json:"result,omitempty"`
+	Error   *rpcError       `json:"error,omitempty"`
+}
+
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+// Serve reads requests from in and writes responses to out until in is closed
+func (s *Server) Serve(in io.Reader, out io.Writer) error {
+	scanner := bufio.NewScanner(in)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	enc := json.NewEncoder(out)
+
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		if len(line) == 0 {
+			continue
+		}
+
+		var req request
+		if err := json.Unmarshal(line, &req); err != nil {
+			if err := enc.Encode(response{JSONRPC: "2.0", ID: json.RawMessage("null"), Error: &rpcError{codeParseError, "parse error"}}); err != nil {
+				return err
+			}
+			continue
+		}
+
+		result, rerr := s.handle(req)
+
+		// Notifications carry no ID and get no response
+		if len(req.ID) == 0 {
+			continue
+		}
+		resp := response{JSONRPC: "2.0", ID: req.ID}
+		if rerr != nil {
+			resp.Error = rerr
+		} else {
+			resp.Result = result
+		}
+		if err := enc.Encode(resp); err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
+
+func (s *Server) handle(req request) (interface{}, *rpcError) {
+	if req.JSONRPC != "2.0" {
+		return nil, &rpcError{codeInvalidRequest, "jsonrpc must be 2.0"}
+	}
+
+	switch req.Method {
+	case "initialize":
+		capabilities := map[string]interface{}{"tools": map[string]interface{}{}}
+		if s.ListResources != nil {
+			capabilities["resources"] = map[string]interface{}{}
+		}
+		return map[string]interface{}{
+			"protocolVersion": ProtocolVersion,
+			"capabilities":    capabilities,
+			"serverInfo":      map[string]string{"name": s.Name, "version": s.Version},
+		}, nil
+
+	case "ping":
+		return map[string]interface{}{}, nil
+
+	case "tools/list":
+		tools := make([]map[string]interface{}, 0, len(s.Tools))
+		for _, t := range s.Tools {
+			tools = append(tools, map[string]interface{}{
+				"name":        t.Name,
+				"description": t.Description,
+				"inputSchema": t.InputSchema,
+			})
+		}
+		return map[string]interface{}{"tools": tools}, nil
+
+	case "tools/call":
+		var params struct {
+			Name      string          `json:"name"`
+			Arguments json.RawMessage `json:"arguments"`
+		}
+		if err := json.Unmarshal(req.Params, &params); err != nil {
+			return nil, &rpcError{codeInvalidParams, "invalid params"}
+		}
+		tool := s.tool(params.Name)
+		if tool == nil {
+			return nil, &rpcError{codeInvalidParams, fmt.Sprintf("unknown tool: %s", params.Name)}
+		}
+		if len(params.Arguments) == 0 {
+			params.Arguments = json.RawMessage("{}")
+		}
+		if err := s.before(); err != nil {
+			return toolResult(err.Error(), true), nil
+		}
+		text, err := tool.Handler(params.Arguments)
+		if err != nil {
+			return toolResult(err.Error(), true), nil
+		}
+		return toolResult(text, false), nil
+
+	case "resources/list":
+		if s.ListResources == nil {
+			return map[string]interface{}{"resources": []Resource{}}, nil
+		}
+		if err := s.before(); err != nil {
+			return nil, &rpcError{codeInternalError, err.Error()}
+		}
+		resources, err := s.ListResources()
+		if err != nil {
+			return nil, &rpcError{codeInternalError, err.Error()}
+		}
+		return map[string]interface{}{"resources": resources}, nil
+
+	case "resources/read":
+		var params struct {
+			URI string `json:"uri"`
+		}
+		if err := json.Unmarshal(req.Params, &params); err != nil || params.URI == "" || s.ReadResource == nil {
+			return nil, &rpcError{codeInvalidParams, "invalid params"}
+		}
+		if err := s.before(); err != nil {
+			return nil, &rpcError{codeInternalError, err.Error()}
+		}
+		mimeType, text, err := s.ReadResource(params.URI)
+		if err != nil {
+			return nil, &rpcError{codeInvalidParams, err.Error()}
+		}
+		return map[string]interface{}{
+			"contents": []map[string]string{{"uri": params.URI, "mimeType": mimeType, "text": text}},
+		}, nil
+
+	case "notifications/initialized", "notifications/cancelled":
+		return nil, nil
+	}
+
+	return nil, &rpcError{codeMethodNotFound, fmt.Sprintf("method not found: %s", req.Method)}
+}
+
+func (s *Server) tool(name string) *Tool {
+	for i := range s.Tools {
+		if s.Tools[i].Name == name {
+			return &s.Tools[i]
+		}
+	}
+	return nil
+}
+
+func (s *Server) before() error {
+	if s.BeforeRequest == nil {
+		return nil
+	}
+	return s.BeforeRequest()
+}
+
+func toolResult(text string, isError bool) map[string]interface{} {
+	return map[string]interface{}{
+		"content": []map[string]string{{"type": "text", "text": text}},
+		"isError": isError,
+	}
+}
@@ -0,0 +1,182 @@
+package mcp
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"testing"
+)
+
+func testServer(refreshes *int) *Server {
+	return &Server{
+		Name:    "keel",
+		Version: "test",
+		Tools: []Tool{
+			{
+				Name:        "echo",
+				Description: "Echo text",
+				InputSchema: map[string]interface{}{"type": "object"},
+				Handler: func(args json.RawMessage) (string, error) {
+					var params struct {
+						Text string `json:"text"`
+					}
+					if err := json.Unmarshal(args, &params); err != nil {
+						return "", err
+					}
+					return params.Text, nil
+				},
+			},
+			{
+				Name:        "fail",
+				Description: "Always fails",
+				InputSchema: map[string]interface{}{"type": "object"},
+				Handler: func(json.RawMessage) (string, error) {
+					return "", errors.New("decision not found: DEC-ffff")
+				},
+			},
+		},
+		ListResources: func() ([]Resource, error) {
+			return []Resource{{URI: "keel://decisions", Name: "Decisions", MimeType: "application/json"}}, nil
+		},
+		ReadResource: func(uri string) (string, string, error) {
+			if uri != "keel://decisions" {
+				return "", "", fmt.Errorf("unknown resource: %s", uri)
+			}
+			return "application/json", "[]", nil
+		},
+		BeforeRequest: func() error {
+			*refreshes++
+			return nil
+		},
+	}
+}
+
+// TestServe drives the server as a stdio client would, one line at a time.
+// Steps without a response are notifications; the next response must still
+// answer the next request.
+func TestServe(t *testing.T) {
+	steps := []struct {
+		name    string
+		request string
+		want    string // expected response, or "" for none
+	}{
+		{
+			name:    "initialize",
+			request: `{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"protocolVersion":"2024-11-05","capabilities":{}}}`,
+			want:    `{"jsonrpc":"2.0","id":1,"result":{"protocolVersion":"2024-11-05","capabilities":{"tools":{},"resources":{}},"serverInfo":{"name":"keel","version":"test"}}}`,
+		},
+		{
+			name:    "initialized notification",
+			request: `{"jsonrpc":"2.0","method":"notifications/initialized"}`,
+		},
+		{
+			name:    "list tools",
+			request: `{"jsonrpc":"2.0","id":2,"method":"tools/list"}`,
+			want: `{"jsonrpc":"2.0","id":2,"result":{"tools":[
+				{"name":"echo","description":"Echo text","inputSchema":{"type":"object"}},
+				{"name":"fail","description":"Always fails","inputSchema":{"type":"object"}}]}}`,
+		},
+		{
+			name:    "call tool",
+			request: `{"jsonrpc":"2.0","id":3,"method":"tools/call","params":{"name":"echo","arguments":{"text":"hello"}}}`,
+			want:    `{"jsonrpc":"2.0","id":3,"result":{"content":[{"type":"text","text":"hello"}],"isError":false}}`,
+		},
+		{
+			name:    "tool error",
+			request: `{"jsonrpc":"2.0","id":4,"method":"tools/call","params":{"name":"fail"}}`,
+			want:    `{"jsonrpc":"2.0","id":4,"result":{"content":[{"type":"text","text":"decision not found: DEC-ffff"}],"isError":true}}`,
+		},
+		{
+			name:    "unknown tool",
+			request: `{"jsonrpc":"2.0","id":5,"method":"tools/call","params":{"name":"nope"}}`,
+			want:    `{"jsonrpc":"2.0","id":5,"error":{"code":-32602,"message":"unknown tool: nope"}}`,
+		},
+		{
+			name:    "list resources",
+			request: `{"jsonrpc":"2.0","id":6,"method":"resources/list"}`,
+			want:    `{"jsonrpc":"2.0","id":6,"result":{"resources":[{"uri":"keel://decisions","name":"Decisions","mimeType":"application/json"}]}}`,
+		},
+		{
+			name:    "read resource",
+			request: `{"jsonrpc":"2.0","id":7,"method":"resources/read","params":{"uri":"keel://decisions"}}`,
+			want:    `{"jsonrpc":"2.0","id":7,"result":{"contents":[{"uri":"keel://decisions","mimeType":"application/json","text":"[]"}]}}`,
+		},
+		{
+			name:    "unknown resource",
+			request: `{"jsonrpc":"2.0","id":8,"method":"resources/read","params":{"uri":"keel://nope"}}`,
+			want:    `{"jsonrpc":"2.0","id":8,"error":{"code":-32602,"message":"unknown resource: keel://nope"}}`,
+		},
+		{
+			name:    "parse error",
+			request: `{"jsonrpc":`,
+			want:    `{"jsonrpc":"2.0","id":null,"error":{"code":-32700,"message":"parse error"}}`,
+		},
+		{
+			name:    "wrong version",
+			request: `{"jsonrpc":"1.0","id":9,"method":"ping"}`,
+			want:    `{"jsonrpc":"2.0","id":9,"error":{"code":-32600,"message":"jsonrpc must be 2.0"}}`,
+		},
+		{
+			name:    "unknown method",
+			request: `{"jsonrpc":"2.0","id":10,"method":"prompts/list"}`,
+			want:    `{"jsonrpc":"2.0","id":10,"error":{"code":-32601,"message":"method not found: prompts/list"}}`,
+		},
+		{
+			name:    "cancelled notification",
+			request: `{"jsonrpc":"2.0","method":"notifications/cancelled","params":{"requestId":10}}`,
+		},
+		{
+			name:    "string ID",
+			request: `{"jsonrpc":"2.0","id":"last","method":"ping"}`,
+			want:    `{"jsonrpc":"2.0","id":"last","result":{}}`,
+		},
+	}
+
+	refreshes := 0
+	clientIn, serverOut := io.Pipe()
+	serverIn, clientOut := io.Pipe()
+	done := make(chan error, 1)
+	go func() {
+		done <- testServer(&refreshes).Serve(serverIn, serverOut)
+		serverOut.Close()
+	}()
+
+	responses := bufio.NewReader(clientIn)
+	for _, step := range steps {
+		if _, err := io.WriteString(clientOut, step.request+"\n"); err != nil {
+			t.Fatalf("%s: %v", step.name, err)
+		}
+		if step.want == "" {
+			continue
+		}
+		line, err := responses.ReadBytes('\n')
+		if err != nil {
+			t.Fatalf("%s: %v", step.name, err)
+		}
+		var got, want interface{}
+		if err := json.Unmarshal(line, &got); err != nil {
+			t.Fatalf("%s: invalid response %s: %v", step.name, line, err)
+		}
+		if err := json.Unmarshal([]byte(step.want), &want); err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: got %s, want %s", step.name, line, step.want)
+		}
+	}
+
+	clientOut.Close()
+	if err := <-done; err != nil {
+		t.Errorf("Serve() = %v", err)
+	}
+	if extra, err := io.ReadAll(responses); err != nil || len(extra) > 0 {
+		t.Errorf("unexpected output after the last response: %q, %v", extra, err)
+	}
+	// Two tool calls, one resource list and two resource reads
+	if refreshes != 5 {
+		t.Errorf("BeforeRequest ran %d times, want 5", refreshes)
+	}
+}
@@ -0,0 +1,118 @@
+package policy
+
+import (
+	"path"
+	"strings"
+
+	"github.com/tyroneavnit/keel/internal/types"
+)
+
+// ApprovalRule requires sign-off for decisions matching its types and paths
+type ApprovalRule struct {
+	Types    []types.DecisionType `yaml:"types,omitempty" json:"types,omitempty"` // empty = all types
+	Paths    []string             `yaml:"paths,omitempty" json:"paths,omitempty"` // empty = all files; glob patterns
+	Roles    []string             `yaml:"roles,omitempty" json:"roles,omitempty"` // empty = agent decisions only
+	Required int                  `yaml:"required" json:"required"`
+}
+
+// Policy holds repository rules for human-gated decisions, set under
+// policy.approvals in .keel/config.yaml
+type Policy struct {
+	Approvals []ApprovalRule
+}
+
+// RequiredApprovals returns how many approvals a decision needs.
+// When several rules match, the strictest one wins.
+func (p *Policy) RequiredApprovals(d *types.Decision) int {
+	// Upstream decisions were approved under their own repository's policy
+	if d.Upstream != "" {
+		return 0
+	}
+	required := 0
+	for _, rule := range p.Approvals {
+		if rule.matches(d) && rule.Required > required {
+			required = rule.Required
+		}
+	}
+	return required
+}
+
+// IsPending reports whether a decision still lacks the approvals it needs
+func (p *Policy) IsPending(d *types.Decision) bool {
+	return len(d.Approvals) < p.RequiredApprovals(d)
+}
+
+// Split separates ratified decisions from those awaiting approval
+func (p *Policy) Split(decisions []*types.Decision) (ratified, pending []*types.Decision) {
+	for _, d := range decisions {
+		if p.IsPending(d) {
+			pending = append(pending, d)
+		} else {
+			ratified = append(ratified, d)
+		}
+	}
+	return ratified, pending
+}
+
+func (r ApprovalRule) matches(d *types.Decision) bool {
+	roles := r.Roles
+	if len(roles) == 0 {
+		roles = []string{"agent"}
+	}
+	if !contains(roles, d.DecidedBy.Role) {
+		return false
+	}
+
+	if len(r.Types) > 0 {
+		typeMatched := false
+		for _, t := range r.Types {
+			if t == d.Type {
+				typeMatched = true
+				break
+			}
+		}
+		if !typeMatched {
+			return false
+		}
+	}
+
+	if len(r.Paths) == 0 {
+		return true
+	}
+	// Anchored files are linked as much as listed ones
+	files := append([]string(nil), d.Files...)
+	for _, a := range d.Anchors {
+		files = append(files, a.File)
+	}
+	for _, pattern := range r.Paths {
+		for _, file := range files {
+			if MatchPath(pattern, file) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// MatchPath reports whether a file path matches a policy pattern.
+// Patterns are path.Match globs; a trailing "/*" or "/**" matches the whole subtree.
+func MatchPath(pattern, file string) bool {
+	if ok, _ := path.Match(pattern, file); ok {
+		return true
+	}
+	for _, suffix := range []string{"/**", "/*"} {
+		if strings.HasSuffix(pattern, suffix) {
+			return strings.HasPrefix(file, strings.TrimSuffix(pattern, suffix)+"/")
+		}
+	}
+	return false
+}
+
+func contains(values []string, v string) bool {
+	for _, s := range values {
+		if s == v {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,109 @@
+package policy
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/tyroneavnit/keel/internal/types"
+)
+
+func TestMatchPath(t *testing.T) {
+	tests := []struct {
+		pattern, file string
+		want          bool
+	}{
+		{"billing/*.go", "billing/invoice.go", true},
+		{"billing/*.go", "billing/tax/rate.go", false},
+		{"billing/*", "billing/tax/rate.go", true},
+		{"billing/**", "billing/tax/rate.go", true},
+		{"billing/**", "billing", false},
+		{"billing/**", "billingx/rate.go", false},
+		{"go.mod", "go.mod", true},
+		{"[", "[", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.pattern+" "+tt.file, func(t *testing.T) {
+			if got := MatchPath(tt.pattern, tt.file); got != tt.want {
+				t.Errorf("MatchPath(%q, %q) = %v, want %v", tt.pattern, tt.file, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRequiredApprovals(t *testing.T) {
+	p := &Policy{Approvals: []ApprovalRule{
+		{Required: 1},
+		{Types: []types.DecisionType{types.TypeConstraint}, Required: 2},
+		{Paths: []string{"billing/**"}, Roles: []string{"agent", "human"}, Required: 3},
+	}}
+	decision := func(role string, typ types.DecisionType, files ...string) *types.Decision {
+		return &types.Decision{Type: typ, Files: files, DecidedBy: types.DecidedBy{Role: role}}
+	}
+
+	tests := []struct {
+		name string
+		d    *types.Decision
+		want int
+	}{
+		{name: "agent decision", d: decision("agent", types.TypeProduct), want: 1},
+		{name: "human decision", d: decision("human", types.TypeConstraint), want: 0},
+		{name: "agent constraint", d: decision("agent", types.TypeConstraint), want: 2},
+		{name: "strictest rule wins", d: decision("agent", types.TypeConstraint, "billing/tax.go"), want: 3},
+		{name: "rule for humans too", d: decision("human", types.TypeProduct, "billing/tax.go"), want: 3},
+		{name: "path outside rule", d: decision("human", types.TypeProduct, "auth/login.go"), want: 0},
+		{
+			name: "anchored path",
+			d: &types.Decision{
+				Type:      types.TypeProduct,
+				Files:     []string{"auth/login.go"},
+				Anchors:   []types.Anchor{{File: "billing/tax.go", StartLine: 3, EndLine: 9}},
+				DecidedBy: types.DecidedBy{Role: "human"},
+			},
+			want: 3,
+		},
+		{
+			name: "upstream decision",
+			d:    &types.Decision{Type: types.TypeConstraint, DecidedBy: types.DecidedBy{Role: "agent"}, Upstream: "platform"},
+			want: 0,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := p.RequiredApprovals(tt.d); got != tt.want {
+				t.Errorf("RequiredApprovals() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSplit(t *testing.T) {
+	p := &Policy{Approvals: []ApprovalRule{{Types: []types.DecisionType{types.TypeConstraint}, Required: 2}}}
+	approved := func(id string, by ...string) *types.Decision {
+		d := &types.Decision{ID: id, Type: types.TypeConstraint, DecidedBy: types.DecidedBy{Role: "agent"}}
+		for _, b := range by {
+			d.Approvals = append(d.Approvals, types.Approval{By: b})
+		}
+		return d
+	}
+
+	ratified, pending := p.Split([]*types.Decision{
+		approved("DEC-0001"),
+		approved("DEC-0002", "ana@example.com"),
+		approved("DEC-0003", "ana@example.com", "bo@example.com"),
+		{ID: "DEC-0004", Type: types.TypeProduct, DecidedBy: types.DecidedBy{Role: "agent"}},
+	})
+	if got := ids(ratified); got != "DEC-0003 DEC-0004" {
+		t.Errorf("ratified = %s, want DEC-0003 DEC-0004", got)
+	}
+	if got := ids(pending); got != "DEC-0001 DEC-0002" {
+		t.Errorf("pending = %s, want DEC-0001 DEC-0002", got)
+	}
+}
+
+func ids(decisions []*types.Decision) string {
+	var list []string
+	for _, d := range decisions {
+		list = append(list, d.ID)
+	}
+	return strings.Join(list, " ")
+}
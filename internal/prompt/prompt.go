@@ -0,0 +1,227 @@
+package prompt
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/tyroneavnit/keel/internal/types"
+)
+
+// CharsPerToken approximates how many characters of English and code make one token
+const CharsPerToken = 4
+
+// Specificity ranks how directly a decision matched the queried location
+type Specificity int
+
+const (
+	MatchGlobal   Specificity = iota // in force everywhere, e.g. an active constraint
+	MatchIndirect                    // matched by annotation, glob or the declaring file of a symbol
+	MatchFile                        // linked to the file or ref
+	MatchSymbol                      // linked to the enclosing symbol
+	MatchLine                        // anchored to a range covering the line
+)
+
+// Target is the location context was requested for
+type Target struct {
+	File   string
+	Line   int
+	Symbol string
+	Ref    string
+}
+
+// Entry is a decision with its ranking inputs
+type Entry struct {
+	Decision    *types.Decision
+	Specificity Specificity
+	Score       float64
+	Files       []string // requested files the decision matched, when several were asked for
+}
+
+// typePriority orders decision types; constraints are hard limits and come first
+var typePriority = map[types.DecisionType]float64{
+	types.TypeConstraint: 3,
+	types.TypeProduct:    2,
+	types.TypeProcess:    1.5,
+	types.TypeLearning:   1,
+}
+
+// Classify works out how specifically a decision matched the target
+func Classify(d *types.Decision, t Target) Specificity {
+	if t.File != "" && t.Line > 0 {
+		for _, a := range d.Anchors {
+			if a.File == t.File && a.StartLine <= t.Line && t.Line <= a.EndLine {
+				return MatchLine
+			}
+		}
+	}
+	if t.Symbol != "" && contains(d.Symbols, t.Symbol) {
+		return MatchSymbol
+	}
+	if t.File != "" && contains(d.Files, t.File) {
+		return MatchFile
+	}
+	if t.Ref != "" && contains(d.Refs, t.Ref) {
+		return MatchFile
+	}
+	for _, a := range d.Anchors {
+		if a.File == t.File {
+			return MatchFile
+		}
+	}
+	return MatchIndirect
+}
+
+// Rank scores matched decisions and constraints and returns them best first.
+// Constraints come before everything else, since they are the rules an agent
+// must stay within; within each group, specificity against the closest
+// target dominates, then type priority, recency and how many links a
+// decision carries. Ties are broken by ID so the order is stable.
+func Rank(decisions, constraints []*types.Decision, targets []Target, now time.Time) []Entry {
+	seen := make(map[string]bool)
+	var entries []Entry
+	add := func(d *types.Decision, spec Specificity) {
+		if seen[d.ID] {
+			return
+		}
+		seen[d.ID] = true
+		entries = append(entries, Entry{Decision: d, Specificity: spec, Score: score(d, spec, now)})
+	}
+	for _, d := range decisions {
+		spec := MatchIndirect
+		for _, t := range targets {
+			if s := Classify(d, t); s > spec {
+				spec = s
+			}
+		}
+		add(d, spec)
+	}
+	for _, c := range constraints {
+		add(c, MatchGlobal)
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		if ci, cj := isConstraint(entries[i]), isConstraint(entries[j]); ci != cj {
+			return ci
+		}
+		if entries[i].Score != entries[j].Score {
+			return entries[i].Score > entries[j].Score
+		}
+		return entries[i].Decision.ID < entries[j].Decision.ID
+	})
+	return entries
+}
+
+func score(d *types.Decision, spec Specificity, now time.Time) float64 {
+	priority, ok := typePriority[d.Type]
+	if !ok {
+		priority = 1.5
+	}
+
+	// Recency decays over roughly a quarter
+	recency := 0.0
+	if created, err := time.Parse(time.RFC3339Nano, d.CreatedAt); err == nil {
+		ageDays := now.Sub(created).Hours() / 24
+		recency = 1 / (1 + math.Max(ageDays, 0)/90)
+	}
+
+	links := len(d.Files) + len(d.Symbols) + len(d.Refs) + len(d.Anchors)
+	linkScore := math.Log1p(float64(links))
+
+	return float64(spec)*10 + priority*5 + recency*3 + linkScore
+}
+
+// Render prints ranked entries as a compact Markdown block. Entries are
+// written in full while they fit the token budget, then as one-line
+// summaries; whatever is left is counted in a closing note. Constraints are
+// never left out: they are summarized even when that overruns the budget. A
+// budget of 0 means no limit.
+func Render(title string, entries []Entry, budget int) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "## Keel decisions: %s\n\n", oneLine(title))
+	if len(entries) == 0 {
+		b.WriteString("No recorded decisions apply.\n")
+		return b.String()
+	}
+
+	limit := budget * CharsPerToken
+	// Room for the omission note, so adding it never breaks the budget
+	reserve := len("\n_99 more decisions omitted to fit the token budget._\n")
+
+	full, room := true, true
+	written := 0
+	for _, e := range entries {
+		if !room && !isConstraint(e) {
+			continue
+		}
+		var entry string
+		if full {
+			entry = fullEntry(e)
+			if limit > 0 && b.Len()+len(entry)+reserve > limit {
+				full = false
+			}
+		}
+		if !full {
+			entry = summaryEntry(e.Decision)
+			if limit > 0 && b.Len()+len(entry)+reserve > limit {
+				room = false
+				if !isConstraint(e) {
+					continue
+				}
+			}
+		}
+		b.WriteString(entry)
+		written++
+	}
+
+	if omitted := len(entries) - written; omitted == 1 {
+		b.WriteString("\n_1 more decision omitted to fit the token budget._\n")
+	} else if omitted > 1 {
+		fmt.Fprintf(&b, "\n_%d more decisions omitted to fit the token budget._\n", omitted)
+	}
+	return b.String()
+}
+
+func fullEntry(e Entry) string {
+	d := e.Decision
+	var b strings.Builder
+	fmt.Fprintf(&b, "- **%s** (%s) %s\n", d.ID, d.Type, oneLine(d.Choice))
+	if len(e.Files) > 0 {
+		fmt.Fprintf(&b, "  - Files: %s\n", strings.Join(e.Files, ", "))
+	}
+	fmt.Fprintf(&b, "  - Problem: %s\n", oneLine(d.Problem))
+	if d.Rationale != nil && *d.Rationale != "" {
+		fmt.Fprintf(&b, "  - Why: %s\n", oneLine(*d.Rationale))
+	}
+	if len(d.Tradeoffs) > 0 {
+		fmt.Fprintf(&b, "  - Tradeoffs: %s\n", oneLine(strings.Join(d.Tradeoffs, "; ")))
+	}
+	if d.ExpiresAt != nil {
+		fmt.Fprintf(&b, "  - Expires: %s\n", *d.ExpiresAt)
+	}
+	return b.String()
+}
+
+func summaryEntry(d *types.Decision) string {
+	return fmt.Sprintf("- %s (%s) %s\n", d.ID, d.Type, oneLine(d.Choice))
+}
+
+func isConstraint(e Entry) bool {
+	return e.Decision.Type == types.TypeConstraint
+}
+
+// oneLine collapses whitespace so multi-line fields stay on one Markdown line
+func oneLine(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
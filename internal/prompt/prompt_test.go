@@ -0,0 +1,177 @@
+package prompt
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/tyroneavnit/keel/internal/types"
+)
+
+// testEntries returns n entries of equal length, in full and as summaries
+func testEntries(n int) []Entry {
+	rationale := "Clients already retry, so the server must not"
+	entries := make([]Entry, n)
+	for i := range entries {
+		entries[i] = Entry{Decision: &types.Decision{
+			ID:        fmt.Sprintf("DEC-%04x", i),
+			Type:      types.TypeProduct,
+			Problem:   "Where retries\nlive",
+			Choice:    fmt.Sprintf("Choice %d", i),
+			Rationale: &rationale,
+		}}
+	}
+	return entries
+}
+
+func TestRender(t *testing.T) {
+	entries := testEntries(5)
+	header := len("## Keel decisions: billing.go\n\n")
+	full := len(fullEntry(entries[0]))
+	summary := len(summaryEntry(entries[0].Decision))
+	reserve := len("\n_99 more decisions omitted to fit the token budget._\n")
+	// tokens returns the smallest budget that fits the given characters
+	tokens := func(chars int) int { return (chars + CharsPerToken - 1) / CharsPerToken }
+
+	tests := []struct {
+		name                   string
+		budget                 int
+		full, summary, omitted int
+	}{
+		{name: "no limit", budget: 0, full: 5},
+		{name: "everything fits", budget: tokens(header + 5*full + reserve), full: 5},
+		{name: "two in full", budget: tokens(header + 2*full + 3*summary + reserve), full: 2, summary: 3},
+		{name: "summaries only", budget: tokens(header + 2*summary + reserve), summary: 2, omitted: 3},
+		{name: "one omitted", budget: tokens(header + full + 3*summary + reserve), full: 1, summary: 3, omitted: 1},
+		{name: "nothing fits", budget: tokens(header + reserve), omitted: 5},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out := Render("billing.go", entries, tt.budget)
+
+			if tt.budget > 0 && len(out) > tt.budget*CharsPerToken {
+				t.Errorf("output is %d chars, over the budget of %d", len(out), tt.budget*CharsPerToken)
+			}
+			if got := strings.Count(out, "  - Problem: Where retries live\n"); got != tt.full {
+				t.Errorf("%d full entries, want %d:\n%s", got, tt.full, out)
+			}
+			if got := strings.Count(out, "\n- DEC-"); got != tt.summary {
+				t.Errorf("%d summaries, want %d:\n%s", got, tt.summary, out)
+			}
+
+			var note string
+			switch tt.omitted {
+			case 0:
+			case 1:
+				note = "_1 more decision omitted to fit the token budget._"
+			default:
+				note = fmt.Sprintf("_%d more decisions omitted to fit the token budget._", tt.omitted)
+			}
+			if note != "" && !strings.HasSuffix(out, "\n"+note+"\n") {
+				t.Errorf("output does not end with %q:\n%s", note, out)
+			}
+			if note == "" && strings.Contains(out, "omitted") {
+				t.Errorf("unexpected omission note:\n%s", out)
+			}
+
+			// Entries keep their ranked order whether full or summarized
+			last := -1
+			for i := range entries {
+				at := strings.Index(out, entries[i].Decision.ID)
+				if at < 0 {
+					continue
+				}
+				if at < last {
+					t.Errorf("%s is out of order:\n%s", entries[i].Decision.ID, out)
+				}
+				last = at
+			}
+		})
+	}
+}
+
+func TestRank(t *testing.T) {
+	now := time.Date(2026, 6, 1, 0, 0, 0, 0, time.UTC)
+	decision := func(id string, typ types.DecisionType, modify func(d *types.Decision)) *types.Decision {
+		d := &types.Decision{ID: id, Type: typ, CreatedAt: "2026-05-01T00:00:00Z"}
+		if modify != nil {
+			modify(d)
+		}
+		return d
+	}
+
+	anchored := decision("DEC-0001", types.TypeLearning, func(d *types.Decision) {
+		d.Anchors = []types.Anchor{{File: "billing.go", StartLine: 10, EndLine: 20}}
+	})
+	symbol := decision("DEC-0002", types.TypeLearning, func(d *types.Decision) { d.Symbols = []string{"billing.Total"} })
+	file := decision("DEC-0003", types.TypeProduct, func(d *types.Decision) { d.Files = []string{"billing.go"} })
+	fileConstraint := decision("DEC-0004", types.TypeConstraint, func(d *types.Decision) { d.Files = []string{"billing.go"} })
+	indirect := decision("DEC-0005", types.TypeConstraint, nil)
+	global := decision("DEC-0006", types.TypeConstraint, nil)
+
+	target := Target{File: "billing.go", Line: 12, Symbol: "billing.Total"}
+	got := Rank([]*types.Decision{indirect, file, symbol, fileConstraint, anchored}, []*types.Decision{global, file}, []Target{target}, now)
+
+	want := []struct {
+		id   string
+		spec Specificity
+	}{
+		{"DEC-0004", MatchFile},
+		{"DEC-0005", MatchIndirect},
+		{"DEC-0006", MatchGlobal},
+		{"DEC-0001", MatchLine},
+		{"DEC-0002", MatchSymbol},
+		{"DEC-0003", MatchFile},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("Rank() returned %d entries, want %d", len(got), len(want))
+	}
+	for i, w := range want {
+		if got[i].Decision.ID != w.id || got[i].Specificity != w.spec {
+			t.Errorf("entry %d = %s (%d), want %s (%d)", i, got[i].Decision.ID, got[i].Specificity, w.id, w.spec)
+		}
+	}
+}
+
+func TestRenderKeepsConstraints(t *testing.T) {
+	now := time.Date(2026, 6, 1, 0, 0, 0, 0, time.UTC)
+	decisions := make([]*types.Decision, 4)
+	for i := range decisions {
+		decisions[i] = &types.Decision{
+			ID:      fmt.Sprintf("DEC-%04x", i+1),
+			Type:    types.TypeProduct,
+			Problem: "Where retries live",
+			Choice:  fmt.Sprintf("Choice %d", i+1),
+			Files:   []string{"billing.go"},
+		}
+	}
+	constraint := &types.Decision{ID: "DEC-00ff", Type: types.TypeConstraint, Problem: "Money", Choice: "Store amounts as integer cents"}
+	entries := Rank(decisions, []*types.Decision{constraint}, []Target{{File: "billing.go"}}, now)
+
+	header := len("## Keel decisions: billing.go\n\n")
+	reserve := len("\n_99 more decisions omitted to fit the token budget._\n")
+	summary := len(summaryEntry(constraint))
+
+	tests := []struct {
+		name    string
+		budget  int
+		omitted int
+	}{
+		{name: "room for one summary", budget: (header + summary + reserve) / CharsPerToken, omitted: 4},
+		{name: "no room at all", budget: 1, omitted: 4},
+		{name: "room for two entries", budget: (header + 2*summary + reserve + CharsPerToken) / CharsPerToken, omitted: 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out := Render("billing.go", entries, tt.budget)
+			if !strings.Contains(out, "DEC-00ff") {
+				t.Errorf("constraint was trimmed:\n%s", out)
+			}
+			note := fmt.Sprintf("_%d more decisions omitted to fit the token budget._", tt.omitted)
+			if !strings.HasSuffix(out, "\n"+note+"\n") {
+				t.Errorf("output does not end with %q:\n%s", note, out)
+			}
+		})
+	}
+}
@@ -1,10 +1,15 @@
 package query
 
 import (
+	"context"
+	"database/sql"
 	"encoding/json"
 	"fmt"
 	"strings"
+	"time"
 
+	"github.com/ncruces/go-sqlite3"
+	"github.com/ncruces/go-sqlite3/driver"
 	"github.com/tyroneavnit/keel/internal/index"
 	"github.com/tyroneavnit/keel/internal/types"
 )
@@ -13,6 +18,9 @@ import (
 type Options struct {
 	Type   string
 	Status string
+	Author string
+	Ledger string // repo-relative ledger directory
+	Local  bool   // leave out read-only upstream decisions
 	Limit  int
 }
 
@@ -40,7 +48,8 @@ func ByID(db *index.DB, id string) (*types.Decision, error) {
 	return rowToDecision(rawJSON)
 }
 
-// ByFile queries decisions affecting a file path
+// ByFile queries decisions affecting a file path, whether linked explicitly
+// or by a keel: annotation in the file
 func ByFile(db *index.DB, filePath string) ([]*types.Decision, error) {
 	// Support glob patterns with LIKE
 	pattern := filePath
@@ -50,11 +59,14 @@ func ByFile(db *index.DB, filePath string) ([]*types.Decision, error) {
 
 	rows, err := db.Query(`
 		SELECT d.raw_json FROM decisions d
-		INNER JOIN decision_files df ON d.id = df.decision_id
-		WHERE df.file_path LIKE ?
+		WHERE d.id IN (
+			SELECT decision_id FROM decision_files WHERE file_path LIKE ?
+			UNION
+			SELECT decision_id FROM decision_annotations WHERE file_path LIKE ?
+		)
 		AND d.status = 'active'
 		ORDER BY d.created_at DESC
-	`, pattern)
+	`, pattern, pattern)
 	if err != nil {
 		return nil, err
 	}
@@ -102,6 +114,65 @@ func BySymbol(db *index.DB, symbol string) ([]*types.Decision, error) {
 	return decisions, nil
 }
 
+// ByLine queries decisions anchored to a line range covering the given line,
+// narrowest range first
+func ByLine(db *index.DB, filePath string, line int) ([]*types.Decision, error) {
+	rows, err := db.Query(`
+		SELECT d.raw_json FROM decisions d
+		INNER JOIN decision_anchors da ON d.id = da.decision_id
+		WHERE da.file_path = ?
+		AND da.start_line <= ? AND da.end_line >= ?
+		AND d.status = 'active'
+		GROUP BY d.id
+		ORDER BY MIN(da.end_line - da.start_line) ASC, d.created_at DESC
+	`, filePath, line, line)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var decisions []*types.Decision
+	for rows.Next() {
+		var rawJSON string
+		if err := rows.Scan(&rawJSON); err != nil {
+			continue
+		}
+		if d, err := rowToDecision(rawJSON); err == nil {
+			decisions = append(decisions, d)
+		}
+	}
+
+	return decisions, nil
+}
+
+// Annotations returns the keel: markers recorded by the last scan,
+// optionally limited to one file
+func Annotations(db *index.DB, filePath string) ([]types.Annotation, error) {
+	sql := "SELECT decision_id, file_path, line FROM decision_annotations"
+	var args []interface{}
+	if filePath != "" {
+		sql += " WHERE file_path = ?"
+		args = append(args, filePath)
+	}
+	sql += " ORDER BY file_path, line"
+
+	rows, err := db.Query(sql, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var annotations []types.Annotation
+	for rows.Next() {
+		var a types.Annotation
+		if err := rows.Scan(&a.DecisionID, &a.File, &a.Line); err != nil {
+			continue
+		}
+		annotations = append(annotations, a)
+	}
+	return annotations, nil
+}
+
 // ByRef queries decisions linked to a reference ID
 func ByRef(db *index.DB, refID string) ([]*types.Decision, error) {
 	rows, err := db.Query(`
@@ -130,6 +201,38 @@ func ByRef(db *index.DB, refID string) ([]*types.Decision, error) {
 	return decisions, nil
 }
 
+// HeadsByRef queries the decisions in force for a ref: those linked to it,
+// with superseded ones replaced by the heads of their chains
+func HeadsByRef(db *index.DB, refID string) ([]*types.Decision, error) {
+	rows, err := db.Query(`
+		SELECT d.raw_json FROM decisions d
+		WHERE d.status = 'active'
+		AND d.id IN (
+			SELECT dh.head_id FROM decision_heads dh
+			INNER JOIN decision_refs dr ON dh.id = dr.decision_id
+			WHERE dr.ref_id = ?
+		)
+		ORDER BY d.created_at DESC
+	`, refID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var decisions []*types.Decision
+	for rows.Next() {
+		var rawJSON string
+		if err := rows.Scan(&rawJSON); err != nil {
+			continue
+		}
+		if d, err := rowToDecision(rawJSON); err == nil {
+			decisions = append(decisions, d)
+		}
+	}
+
+	return decisions, nil
+}
+
 // All queries all decisions with optional filters
 func All(db *index.DB, opts Options) ([]*types.Decision, error) {
 	sql := "SELECT raw_json FROM decisions WHERE 1=1"
@@ -145,6 +248,20 @@ func All(db *index.DB, opts Options) ([]*types.Decision, error) {
 		args = append(args, opts.Status)
 	}
 
+	if opts.Author != "" {
+		sql += " AND decided_by_identifier = ?"
+		args = append(args, opts.Author)
+	}
+
+	if opts.Ledger != "" {
+		sql += " AND ledger = ?"
+		args = append(args, opts.Ledger)
+	}
+
+	if opts.Local {
+		sql += " AND upstream IS NULL"
+	}
+
 	sql += " ORDER BY created_at DESC"
 
 	if opts.Limit > 0 {
@@ -171,14 +288,171 @@ func All(db *index.DB, opts Options) ([]*types.Decision, error) {
 	return decisions, nil
 }
 
+// Search runs a full-text search over problem, choice and rationale.
+// Every word must match as a prefix (retry matches retries); results are
+// ordered by relevance.
+func Search(db *index.DB, text string, opts Options) ([]*types.Decision, error) {
+	var terms []string
+	for _, word := range strings.Fields(text) {
+		terms = append(terms, `"`+strings.ReplaceAll(word, `"`, `""`)+`"*`)
+	}
+	if len(terms) == 0 {
+		return nil, fmt.Errorf("search text is empty")
+	}
+
+	sql := `SELECT d.raw_json FROM decisions_fts f
+		INNER JOIN decisions d ON d.rowid = f.rowid
+		WHERE decisions_fts MATCH ?`
+	args := []interface{}{strings.Join(terms, " ")}
+
+	if opts.Type != "" {
+		sql += " AND d.type = ?"
+		args = append(args, opts.Type)
+	}
+	if opts.Status != "" {
+		sql += " AND d.status = ?"
+		args = append(args, opts.Status)
+	}
+	if opts.Author != "" {
+		sql += " AND d.decided_by_identifier = ?"
+		args = append(args, opts.Author)
+	}
+	if opts.Ledger != "" {
+		sql += " AND d.ledger = ?"
+		args = append(args, opts.Ledger)
+	}
+	if opts.Local {
+		sql += " AND d.upstream IS NULL"
+	}
+
+	sql += " ORDER BY rank"
+	if opts.Limit > 0 {
+		sql += fmt.Sprintf(" LIMIT %d", opts.Limit)
+	}
+
+	rows, err := db.Query(sql, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var decisions []*types.Decision
+	for rows.Next() {
+		var rawJSON string
+		if err := rows.Scan(&rawJSON); err != nil {
+			continue
+		}
+		if d, err := rowToDecision(rawJSON); err == nil {
+			decisions = append(decisions, d)
+		}
+	}
+
+	return decisions, nil
+}
+
+// Raw runs a read-only SQL statement and returns its columns and rows.
+// Byte values are converted to strings for readability.
+func Raw(db *index.DB, statement string) ([]string, []map[string]interface{}, error) {
+	ctx := context.Background()
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer conn.Close()
+
+	// A read-only transaction sets query_only, so SQLite refuses any
+	// statement that writes however it is phrased, and VACUUM. ATTACH and
+	// PRAGMA change the connection rather than the data, so an authorizer
+	// refuses those until the transaction ends.
+	tx, err := conn.BeginTx(ctx, &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		return nil, nil, err
+	}
+	defer tx.Rollback()
+	if err := setAuthorizer(conn, readOnlyAuthorizer); err != nil {
+		return nil, nil, err
+	}
+	defer setAuthorizer(conn, nil)
+
+	rows, err := tx.Query(statement)
+	if err != nil {
+		return nil, nil, fmt.Errorf("query failed: %w", err)
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get columns: %w", err)
+	}
+
+	var results []map[string]interface{}
+	for rows.Next() {
+		values := make([]interface{}, len(columns))
+		valuePtrs := make([]interface{}, len(columns))
+		for i := range values {
+			valuePtrs[i] = &values[i]
+		}
+
+		if err := rows.Scan(valuePtrs...); err != nil {
+			return nil, nil, fmt.Errorf("failed to scan row: %w", err)
+		}
+
+		row := make(map[string]interface{})
+		for i, col := range columns {
+			if b, ok := values[i].([]byte); ok {
+				row[col] = string(b)
+			} else {
+				row[col] = values[i]
+			}
+		}
+		results = append(results, row)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, nil, fmt.Errorf("error iterating rows: %w", err)
+	}
 
-// ActiveConstraints returns all active constraint decisions
+	return columns, results, nil
+}
+
+// ActiveConstraints returns all active constraint decisions.
+// Constraints past their expiry date are no longer in force and are left out.
 func ActiveConstraints(db *index.DB) ([]*types.Decision, error) {
 	rows, err := db.Query(`
 		SELECT raw_json FROM decisions
 		WHERE type = 'constraint' AND status = 'active'
+		AND (expires_at IS NULL OR expires_at > ?)
 		ORDER BY created_at DESC
-	`)
+	`, types.FormatDate(time.Now()))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var decisions []*types.Decision
+	for rows.Next() {
+		var rawJSON string
+		if err := rows.Scan(&rawJSON); err != nil {
+			continue
+		}
+		if d, err := rowToDecision(rawJSON); err == nil {
+			decisions = append(decisions, d)
+		}
+	}
+
+	return decisions, nil
+}
+
+// Due returns active decisions whose review or expiry date is on or before
+// the given time. Upstream decisions are reviewed in their own repository.
+func Due(db *index.DB, at time.Time) ([]*types.Decision, error) {
+	cutoff := types.FormatDate(at)
+	rows, err := db.Query(`
+		SELECT raw_json FROM decisions
+		WHERE status = 'active' AND upstream IS NULL
+		AND ((review_by IS NOT NULL AND review_by <= ?) OR (expires_at IS NOT NULL AND expires_at <= ?))
+		ORDER BY COALESCE(MIN(review_by, expires_at), review_by, expires_at) ASC
+	`, cutoff, cutoff)
 	if err != nil {
 		return nil, err
 	}
@@ -216,6 +490,100 @@ func ForContext(db *index.DB, path string) (*ContextResult, error) {
 	}, nil
 }
 
+// ForLine returns decisions for a line of a file: decisions anchored to a
+// range covering the line first, then the rest of the file's decisions,
+// plus active constraints
+func ForLine(db *index.DB, path string, line int) (*ContextResult, error) {
+	decisions, err := ByLine(db, path, line)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := ForContext(db, path)
+	if err != nil {
+		return nil, err
+	}
+	result.Decisions = appendUnique(decisions, result.Decisions...)
+	return result, nil
+}
+
+// ForSymbol returns decisions linked to a symbol or to the file that
+// declares it, with symbol links first, plus active constraints
+func ForSymbol(db *index.DB, symbol, file string) (*ContextResult, error) {
+	var decisions []*types.Decision
+	if symbol != "" {
+		var err error
+		decisions, err = BySymbol(db, symbol)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if file != "" {
+		fileDecisions, err := ByFile(db, file)
+		if err != nil {
+			return nil, err
+		}
+		decisions = appendUnique(decisions, fileDecisions...)
+	}
+
+	constraints, err := ActiveConstraints(db)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ContextResult{
+		Decisions:   decisions,
+		Constraints: constraints,
+	}, nil
+}
+
+// ForSymbolAt returns decisions for a line of a file: decisions anchored to
+// a range covering the line, then those linked to the enclosing symbol, then
+// the rest of the file's decisions, plus active constraints
+func ForSymbolAt(db *index.DB, symbol, file string, line int) (*ContextResult, error) {
+	decisions, err := ByLine(db, file, line)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := ForSymbol(db, symbol, file)
+	if err != nil {
+		return nil, err
+	}
+	result.Decisions = appendUnique(decisions, result.Decisions...)
+	return result, nil
+}
+
+// appendUnique appends decisions whose IDs are not already present
+func appendUnique(decisions []*types.Decision, more ...*types.Decision) []*types.Decision {
+	seen := make(map[string]bool, len(decisions))
+	for _, d := range decisions {
+		seen[d.ID] = true
+	}
+	for _, d := range more {
+		if !seen[d.ID] {
+			seen[d.ID] = true
+			decisions = append(decisions, d)
+		}
+	}
+	return decisions
+}
+
+// FilterByAuthor keeps only decisions made by the given identifier
+func FilterByAuthor(decisions []*types.Decision, author string) []*types.Decision {
+	if author == "" {
+		return decisions
+	}
+	var filtered []*types.Decision
+	for _, d := range decisions {
+		if d.DecidedBy.Identifier != nil && *d.DecidedBy.Identifier == author {
+			filtered = append(filtered, d)
+		}
+	}
+	return filtered
+}
+
 // RefLink represents a decision-to-ref relationship
 type RefLink struct {
 	DecisionID string
@@ -265,3 +633,19 @@ func AllFileLinks(db *index.DB) ([]FileLink, error) {
 	}
 	return links, nil
 }
+
+// readOnlyAuthorizer refuses statements that attach databases or set pragmas
+func readOnlyAuthorizer(action sqlite3.AuthorizerActionCode, _, _, _, _ string) sqlite3.AuthorizerReturnCode {
+	switch action {
+	case sqlite3.AUTH_ATTACH, sqlite3.AUTH_DETACH, sqlite3.AUTH_PRAGMA:
+		return sqlite3.AUTH_DENY
+	}
+	return sqlite3.AUTH_OK
+}
+
+// setAuthorizer installs an authorizer on one connection, or removes it
+func setAuthorizer(conn *sql.Conn, cb func(sqlite3.AuthorizerActionCode, string, string, string, string) sqlite3.AuthorizerReturnCode) error {
+	return conn.Raw(func(c interface{}) error {
+		return c.(driver.Conn).Raw().SetAuthorizer(cb)
+	})
+}
@@ -0,0 +1,61 @@
+package query
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/tyroneavnit/keel/internal/index"
+	"github.com/tyroneavnit/keel/internal/store"
+)
+
+func TestRawIsReadOnly(t *testing.T) {
+	root := t.TempDir()
+	if err := store.EnsureKeelDir(root); err != nil {
+		t.Fatal(err)
+	}
+	line := `{"id":"DEC-0001","created_at":"2026-01-01T00:00:00Z","type":"product","problem":"p","choice":"c","decided_by":{"role":"human"},"status":"active"}` + "\n"
+	if err := os.WriteFile(store.GetDecisionsPath(root), []byte(line), 0644); err != nil {
+		t.Fatal(err)
+	}
+	db, err := index.Open(root, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	copied := filepath.Join(t.TempDir(), "copy.sqlite")
+
+	tests := []struct {
+		name      string
+		statement string
+		wantErr   bool
+	}{
+		{name: "select", statement: "SELECT id FROM decisions"},
+		{name: "select in a CTE", statement: "WITH d AS (SELECT id FROM decisions) SELECT count(*) FROM d"},
+		{name: "delete", statement: "DELETE FROM decisions", wantErr: true},
+		{name: "delete behind a CTE", statement: "WITH x AS (SELECT 1) DELETE FROM decisions WHERE id IN (SELECT id FROM decisions)", wantErr: true},
+		{name: "vacuum into a file", statement: "VACUUM INTO '" + copied + "'", wantErr: true},
+		{name: "attach", statement: "ATTACH DATABASE '" + copied + "' AS other", wantErr: true},
+		{name: "pragma", statement: "PRAGMA query_only = OFF", wantErr: true},
+		{name: "insert", statement: "  insert into decisions (id) values ('DEC-0002')", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, err := Raw(db, tt.statement)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Raw(%q) err = %v, want error: %v", tt.statement, err, tt.wantErr)
+			}
+		})
+	}
+
+	if _, err := os.Stat(copied); !os.IsNotExist(err) {
+		t.Errorf("a statement wrote %s", copied)
+	}
+	_, rows, err := Raw(db, "SELECT count(*) AS n FROM decisions")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := rows[0]["n"]; n != int64(1) {
+		t.Errorf("decisions = %v after the refused writes, want 1", n)
+	}
+}
@@ -0,0 +1,221 @@
+package scan
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/tyroneavnit/keel/internal/id"
+	"github.com/tyroneavnit/keel/internal/types"
+)
+
+// MaxFileSize skips files too large to be hand-written source
+const MaxFileSize = 1 << 20
+
+// markerPattern matches keel:DEC-xxxx; the ID is normalized so configured prefixes work too
+var markerPattern = regexp.MustCompile(`keel:([A-Za-z][A-Za-z0-9]*-[0-9a-fA-F]{4,8})\b`)
+
+// commentStarts are the comment openers a marker must follow on its line
+var commentStarts = []string{"//", "/*", "*", "#", "--", ";", "<!--"}
+
+// Result holds the markers found in the repository
+type Result struct {
+	Files       int                // files scanned
+	Annotations []types.Annotation // markers in file and line order
+}
+
+// Repo finds keel: markers in every file of the repository that git would
+// track, so .gitignore is respected. Outside a git work tree the root
+// .gitignore is applied to a directory walk instead. IDs are normalized
+// to the repository's ID format.
+func Repo(repoRoot string, format id.Format) (*Result, error) {
+	files, err := gitFiles(repoRoot)
+	if err != nil {
+		files, err = walkFiles(repoRoot)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list files: %w", err)
+		}
+	}
+
+	result := &Result{}
+	for _, rel := range files {
+		annotations, ok, err := File(repoRoot, rel, format)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			result.Files++
+			result.Annotations = append(result.Annotations, annotations...)
+		}
+	}
+	return result, nil
+}
+
+// File returns the markers in one repo-relative file. ok is false when the
+// file was skipped as missing, binary or too large.
+func File(repoRoot, rel string, format id.Format) (annotations []types.Annotation, ok bool, err error) {
+	full := filepath.Join(repoRoot, filepath.FromSlash(rel))
+	info, err := os.Stat(full)
+	if err != nil || !info.Mode().IsRegular() || info.Size() > MaxFileSize {
+		return nil, false, nil
+	}
+
+	data, err := os.ReadFile(full)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to read %s: %w", rel, err)
+	}
+	if bytes.IndexByte(data[:min(len(data), 8000)], 0) >= 0 {
+		return nil, false, nil
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 0, 64*1024), MaxFileSize)
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := scanner.Text()
+		if !strings.Contains(text, "keel:") {
+			continue
+		}
+		for _, m := range markerPattern.FindAllStringSubmatchIndex(text, -1) {
+			if !inComment(text[:m[0]]) {
+				continue
+			}
+			decisionID, err := format.Normalize(text[m[2]:m[3]])
+			if err != nil {
+				continue
+			}
+			annotations = append(annotations, types.Annotation{
+				DecisionID: decisionID,
+				File:       rel,
+				Line:       line,
+			})
+		}
+	}
+	return annotations, true, nil
+}
+
+// inComment reports whether the text before a marker opens a comment
+func inComment(before string) bool {
+	for _, start := range commentStarts {
+		if strings.Contains(before, start) {
+			return true
+		}
+	}
+	return false
+}
+
+// gitFiles lists tracked and untracked-but-not-ignored files
+func gitFiles(repoRoot string) ([]string, error) {
+	cmd := exec.Command("git", "ls-files", "-z", "--cached", "--others", "--exclude-standard")
+	cmd.Dir = repoRoot
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, err
+	}
+
+	var files []string
+	seen := make(map[string]bool)
+	for _, rel := range strings.Split(string(out), "\x00") {
+		if rel == "" || seen[rel] || strings.HasPrefix(rel, ".keel/") {
+			continue
+		}
+		seen[rel] = true
+		files = append(files, rel)
+	}
+	return files, nil
+}
+
+// walkFiles lists files under repoRoot, skipping hidden directories and
+// paths matched by the root .gitignore
+func walkFiles(repoRoot string) ([]string, error) {
+	ignore := loadIgnore(repoRoot)
+
+	var files []string
+	err := filepath.WalkDir(repoRoot, func(p string, entry os.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if p == repoRoot {
+			return nil
+		}
+		rel, err := filepath.Rel(repoRoot, p)
+		if err != nil {
+			return nil
+		}
+		rel = filepath.ToSlash(rel)
+		if entry.IsDir() {
+			if strings.HasPrefix(entry.Name(), ".") || ignore.matches(rel, true) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !ignore.matches(rel, false) {
+			files = append(files, rel)
+		}
+		return nil
+	})
+	return files, err
+}
+
+// ignoreRule is one .gitignore pattern
+type ignoreRule struct {
+	pattern  string
+	anchored bool // pattern contains a slash, so it matches from the root
+	dirOnly  bool
+	negate   bool
+}
+
+type ignoreRules []ignoreRule
+
+func loadIgnore(repoRoot string) ignoreRules {
+	data, err := os.ReadFile(filepath.Join(repoRoot, ".gitignore"))
+	if err != nil {
+		return nil
+	}
+
+	var rules ignoreRules
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		var rule ignoreRule
+		if strings.HasPrefix(line, "!") {
+			rule.negate = true
+			line = line[1:]
+		}
+		if strings.HasSuffix(line, "/") {
+			rule.dirOnly = true
+			line = strings.TrimSuffix(line, "/")
+		}
+		rule.anchored = strings.Contains(line, "/")
+		rule.pattern = strings.TrimPrefix(line, "/")
+		rules = append(rules, rule)
+	}
+	return rules
+}
+
+// matches applies the rules in order; the last matching rule decides
+func (rules ignoreRules) matches(rel string, isDir bool) bool {
+	ignored := false
+	for _, rule := range rules {
+		if rule.dirOnly && !isDir {
+			continue
+		}
+		target := path.Base(rel)
+		if rule.anchored {
+			target = rel
+		}
+		if ok, _ := path.Match(rule.pattern, target); ok {
+			ignored = !rule.negate
+		}
+	}
+	return ignored
+}
@@ -0,0 +1,139 @@
+package scan
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/tyroneavnit/keel/internal/id"
+	"github.com/tyroneavnit/keel/internal/types"
+)
+
+func writeFiles(t *testing.T, root string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		path := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestFile(t *testing.T) {
+	adr, err := id.NewFormat("ADR", 6)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		content string
+		format  id.Format
+		want    []int    // lines with markers
+		wantIDs []string // IDs, when they need checking
+		skipped bool
+	}{
+		{
+			name:    "comment styles",
+			content: "// keel:DEC-a1b2\n/* keel:DEC-a1b2 */\n * keel:DEC-a1b2\n# keel:DEC-a1b2\n-- keel:DEC-a1b2\n; keel:DEC-a1b2\n<!-- keel:DEC-a1b2 -->\n",
+			format:  id.DefaultFormat,
+			want:    []int{1, 2, 3, 4, 5, 6, 7},
+		},
+		{
+			name:    "trailing comment and several markers",
+			content: "retry(3) // keel:DEC-a1b2 keel:DEC-c3d4\n",
+			format:  id.DefaultFormat,
+			want:    []int{1, 1},
+			wantIDs: []string{"DEC-a1b2", "DEC-c3d4"},
+		},
+		{
+			name:    "outside a comment",
+			content: "label := \"keel:DEC-a1b2\"\n",
+			format:  id.DefaultFormat,
+		},
+		{
+			name:    "normalized",
+			content: "// keel:dec-A1B2\n",
+			format:  id.DefaultFormat,
+			want:    []int{1},
+			wantIDs: []string{"DEC-a1b2"},
+		},
+		{
+			name:    "configured prefix",
+			content: "// keel:ADR-00ff11\n// keel:DEC-a1b2\n// keel:RFC-a1b2\n",
+			format:  adr,
+			want:    []int{1, 2},
+			wantIDs: []string{"ADR-00ff11", "DEC-a1b2"},
+		},
+		{
+			name:    "binary",
+			content: "\x00\x01// keel:DEC-a1b2\n",
+			format:  id.DefaultFormat,
+			skipped: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := t.TempDir()
+			writeFiles(t, root, map[string]string{"src/main.go": tt.content})
+
+			annotations, ok, err := File(root, "src/main.go", tt.format)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if ok == tt.skipped {
+				t.Fatalf("ok = %v, want %v", ok, !tt.skipped)
+			}
+			var lines []int
+			var ids []string
+			for _, a := range annotations {
+				if a.File != "src/main.go" {
+					t.Errorf("file = %s, want src/main.go", a.File)
+				}
+				lines = append(lines, a.Line)
+				ids = append(ids, a.DecisionID)
+			}
+			if !reflect.DeepEqual(lines, tt.want) {
+				t.Errorf("lines = %v, want %v", lines, tt.want)
+			}
+			if tt.wantIDs != nil && !reflect.DeepEqual(ids, tt.wantIDs) {
+				t.Errorf("IDs = %v, want %v", ids, tt.wantIDs)
+			}
+		})
+	}
+}
+
+func TestRepoOutsideGit(t *testing.T) {
+	root := t.TempDir()
+	writeFiles(t, root, map[string]string{
+		".gitignore":          "build/\n*.gen.go\n!keep.gen.go\n/docs/draft.md\n",
+		"main.go":             "// keel:DEC-0001\n",
+		"build/out.go":        "// keel:DEC-0002\n",
+		"api/types.gen.go":    "// keel:DEC-0003\n",
+		"api/keep.gen.go":     "// keel:DEC-0004\n",
+		"docs/draft.md":       "<!-- keel:DEC-0005 -->\n",
+		"docs/guide/draft.md": "<!-- keel:DEC-0006 -->\n",
+		".keel/notes.md":      "# keel:DEC-0007\n",
+	})
+
+	res, err := Repo(root, id.DefaultFormat)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []types.Annotation{
+		{DecisionID: "DEC-0004", File: "api/keep.gen.go", Line: 1},
+		{DecisionID: "DEC-0006", File: "docs/guide/draft.md", Line: 1},
+		{DecisionID: "DEC-0001", File: "main.go", Line: 1},
+	}
+	if !reflect.DeepEqual(res.Annotations, want) {
+		t.Errorf("annotations = %+v, want %+v", res.Annotations, want)
+	}
+	// .gitignore itself is scanned too
+	if res.Files != 4 {
+		t.Errorf("files scanned = %d, want 4", res.Files)
+	}
+}
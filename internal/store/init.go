@@ -1,8 +1,9 @@
 package store
 
 import (
-	"fmt"
 	"os"
+
+	"github.com/tyroneavnit/keel/internal/errs"
 )
 
 // IsInitialized checks if Keel has been initialized in the repo
@@ -15,7 +16,7 @@ func IsInitialized(repoRoot string) bool {
 // RequireInit returns an error if Keel is not initialized
 func RequireInit(repoRoot string) error {
 	if !IsInitialized(repoRoot) {
-		return fmt.Errorf("Keel not initialized. Run 'keel init' first (humans do this, not agents)")
+		return errs.New(errs.NotInitialized, "Keel not initialized. Run 'keel init' first (humans do this, not agents)")
 	}
 	return nil
 }
@@ -0,0 +1,56 @@
+package store
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/tyroneavnit/keel/internal/errs"
+)
+
+const LockFile = "decisions.lock"
+
+const (
+	lockTimeout = 10 * time.Second
+	lockRetry   = 20 * time.Millisecond
+
+	// A lock older than this was left behind by a crashed writer
+	lockStale = 30 * time.Second
+)
+
+// Lock takes the ledger write lock, waiting for other writers to finish.
+// Writers that read the ledger before appending to it, such as marking a
+// decision superseded, hold the lock so concurrent processes can't
+// interleave. The returned function releases it and is safe to call twice.
+func Lock(repoRoot string) (func(), error) {
+	if err := EnsureKeelDir(repoRoot); err != nil {
+		return nil, fmt.Errorf("failed to create keel directory: %w", err)
+	}
+
+	path := filepath.Join(GetKeelDir(repoRoot), LockFile)
+	deadline := time.Now().Add(lockTimeout)
+	for {
+		f, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+		if err == nil {
+			fmt.Fprintf(f, "%d\n", os.Getpid())
+			f.Close()
+			var once sync.Once
+			return func() { once.Do(func() { os.Remove(path) }) }, nil
+		}
+		if !errors.Is(err, os.ErrExist) {
+			return nil, fmt.Errorf("failed to lock decisions file: %w", err)
+		}
+
+		if info, err := os.Stat(path); err == nil && time.Since(info.ModTime()) > lockStale {
+			os.Remove(path)
+			continue
+		}
+		if time.Now().After(deadline) {
+			return nil, errs.New(errs.Conflict, "timed out waiting for the ledger lock; remove %s if no other keel process is running", path)
+		}
+		time.Sleep(lockRetry)
+	}
+}
@@ -0,0 +1,146 @@
+package store
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/tyroneavnit/keel/internal/git"
+)
+
+// RootEnv overrides repository discovery, like the --repo flag
+const RootEnv = "KEEL_DIR"
+
+// RootLedger names the ledger at the repository root
+const RootLedger = "."
+
+// ResolveRoot returns the repository root keel should use. An explicit
+// directory, or else $KEEL_DIR, is used in place of the working directory;
+// either may also name a .keel directory.
+func ResolveRoot(explicit string) (string, error) {
+	dir, err := startDir(explicit)
+	if err != nil {
+		return "", err
+	}
+	return FindRoot(dir), nil
+}
+
+// CurrentLedger returns the ledger new decisions go to: the nearest one at
+// or above the explicit directory, $KEEL_DIR or the working directory
+func CurrentLedger(explicit, root string) (string, error) {
+	dir, err := startDir(explicit)
+	if err != nil {
+		return "", err
+	}
+	return NearestLedger(root, dir), nil
+}
+
+// startDir returns the directory repository discovery starts from
+func startDir(explicit string) (string, error) {
+	if explicit == "" {
+		explicit = os.Getenv(RootEnv)
+	}
+	if explicit == "" {
+		return os.Getwd()
+	}
+	dir, err := filepath.Abs(explicit)
+	if err != nil {
+		return "", err
+	}
+	if filepath.Base(dir) == KeelDir {
+		dir = filepath.Dir(dir)
+	}
+	return dir, nil
+}
+
+// FindRoot returns the repository root for dir: the git toplevel, which
+// holds the root ledger and which every path is relative to. Outside git
+// it is the outermost directory above dir holding a .keel directory, or
+// dir itself.
+func FindRoot(dir string) string {
+	if resolved, err := filepath.EvalSymlinks(dir); err == nil {
+		dir = resolved
+	}
+	if top, err := git.Run(dir, "rev-parse", "--show-toplevel"); err == nil {
+		return filepath.FromSlash(top)
+	}
+
+	root := dir
+	for d := dir; ; d = filepath.Dir(d) {
+		if isKeelDir(d) {
+			root = d
+		}
+		if filepath.Dir(d) == d {
+			break
+		}
+	}
+	return root
+}
+
+// NearestLedger returns the repo-relative directory of the ledger at or
+// above dir, or the root ledger when there is none below the root
+func NearestLedger(root, dir string) string {
+	if resolved, err := filepath.EvalSymlinks(dir); err == nil {
+		dir = resolved
+	}
+	rel, err := filepath.Rel(root, dir)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return RootLedger
+	}
+	for ; rel != "." && rel != string(filepath.Separator); rel = filepath.Dir(rel) {
+		if isKeelDir(filepath.Join(root, rel)) {
+			return filepath.ToSlash(rel)
+		}
+	}
+	return RootLedger
+}
+
+// LedgerDir returns the directory holding a repo-relative ledger
+func LedgerDir(root, ledger string) string {
+	return filepath.Join(root, filepath.FromSlash(ledger))
+}
+
+// Ledgers returns the repo-relative directories of every ledger in the
+// repository, the root ledger first. Nested ledgers are found through git,
+// tracked or not, so ignored and vendored trees are skipped.
+func Ledgers(root string) ([]string, error) {
+	ledgers := []string{RootLedger}
+	out, err := git.Run(root, "ls-files", "--cached", "--others", "--exclude-standard", "--full-name",
+		"--", fmt.Sprintf(":(top,glob)**/%s/%s", KeelDir, DecisionsFile))
+	if err != nil {
+		// Outside git only the root ledger is known
+		return ledgers, nil
+	}
+
+	seen := map[string]bool{RootLedger: true}
+	var nested []string
+	for _, line := range strings.Split(out, "\n") {
+		if line == "" {
+			continue
+		}
+		dir := strings.TrimSuffix(line, "/"+KeelDir+"/"+DecisionsFile)
+		if dir == line || seen[dir] {
+			continue
+		}
+		seen[dir] = true
+		nested = append(nested, dir)
+	}
+	sort.Strings(nested)
+	return append(ledgers, nested...), nil
+}
+
+// LedgerCovers reports whether a ledger applies to a repo-relative path:
+// the root ledger covers everything, a nested one its own subtree
+func LedgerCovers(ledger, path string) bool {
+	if ledger == "" || ledger == RootLedger {
+		return true
+	}
+	return path == ledger || strings.HasPrefix(path, ledger+"/")
+}
+
+func isKeelDir(dir string) bool {
+	info, err := os.Stat(GetKeelDir(dir))
+	return err == nil && info.IsDir()
+}
@@ -0,0 +1,133 @@
+package store
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// mkdirs creates directories under root and returns root with symlinks
+// resolved, as FindRoot reports it
+func mkdirs(t *testing.T, root string, dirs ...string) string {
+	t.Helper()
+	for _, dir := range dirs {
+		if err := os.MkdirAll(filepath.Join(root, filepath.FromSlash(dir)), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	resolved, err := filepath.EvalSymlinks(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return resolved
+}
+
+func TestResolveRoot(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+
+	plain := mkdirs(t, t.TempDir(), ".keel", "app/.keel", "app/src")
+	empty := mkdirs(t, t.TempDir())
+	repo := mkdirs(t, t.TempDir(), "services/billing/.keel", "services/billing/api")
+	if out, err := exec.Command("git", "init", "--quiet", repo).CombinedOutput(); err != nil {
+		t.Fatalf("git init: %v\n%s", err, out)
+	}
+
+	tests := []struct {
+		name     string
+		explicit string
+		env      string
+		want     string
+	}{
+		{name: "outermost ledger outside git", explicit: filepath.Join(plain, "app", "src"), want: plain},
+		{name: "directory without a ledger", explicit: empty, want: empty},
+		{name: "git toplevel", explicit: filepath.Join(repo, "services", "billing", "api"), want: repo},
+		{name: "keel directory given", explicit: filepath.Join(repo, "services", "billing", ".keel"), want: repo},
+		{name: "from KEEL_DIR", env: filepath.Join(plain, "app"), want: plain},
+		{name: "flag over KEEL_DIR", explicit: repo, env: plain, want: repo},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv(RootEnv, tt.env)
+			got, err := ResolveRoot(tt.explicit)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("ResolveRoot(%q) = %s, want %s", tt.explicit, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNestedLedgers(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+
+	root := mkdirs(t, t.TempDir(), ".keel", "services/billing/.keel", "services/billing/api", "services/auth/.keel", "vendor/lib/.keel", "docs")
+	for _, ledger := range []string{".", "services/billing", "services/auth", "vendor/lib"} {
+		if err := os.WriteFile(GetDecisionsPath(LedgerDir(root, ledger)), nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(root, ".gitignore"), []byte("vendor/\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if out, err := exec.Command("git", "init", "--quiet", root).CombinedOutput(); err != nil {
+		t.Fatalf("git init: %v\n%s", err, out)
+	}
+
+	t.Run("Ledgers", func(t *testing.T) {
+		got, err := Ledgers(root)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := []string{".", "services/auth", "services/billing"}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("Ledgers() = %v, want %v", got, want)
+		}
+	})
+
+	nearest := []struct {
+		dir  string
+		want string
+	}{
+		{".", "."},
+		{"docs", "."},
+		{"services", "."},
+		{"services/billing", "services/billing"},
+		{"services/billing/api", "services/billing"},
+		{"services/billing/.keel", "services/billing"},
+		{"..", "."},
+	}
+	for _, tt := range nearest {
+		t.Run("NearestLedger "+tt.dir, func(t *testing.T) {
+			if got := NearestLedger(root, filepath.Join(root, filepath.FromSlash(tt.dir))); got != tt.want {
+				t.Errorf("NearestLedger(%s) = %s, want %s", tt.dir, got, tt.want)
+			}
+		})
+	}
+
+	covers := []struct {
+		ledger, path string
+		want         bool
+	}{
+		{".", "services/billing/api/server.go", true},
+		{"", "main.go", true},
+		{"services/billing", "services/billing/api/server.go", true},
+		{"services/billing", "services/billing", true},
+		{"services/billing", "services/billing-v2/main.go", false},
+		{"services/billing", "main.go", false},
+	}
+	for _, tt := range covers {
+		t.Run("LedgerCovers "+tt.ledger+" "+tt.path, func(t *testing.T) {
+			if got := LedgerCovers(tt.ledger, tt.path); got != tt.want {
+				t.Errorf("LedgerCovers(%q, %q) = %v, want %v", tt.ledger, tt.path, got, tt.want)
+			}
+		})
+	}
+}
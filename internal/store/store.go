@@ -4,6 +4,7 @@ import (
 	"bufio"
 	"encoding/json"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 
@@ -47,7 +48,10 @@ func AppendDecision(decision *types.Decision, repoRoot string) error {
 	}
 	defer f.Close()
 
-	data, err := json.Marshal(decision)
+	// The ledger a decision belongs to is where it is written, not a field of it
+	line := *decision
+	line.Ledger, line.Upstream = "", ""
+	data, err := json.Marshal(&line)
 	if err != nil {
 		return fmt.Errorf("failed to marshal decision: %w", err)
 	}
@@ -61,8 +65,12 @@ func AppendDecision(decision *types.Decision, repoRoot string) error {
 
 // ReadAllDecisions reads all decisions from the JSONL file
 func ReadAllDecisions(repoRoot string) ([]*types.Decision, error) {
-	path := GetDecisionsPath(repoRoot)
+	return ReadDecisionsFile(GetDecisionsPath(repoRoot))
+}
 
+// ReadDecisionsFile reads all decisions from a ledger file at any path,
+// such as a cached upstream ledger
+func ReadDecisionsFile(path string) ([]*types.Decision, error) {
 	if _, err := os.Stat(path); os.IsNotExist(err) {
 		return []*types.Decision{}, nil
 	}
@@ -73,8 +81,25 @@ func ReadAllDecisions(repoRoot string) ([]*types.Decision, error) {
 	}
 	defer f.Close()
 
-	var decisions []*types.Decision
-	scanner := bufio.NewScanner(f)
+	entries, err := ReadEntries(f)
+	if err != nil {
+		return nil, err
+	}
+	return Decisions(entries), nil
+}
+
+// Entry is one line of a ledger: a decision as it was recorded or updated
+type Entry struct {
+	Line     int // 1-based line number in the ledger
+	Decision *types.Decision
+}
+
+// ReadEntries reads the lines of a ledger, such as one read from git
+// history, skipping blank lines and warning about unparseable ones
+func ReadEntries(r io.Reader) ([]Entry, error) {
+	var entries []Entry
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
 	lineNum := 0
 
 	for scanner.Scan() {
@@ -89,24 +114,43 @@ func ReadAllDecisions(repoRoot string) ([]*types.Decision, error) {
 			fmt.Fprintf(os.Stderr, "Warning: Failed to parse line %d: %s\n", lineNum, line)
 			continue
 		}
-		decisions = append(decisions, &d)
+		entries = append(entries, Entry{Line: lineNum, Decision: &d})
 	}
 
 	if err := scanner.Err(); err != nil {
 		return nil, fmt.Errorf("error reading decisions file: %w", err)
 	}
 
-	return decisions, nil
+	return entries, nil
+}
+
+// Decisions returns the decisions recorded by ledger entries, in order
+func Decisions(entries []Entry) []*types.Decision {
+	decisions := make([]*types.Decision, len(entries))
+	for i, e := range entries {
+		decisions[i] = e.Decision
+	}
+	return decisions
 }
 
 // GetLatestState returns the latest state of all decisions
 // (later lines override earlier ones for the same ID)
 func GetLatestState(repoRoot string) (map[string]*types.Decision, error) {
-	decisions, err := ReadAllDecisions(repoRoot)
+	return GetLatestStateFile(GetDecisionsPath(repoRoot))
+}
+
+// GetLatestStateFile returns the latest state of the decisions in a ledger
+// file at any path
+func GetLatestStateFile(path string) (map[string]*types.Decision, error) {
+	decisions, err := ReadDecisionsFile(path)
 	if err != nil {
 		return nil, err
 	}
+	return Reduce(decisions), nil
+}
 
+// Reduce replays ledger lines in order into the latest state of each decision
+func Reduce(decisions []*types.Decision) map[string]*types.Decision {
 	state := make(map[string]*types.Decision)
 	for _, d := range decisions {
 		if existing, ok := state[d.ID]; ok {
@@ -117,8 +161,7 @@ func GetLatestState(repoRoot string) (map[string]*types.Decision, error) {
 			state[d.ID] = d
 		}
 	}
-
-	return state, nil
+	return state
 }
 
 // GetDecisionByID returns a decision by its ID
@@ -146,6 +189,11 @@ func GetActiveDecisions(repoRoot string) ([]*types.Decision, error) {
 	return active, nil
 }
 
+// Merge applies a later ledger line for a decision to its earlier state
+func Merge(existing, newer *types.Decision) *types.Decision {
+	return mergeDecisions(existing, newer)
+}
+
 // mergeDecisions merges two decisions, with newer values overriding older ones
 func mergeDecisions(existing, newer *types.Decision) *types.Decision {
 	merged := *existing
@@ -160,6 +208,37 @@ func mergeDecisions(existing, newer *types.Decision) *types.Decision {
 	if newer.Supersedes != nil {
 		merged.Supersedes = newer.Supersedes
 	}
+	if newer.Anchors != nil {
+		merged.Anchors = newer.Anchors
+	}
+
+	// Fields an amendment may change
+	if newer.Rationale != nil {
+		merged.Rationale = newer.Rationale
+	}
+	if newer.Files != nil {
+		merged.Files = newer.Files
+	}
+	if newer.Symbols != nil {
+		merged.Symbols = newer.Symbols
+	}
+	if newer.Refs != nil {
+		merged.Refs = newer.Refs
+	}
+	if newer.ReviewBy != nil {
+		merged.ReviewBy = newer.ReviewBy
+	}
+	if newer.ExpiresAt != nil {
+		merged.ExpiresAt = newer.ExpiresAt
+	}
+
+	// Approvals accumulate: a sign-off recorded on one branch must survive
+	// another branch appending its own approval for the same decision.
+	for _, a := range newer.Approvals {
+		if !merged.HasApprovalFrom(a.By) {
+			merged.Approvals = append(merged.Approvals, a)
+		}
+	}
 
 	return &merged
 }
@@ -0,0 +1,108 @@
+package store
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/tyroneavnit/keel/internal/types"
+)
+
+func strPtr(s string) *string { return &s }
+
+func TestReduce(t *testing.T) {
+	original := func() *types.Decision {
+		return &types.Decision{
+			ID:        "DEC-a1b2",
+			CreatedAt: "2026-01-01T00:00:00Z",
+			Type:      types.TypeProduct,
+			Problem:   "Where retries live",
+			Choice:    "In the client",
+			Rationale: strPtr("Fewer hops"),
+			Files:     []string{"client.go"},
+			Refs:      []string{"JIRA-1"},
+			Status:    types.StatusActive,
+		}
+	}
+
+	tests := []struct {
+		name  string
+		lines []*types.Decision
+		want  func(d *types.Decision)
+	}{
+		{
+			name: "amend replaces given fields",
+			lines: []*types.Decision{
+				{ID: "DEC-a1b2", Rationale: strPtr("Simpler"), Files: []string{"retry.go"}, ReviewBy: strPtr("2027-01-01T00:00:00Z")},
+			},
+			want: func(d *types.Decision) {
+				d.Rationale = strPtr("Simpler")
+				d.Files = []string{"retry.go"}
+				d.ReviewBy = strPtr("2027-01-01T00:00:00Z")
+			},
+		},
+		{
+			name: "amend keeps unset fields",
+			lines: []*types.Decision{
+				{ID: "DEC-a1b2", Refs: []string{"JIRA-2"}},
+			},
+			want: func(d *types.Decision) {
+				d.Refs = []string{"JIRA-2"}
+			},
+		},
+		{
+			name: "approvals accumulate",
+			lines: []*types.Decision{
+				{ID: "DEC-a1b2", Approvals: []types.Approval{{By: "ana@example.com", ApprovedAt: "2026-01-02T00:00:00Z"}}},
+				{ID: "DEC-a1b2", Approvals: []types.Approval{{By: "bo@example.com", ApprovedAt: "2026-01-03T00:00:00Z"}}},
+			},
+			want: func(d *types.Decision) {
+				d.Approvals = []types.Approval{
+					{By: "ana@example.com", ApprovedAt: "2026-01-02T00:00:00Z"},
+					{By: "bo@example.com", ApprovedAt: "2026-01-03T00:00:00Z"},
+				}
+			},
+		},
+		{
+			name: "approval from another branch is not repeated",
+			lines: []*types.Decision{
+				{ID: "DEC-a1b2", Approvals: []types.Approval{{By: "ana@example.com", ApprovedAt: "2026-01-02T00:00:00Z"}}},
+				{ID: "DEC-a1b2", Approvals: []types.Approval{
+					{By: "ana@example.com", ApprovedAt: "2026-01-04T00:00:00Z"},
+					{By: "bo@example.com", ApprovedAt: "2026-01-03T00:00:00Z"},
+				}},
+			},
+			want: func(d *types.Decision) {
+				d.Approvals = []types.Approval{
+					{By: "ana@example.com", ApprovedAt: "2026-01-02T00:00:00Z"},
+					{By: "bo@example.com", ApprovedAt: "2026-01-03T00:00:00Z"},
+				}
+			},
+		},
+		{
+			name: "supersede",
+			lines: []*types.Decision{
+				{ID: "DEC-a1b2", Status: types.StatusSuperseded, SupersededBy: strPtr("DEC-c3d4")},
+			},
+			want: func(d *types.Decision) {
+				d.Status = types.StatusSuperseded
+				d.SupersededBy = strPtr("DEC-c3d4")
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			successor := &types.Decision{ID: "DEC-c3d4", Status: types.StatusActive, Supersedes: strPtr("DEC-a1b2")}
+			lines := append([]*types.Decision{original(), successor}, tt.lines...)
+
+			state := Reduce(lines)
+			want := original()
+			tt.want(want)
+			if got := state["DEC-a1b2"]; !reflect.DeepEqual(got, want) {
+				t.Errorf("Reduce() =\n%+v\nwant\n%+v", got, want)
+			}
+			if got := state["DEC-c3d4"]; !reflect.DeepEqual(got, successor) {
+				t.Errorf("successor = %+v, want %+v", got, successor)
+			}
+		})
+	}
+}
@@ -0,0 +1,67 @@
+package symbols
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+// Extractor lists the declarations in a source file
+type Extractor interface {
+	Extract(repoRoot, rel string) ([]Symbol, error)
+}
+
+// extractors maps file extensions to the extractor that understands them
+var extractors = map[string]Extractor{
+	".go":   goExtractor{},
+	".ts":   tsExtractor,
+	".tsx":  tsExtractor,
+	".js":   tsExtractor,
+	".jsx":  tsExtractor,
+	".mjs":  tsExtractor,
+	".py":   pythonExtractor,
+	".rs":   rustExtractor,
+	".java": javaExtractor,
+}
+
+// RegisterExtractor sets the extractor for a file extension (e.g. ".rb")
+func RegisterExtractor(ext string, e Extractor) {
+	extractors[strings.ToLower(ext)] = e
+}
+
+// ExtractorFor returns the extractor for a file, or nil if the language is unsupported
+func ExtractorFor(rel string) Extractor {
+	return extractors[strings.ToLower(filepath.Ext(rel))]
+}
+
+// SymbolAt returns the innermost function, method or type enclosing a line
+// of a repo-relative file. It returns nil when no declaration encloses the line
+// or the language is unsupported.
+func SymbolAt(repoRoot, rel string, line int) (*Symbol, error) {
+	extractor := ExtractorFor(rel)
+	if extractor == nil {
+		return nil, nil
+	}
+
+	syms, err := extractor.Extract(repoRoot, rel)
+	if err != nil {
+		return nil, err
+	}
+
+	var best *Symbol
+	for i := range syms {
+		s := &syms[i]
+		if line < s.Line || line > s.EndLine {
+			continue
+		}
+		if best == nil || s.EndLine-s.Line < best.EndLine-best.Line {
+			best = s
+		}
+	}
+	return best, nil
+}
+
+type goExtractor struct{}
+
+func (goExtractor) Extract(repoRoot, rel string) ([]Symbol, error) {
+	return ParseGoFile(repoRoot, rel)
+}
@@ -0,0 +1,63 @@
+package symbols
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSymbolAt(t *testing.T) {
+	root := writeGoRepo(t)
+	for name, content := range map[string]string{
+		"web/checkout.ts": checkoutTS,
+		"py/checkout.py":  checkoutPy,
+		"README.md":       "# Shop\n",
+	} {
+		path := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name string
+		file string
+		line int
+		want string // "" when no symbol encloses the line
+	}{
+		{name: "go method first line", file: "internal/billing/invoice.go", line: 9, want: "billing.(*Invoice).Total"},
+		{name: "go method last line", file: "internal/billing/invoice.go", line: 11, want: "billing.(*Invoice).Total"},
+		{name: "go doc comment", file: "internal/billing/invoice.go", line: 8},
+		{name: "go between declarations", file: "internal/billing/invoice.go", line: 12},
+		{name: "go type body", file: "internal/billing/invoice.go", line: 5, want: "billing.Invoice"},
+		{name: "ts method first line", file: "web/checkout.ts", line: 8, want: "Checkout.pay"},
+		{name: "ts method closing brace", file: "web/checkout.ts", line: 12, want: "Checkout.pay"},
+		{name: "ts class outside methods", file: "web/checkout.ts", line: 4, want: "Checkout"},
+		{name: "ts class closing brace", file: "web/checkout.ts", line: 13, want: "Checkout"},
+		{name: "ts after class", file: "web/checkout.ts", line: 14},
+		{name: "ts import", file: "web/checkout.ts", line: 1},
+		{name: "python blank line inside method", file: "py/checkout.py", line: 10, want: "Checkout.pay"},
+		{name: "python class attribute", file: "py/checkout.py", line: 5, want: "Checkout"},
+		{name: "python after class", file: "py/checkout.py", line: 13},
+		{name: "past the end", file: "py/checkout.py", line: 99},
+		{name: "unsupported language", file: "README.md", line: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := SymbolAt(root, tt.file, tt.line)
+			if err != nil {
+				t.Fatal(err)
+			}
+			name := ""
+			if got != nil {
+				name = got.Name
+			}
+			if name != tt.want {
+				t.Errorf("SymbolAt(%s:%d) = %q, want %q", tt.file, tt.line, name, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,307 @@
+package symbols
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// goSymbolPattern matches qualified Go symbols:
+//
+//	billing.Total                  function, type, var or const
+//	billing.Invoice.Total          method with value receiver
+//	billing.(*Invoice).Total       method with pointer receiver
+//	internal/billing.Invoice       qualified by repo-relative directory
+var goSymbolPattern = regexp.MustCompile(`^([\w.-]+/)*\w+\.(\(\*\w+\)\.\w+|\w+(\.\w+)?)$`)
+
+// IsGoSymbol reports whether a name is written as a qualified Go symbol
+func IsGoSymbol(name string) bool {
+	return goSymbolPattern.MatchString(name)
+}
+
+// HasGoModule reports whether the repository root contains a go.mod
+func HasGoModule(repoRoot string) bool {
+	_, err := os.Stat(filepath.Join(repoRoot, "go.mod"))
+	return err == nil
+}
+
+// splitGoSymbol separates the package qualifier from the member name.
+// The qualifier is a package name, or a repo-relative directory when it contains a slash.
+func splitGoSymbol(name string) (qualifier, member string) {
+	slash := strings.LastIndex(name, "/")
+	dot := strings.Index(name[slash+1:], ".")
+	if dot < 0 {
+		return "", name
+	}
+	dot += slash + 1
+	return name[:dot], name[dot+1:]
+}
+
+// memberKey drops pointer-receiver syntax so "(*Invoice).Total" and
+// "Invoice.Total" refer to the same method
+func memberKey(member string) string {
+	return strings.NewReplacer("(*", "", ")", "").Replace(member)
+}
+
+// GoResolver resolves qualified Go symbols against the repository source tree.
+// Package clauses are read up front; files are fully parsed only when a
+// symbol in their package is requested.
+type GoResolver struct {
+	repoRoot string
+	byPkg    map[string][]string // package name -> files
+	byDir    map[string][]string // repo-relative directory -> files
+	parsed   map[string][]Symbol // file -> symbols
+}
+
+// NewGoResolver scans the repository for Go packages
+func NewGoResolver(repoRoot string) (*GoResolver, error) {
+	r := &GoResolver{
+		repoRoot: repoRoot,
+		byPkg:    make(map[string][]string),
+		byDir:    make(map[string][]string),
+		parsed:   make(map[string][]Symbol),
+	}
+
+	fset := token.NewFileSet()
+	err := walkSource(repoRoot, ".go", func(rel string) error {
+		f, err := parser.ParseFile(fset, filepath.Join(repoRoot, rel), nil, parser.PackageClauseOnly)
+		if err != nil {
+			return nil
+		}
+		r.byPkg[f.Name.Name] = append(r.byPkg[f.Name.Name], rel)
+		dir := path.Dir(rel)
+		r.byDir[dir] = append(r.byDir[dir], rel)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan Go sources: %w", err)
+	}
+	return r, nil
+}
+
+// Owns reports whether a symbol's qualifier names a Go package (or package
+// directory) in the repository. Names that don't, such as TypeScript's
+// Checkout.pay, are not Go symbols even if they are spelled like one.
+func (r *GoResolver) Owns(name string) bool {
+	if !IsGoSymbol(name) {
+		return false
+	}
+	qualifier, _ := splitGoSymbol(name)
+	if strings.Contains(qualifier, "/") {
+		return len(r.byDir[qualifier]) > 0
+	}
+	return len(r.byPkg[qualifier]) > 0
+}
+
+// Resolve finds a qualified symbol. It returns nil when no declaration matches.
+func (r *GoResolver) Resolve(name string) (*Symbol, error) {
+	qualifier, member := splitGoSymbol(name)
+	if qualifier == "" {
+		return nil, nil
+	}
+
+	files := r.byPkg[qualifier]
+	if strings.Contains(qualifier, "/") {
+		files = r.byDir[qualifier]
+	}
+
+	key := memberKey(member)
+	for _, file := range files {
+		syms, err := r.FileSymbols(file)
+		if err != nil {
+			continue
+		}
+		for i := range syms {
+			_, symMember := splitGoSymbol(syms[i].Name)
+			if memberKey(symMember) == key {
+				return &syms[i], nil
+			}
+		}
+	}
+	return nil, nil
+}
+
+// FileSymbols returns the top-level declarations of a repo-relative Go file
+func (r *GoResolver) FileSymbols(rel string) ([]Symbol, error) {
+	if syms, ok := r.parsed[rel]; ok {
+		return syms, nil
+	}
+	syms, err := ParseGoFile(r.repoRoot, rel)
+	if err != nil {
+		return nil, err
+	}
+	r.parsed[rel] = syms
+	return syms, nil
+}
+
+// ParseGoFile extracts top-level functions, methods, types, vars and consts
+// from a repo-relative Go file, named as qualified symbols
+func ParseGoFile(repoRoot, rel string) ([]Symbol, error) {
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, filepath.Join(repoRoot, rel), nil, parser.SkipObjectResolution)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", rel, err)
+	}
+
+	pkg := f.Name.Name
+	var syms []Symbol
+	add := func(name string, kind Kind, node ast.Node) {
+		syms = append(syms, Symbol{
+			Name:    pkg + "." + name,
+			Kind:    kind,
+			File:    rel,
+			Line:    fset.Position(node.Pos()).Line,
+			EndLine: fset.Position(node.End()).Line,
+		})
+	}
+
+	for _, decl := range f.Decls {
+		switch decl := decl.(type) {
+		case *ast.FuncDecl:
+			if decl.Recv == nil || len(decl.Recv.List) == 0 {
+				add(decl.Name.Name, KindFunc, decl)
+				continue
+			}
+			recv, pointer := receiverName(decl.Recv.List[0].Type)
+			if pointer {
+				add(fmt.Sprintf("(*%s).%s", recv, decl.Name.Name), KindMethod, decl)
+			} else {
+				add(fmt.Sprintf("%s.%s", recv, decl.Name.Name), KindMethod, decl)
+			}
+		case *ast.GenDecl:
+			for _, spec := range decl.Specs {
+				// A lone spec covers its keyword; grouped specs cover themselves
+				var node ast.Node = spec
+				if len(decl.Specs) == 1 {
+					node = decl
+				}
+				switch spec := spec.(type) {
+				case *ast.TypeSpec:
+					add(spec.Name.Name, KindType, node)
+				case *ast.ValueSpec:
+					kind := KindVar
+					if decl.Tok == token.CONST {
+						kind = KindConst
+					}
+					for _, name := range spec.Names {
+						if name.Name != "_" {
+							add(name.Name, kind, node)
+						}
+					}
+				}
+			}
+		}
+	}
+
+	sort.SliceStable(syms, func(i, j int) bool { return syms[i].Line < syms[j].Line })
+	return syms, nil
+}
+
+// receiverName returns the base type name of a method receiver,
+// stripping pointers and type parameters
+func receiverName(expr ast.Expr) (name string, pointer bool) {
+	if star, ok := expr.(*ast.StarExpr); ok {
+		expr = star.X
+		pointer = true
+	}
+	switch t := expr.(type) {
+	case *ast.IndexExpr:
+		expr = t.X
+	case *ast.IndexListExpr:
+		expr = t.X
+	}
+	if ident, ok := expr.(*ast.Ident); ok {
+		return ident.Name, pointer
+	}
+	return "", pointer
+}
+
+// GoSymbols resolves qualified names against a repository's Go sources. The
+// sources are scanned on first use and the scan is kept, so make a new one
+// when files may have changed.
+type GoSymbols struct {
+	repoRoot string
+	resolver *GoResolver
+}
+
+// NewGoSymbols returns a resolver for the repository's Go sources
+func NewGoSymbols(repoRoot string) *GoSymbols {
+	return &GoSymbols{repoRoot: repoRoot}
+}
+
+// Resolve looks up qualified Go symbols in the source tree.
+// Only repos with a go.mod are checked, and only names qualified by a Go
+// package in the repo; other names are free-form and neither resolved nor missing.
+func (g *GoSymbols) Resolve(names []string) (resolved map[string]*Symbol, missing []string, err error) {
+	resolved = make(map[string]*Symbol)
+	if !HasGoModule(g.repoRoot) {
+		return resolved, nil, nil
+	}
+
+	for _, name := range names {
+		if !IsGoSymbol(name) {
+			continue
+		}
+		resolver, err := g.goResolver()
+		if err != nil {
+			return nil, nil, err
+		}
+		if !resolver.Owns(name) {
+			continue
+		}
+		sym, err := resolver.Resolve(name)
+		if err != nil {
+			return nil, nil, err
+		}
+		if sym == nil {
+			missing = append(missing, name)
+		} else {
+			resolved[name] = sym
+		}
+	}
+	return resolved, missing, nil
+}
+
+// Lookup resolves one qualified Go symbol. It returns nil when the name
+// isn't a Go symbol of the repository or has no declaration.
+func (g *GoSymbols) Lookup(name string) (*Symbol, error) {
+	if !IsGoSymbol(name) || !HasGoModule(g.repoRoot) {
+		return nil, nil
+	}
+	resolver, err := g.goResolver()
+	if err != nil {
+		return nil, err
+	}
+	return resolver.Resolve(name)
+}
+
+func (g *GoSymbols) goResolver() (*GoResolver, error) {
+	if g.resolver == nil {
+		r, err := NewGoResolver(g.repoRoot)
+		if err != nil {
+			return nil, err
+		}
+		g.resolver = r
+	}
+	return g.resolver, nil
+}
+
+// Canonical rewrites resolved Go symbols to their canonical spelling
+// (e.g. billing.Invoice.Total -> billing.(*Invoice).Total) so lookups match
+func Canonical(names []string, resolved map[string]*Symbol) []string {
+	result := make([]string, 0, len(names))
+	for _, name := range names {
+		if sym, ok := resolved[name]; ok {
+			name = sym.Name
+		}
+		result = append(result, name)
+	}
+	return result
+}
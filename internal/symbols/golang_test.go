@@ -0,0 +1,106 @@
+package symbols
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+const invoiceSource = `package billing
+
+// Invoice is a bill
+type Invoice struct {
+	Lines []int
+}
+
+// Total sums the lines
+func (inv *Invoice) Total() int {
+	return 0
+}
+
+func (inv Invoice) Currency() string {
+	return "EUR"
+}
+
+func New() *Invoice { return &Invoice{} }
+
+const (
+	MaxLines = 100
+	MinLines = 1
+)
+`
+
+// writeGoRepo creates a Go module holding internal/billing/invoice.go
+func writeGoRepo(t *testing.T) string {
+	t.Helper()
+	root := t.TempDir()
+	files := map[string]string{
+		"go.mod":                      "module example.com/shop\n",
+		"internal/billing/invoice.go": invoiceSource,
+		"internal/broken/broken.go":   "package broken\n\nfunc Half( {\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return root
+}
+
+func TestGoResolverResolve(t *testing.T) {
+	r, err := NewGoResolver(writeGoRepo(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		want *Symbol // nil when the symbol has no declaration
+	}{
+		{"billing.Invoice", &Symbol{Name: "billing.Invoice", Kind: KindType, File: "internal/billing/invoice.go", Line: 4, EndLine: 6}},
+		{"billing.New", &Symbol{Name: "billing.New", Kind: KindFunc, File: "internal/billing/invoice.go", Line: 17, EndLine: 17}},
+		{"billing.MinLines", &Symbol{Name: "billing.MinLines", Kind: KindConst, File: "internal/billing/invoice.go", Line: 21, EndLine: 21}},
+		{"billing.(*Invoice).Total", &Symbol{Name: "billing.(*Invoice).Total", Kind: KindMethod, File: "internal/billing/invoice.go", Line: 9, EndLine: 11}},
+		{"billing.Invoice.Total", &Symbol{Name: "billing.(*Invoice).Total", Kind: KindMethod, File: "internal/billing/invoice.go", Line: 9, EndLine: 11}},
+		{"billing.Invoice.Currency", &Symbol{Name: "billing.Invoice.Currency", Kind: KindMethod, File: "internal/billing/invoice.go", Line: 13, EndLine: 15}},
+		{"billing.(*Invoice).Currency", &Symbol{Name: "billing.Invoice.Currency", Kind: KindMethod, File: "internal/billing/invoice.go", Line: 13, EndLine: 15}},
+		{"internal/billing.New", &Symbol{Name: "billing.New", Kind: KindFunc, File: "internal/billing/invoice.go", Line: 17, EndLine: 17}},
+		{"billing.Refund", nil},
+		{"billing.Invoice.Refund", nil},
+		{"other/billing.New", nil},
+		{"broken.Half", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := r.Resolve(tt.name)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Resolve(%q) = %+v, want %+v", tt.name, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGoSymbolsResolve(t *testing.T) {
+	g := NewGoSymbols(writeGoRepo(t))
+	names := []string{"billing.Invoice.Total", "billing.Refund", "Checkout.pay", "rate limiter"}
+
+	resolved, missing, err := g.Resolve(names)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(missing, []string{"billing.Refund"}) {
+		t.Errorf("missing = %v, want [billing.Refund]", missing)
+	}
+	want := []string{"billing.(*Invoice).Total", "billing.Refund", "Checkout.pay", "rate limiter"}
+	if got := Canonical(names, resolved); !reflect.DeepEqual(got, want) {
+		t.Errorf("Canonical() = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,183 @@
+package symbols
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// BlockStyle tells a RegexExtractor where a declaration ends
+type BlockStyle int
+
+const (
+	// BlockBraces ends a declaration at the brace closing its body
+	BlockBraces BlockStyle = iota
+	// BlockIndent ends a declaration before the next line indented at or above it
+	BlockIndent
+)
+
+// RegexRule recognises one kind of declaration. The pattern is matched
+// against each line and must capture the symbol name in a group called "name".
+type RegexRule struct {
+	Kind    Kind
+	Pattern *regexp.Regexp
+}
+
+// RegexExtractor is a line-based extractor for languages without a Go parser.
+// Members declared inside a type are named Type.member.
+type RegexExtractor struct {
+	Rules  []RegexRule
+	Blocks BlockStyle
+}
+
+var tsExtractor = &RegexExtractor{
+	Blocks: BlockBraces,
+	Rules: []RegexRule{
+		{KindType, regexp.MustCompile(`^\s*(?:export\s+)?(?:default\s+)?(?:abstract\s+)?(?:class|interface|enum)\s+(?P<name>[A-Za-z_$][\w$]*)`)},
+		{KindType, regexp.MustCompile(`^\s*(?:export\s+)?type\s+(?P<name>[A-Za-z_$][\w$]*)\s*(?:<[^=]*>)?\s*=`)},
+		{KindFunc, regexp.MustCompile(`^\s*(?:export\s+)?(?:default\s+)?(?:async\s+)?function\s*\*?\s*(?P<name>[A-Za-z_$][\w$]*)`)},
+		{KindFunc, regexp.MustCompile(`^\s*(?:export\s+)?(?:const|let|var)\s+(?P<name>[A-Za-z_$][\w$]*)\s*(?::[^=]+)?=\s*(?:async\s+)?(?:function\b|\([^)]*\)\s*(?::[^=]+)?=>|[A-Za-z_$][\w$]*\s*=>)`)},
+		{KindMethod, regexp.MustCompile(`^\s+(?:(?:public|private|protected|static|readonly|async|override|get|set)\s+)*(?P<name>[A-Za-z_$][\w$]*)\s*(?:<[^>]*>)?\s*\([^;]*$`)},
+	},
+}
+
+var pythonExtractor = &RegexExtractor{
+	Blocks: BlockIndent,
+	Rules: []RegexRule{
+		{KindType, regexp.MustCompile(`^\s*class\s+(?P<name>\w+)`)},
+		{KindFunc, regexp.MustCompile(`^\s*(?:async\s+)?def\s+(?P<name>\w+)`)},
+	},
+}
+
+var rustExtractor = &RegexExtractor{
+	Blocks: BlockBraces,
+	Rules: []RegexRule{
+		{KindType, regexp.MustCompile(`^\s*(?:pub(?:\([^)]*\))?\s+)?(?:struct|enum|trait|union)\s+(?P<name>\w+)`)},
+		{KindType, regexp.MustCompile(`^\s*impl(?:<[^>]*>)?\s+(?:[\w:]+(?:<[^>]*>)?\s+for\s+)?(?:\w+::)*(?P<name>\w+)`)},
+		{KindFunc, regexp.MustCompile(`^\s*(?:pub(?:\([^)]*\))?\s+)?(?:const\s+)?(?:async\s+)?(?:unsafe\s+)?fn\s+(?P<name>\w+)`)},
+	},
+}
+
+var javaExtractor = &RegexExtractor{
+	Blocks: BlockBraces,
+	Rules: []RegexRule{
+		{KindType, regexp.MustCompile(`^\s*(?:(?:public|private|protected|static|final|abstract|sealed)\s+)*(?:class|interface|enum|record)\s+(?P<name>\w+)`)},
+		{KindMethod, regexp.MustCompile(`^\s+(?:(?:public|private|protected|static|final|abstract|synchronized|native|default)\s+)*(?:<[^>]*>\s*)?[\w<>\[\],.? ]+\s+(?P<name>\w+)\s*\([^;]*$`)},
+	},
+}
+
+// controlWords are keywords the loose method patterns must not mistake for names
+var controlWords = map[string]bool{
+	"if": true, "for": true, "while": true, "switch": true, "catch": true,
+	"return": true, "function": true, "new": true, "else": true, "do": true,
+}
+
+// Extract scans a repo-relative file line by line
+func (x *RegexExtractor) Extract(repoRoot, rel string) ([]Symbol, error) {
+	data, err := os.ReadFile(filepath.Join(repoRoot, rel))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", rel, err)
+	}
+	lines := strings.Split(string(data), "\n")
+
+	var syms []Symbol
+	for i, line := range lines {
+		for _, rule := range x.Rules {
+			m := rule.Pattern.FindStringSubmatch(line)
+			if m == nil {
+				continue
+			}
+			name := m[rule.Pattern.SubexpIndex("name")]
+			if name == "" || controlWords[name] {
+				continue
+			}
+			syms = append(syms, Symbol{
+				Name:    name,
+				Kind:    rule.Kind,
+				File:    rel,
+				Line:    i + 1,
+				EndLine: x.blockEnd(lines, i) + 1,
+			})
+			break
+		}
+	}
+
+	// Qualify members with the innermost type that encloses them
+	for i := range syms {
+		var owner *Symbol
+		for j := range syms {
+			t := &syms[j]
+			if i == j || t.Kind != KindType || t.Line > syms[i].Line || t.EndLine < syms[i].EndLine {
+				continue
+			}
+			if owner == nil || t.Line > owner.Line {
+				owner = t
+			}
+		}
+		if owner != nil {
+			syms[i].Name = owner.Name + "." + syms[i].Name
+			if syms[i].Kind == KindFunc {
+				syms[i].Kind = KindMethod
+			}
+		}
+	}
+
+	return syms, nil
+}
+
+// blockEnd returns the index of the last line belonging to the declaration at start
+func (x *RegexExtractor) blockEnd(lines []string, start int) int {
+	if x.Blocks == BlockIndent {
+		indent := indentOf(lines[start])
+		end := start
+		for i := start + 1; i < len(lines); i++ {
+			if strings.TrimSpace(lines[i]) == "" {
+				continue
+			}
+			if indentOf(lines[i]) <= indent {
+				break
+			}
+			end = i
+		}
+		return end
+	}
+
+	depth := 0
+	opened := false
+	for i := start; i < len(lines); i++ {
+		for _, c := range lines[i] {
+			switch c {
+			case '{':
+				depth++
+				opened = true
+			case '}':
+				depth--
+			}
+		}
+		if opened && depth <= 0 {
+			return i
+		}
+		// A declaration without a body (e.g. a type alias or expression arrow
+		// function) ends on the first line that doesn't continue its signature
+		if !opened && !continuesSignature(lines[i]) {
+			return i
+		}
+	}
+	return start
+}
+
+func continuesSignature(line string) bool {
+	trimmed := strings.TrimSpace(line)
+	for _, suffix := range []string{"(", ",", "=>", "=", ":", "<"} {
+		if strings.HasSuffix(trimmed, suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+func indentOf(line string) int {
+	return len(line) - len(strings.TrimLeft(line, " \t"))
+}
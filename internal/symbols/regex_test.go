@@ -0,0 +1,170 @@
+package symbols
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const checkoutTS = `import { Cart } from "./cart";
+
+export class Checkout {
+  private total = 0;
+
+  constructor(private cart: Cart) {}
+
+  async pay(amount: number): Promise<void> {
+    if (amount > 0) {
+      this.total += amount;
+    }
+  }
+}
+
+export interface Receipt {
+  id: string;
+}
+
+export type Currency = "EUR" | "USD";
+
+export function refund(id: string) {
+  return id;
+}
+
+export const format = (n: number): string =>
+  n.toFixed(2);
+`
+
+const checkoutPy = `import os
+
+
+class Checkout:
+    rate = 1
+
+    def pay(self, amount):
+        if amount > 0:
+            return amount
+
+        return 0
+
+
+async def refund(order_id):
+    return order_id
+`
+
+const checkoutRs = `use std::fmt;
+
+pub struct Checkout {
+    total: u64,
+}
+
+impl Checkout {
+    pub fn pay(&mut self, amount: u64) {
+        self.total += amount;
+    }
+}
+
+impl fmt::Display for crate::Checkout {
+    fn fmt(&self, f: &mut fmt::Formatter) -> fmt::Result {
+        write!(f, "{}", self.total)
+    }
+}
+
+pub(crate) async fn refund(id: u64) -> u64 {
+    id
+}
+`
+
+const checkoutJava = `package shop;
+
+public final class Checkout {
+    private long total;
+
+    public void pay(long amount) {
+        if (amount > 0) {
+            total += amount;
+        }
+    }
+
+    static <T> List<T> items(List<T> all) {
+        return all;
+    }
+
+    record Receipt(String id) {}
+}
+`
+
+func TestRegexExtractors(t *testing.T) {
+	tests := []struct {
+		file   string
+		source string
+		want   []string // name kind line-end
+	}{
+		{
+			file:   "checkout.ts",
+			source: checkoutTS,
+			want: []string{
+				"Checkout type 3-13",
+				"Checkout.constructor method 6-6",
+				"Checkout.pay method 8-12",
+				"Receipt type 15-17",
+				"Currency type 19-19",
+				"refund func 21-23",
+				"format func 25-26",
+			},
+		},
+		{
+			file:   "checkout.py",
+			source: checkoutPy,
+			want: []string{
+				"Checkout type 4-11",
+				"Checkout.pay method 7-11",
+				"refund func 14-15",
+			},
+		},
+		{
+			file:   "checkout.rs",
+			source: checkoutRs,
+			want: []string{
+				"Checkout type 3-5",
+				"Checkout type 7-11",
+				"Checkout.pay method 8-10",
+				"Checkout type 13-17",
+				"Checkout.fmt method 14-16",
+				"refund func 19-21",
+			},
+		},
+		{
+			file:   "Checkout.java",
+			source: checkoutJava,
+			want: []string{
+				"Checkout type 3-17",
+				"Checkout.pay method 6-10",
+				"Checkout.items method 12-14",
+				"Checkout.Receipt type 16-16",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.file, func(t *testing.T) {
+			root := t.TempDir()
+			if err := os.WriteFile(filepath.Join(root, tt.file), []byte(tt.source), 0644); err != nil {
+				t.Fatal(err)
+			}
+			syms, err := ExtractorFor(tt.file).Extract(root, tt.file)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, s := range syms {
+				if s.File != tt.file {
+					t.Errorf("%s has file %q, want %q", s.Name, s.File, tt.file)
+				}
+				got = append(got, fmt.Sprintf("%s %s %d-%d", s.Name, s.Kind, s.Line, s.EndLine))
+			}
+			if fmt.Sprint(got) != fmt.Sprint(tt.want) {
+				t.Errorf("Extract() =\n%q\nwant\n%q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,59 @@
+package symbols
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Kind classifies a source symbol
+type Kind string
+
+const (
+	KindFunc   Kind = "func"
+	KindMethod Kind = "method"
+	KindType   Kind = "type"
+	KindVar    Kind = "var"
+	KindConst  Kind = "const"
+)
+
+// Symbol is a named declaration resolved to its location in the repository
+type Symbol struct {
+	Name    string `json:"name"`     // canonical name, e.g. billing.(*Invoice).Total
+	Kind    Kind   `json:"kind"`     // func, method, type, var, const
+	File    string `json:"file"`     // repo-relative, slash-separated
+	Line    int    `json:"line"`     // first line of the declaration
+	EndLine int    `json:"end_line"` // last line of the declaration
+}
+
+// skipDirs are never walked when scanning for source files
+var skipDirs = map[string]bool{
+	"vendor":       true,
+	"node_modules": true,
+	"testdata":     true,
+}
+
+// walkSource calls fn for every file under repoRoot with the given extension,
+// passing the repo-relative slash path. Hidden and vendored directories are skipped.
+func walkSource(repoRoot, ext string, fn func(rel string) error) error {
+	return filepath.WalkDir(repoRoot, func(path string, entry os.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		name := entry.Name()
+		if entry.IsDir() {
+			if path != repoRoot && (strings.HasPrefix(name, ".") || skipDirs[name]) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if filepath.Ext(name) != ext {
+			return nil
+		}
+		rel, err := filepath.Rel(repoRoot, path)
+		if err != nil {
+			return nil
+		}
+		return fn(filepath.ToSlash(rel))
+	})
+}
@@ -28,6 +28,39 @@ const (
 type DecidedBy struct {
 	Role       string  `json:"role"`                 // "human" or "agent"
 	Identifier *string `json:"identifier,omitempty"` // email, agent name, etc.
+	Session    *string `json:"session,omitempty"`    // agent session or run ID
+}
+
+// Approval records a sign-off on a decision
+type Approval struct {
+	By         string `json:"by"`
+	ApprovedAt string `json:"approved_at"`
+}
+
+// Anchor ties a decision to a line range of a file. The fingerprint and
+// per-line hashes identify the anchored code so it can be found again after edits.
+type Anchor struct {
+	File        string   `json:"file"`
+	StartLine   int      `json:"start_line"`
+	EndLine     int      `json:"end_line"`
+	Fingerprint string   `json:"fingerprint"`
+	LineHashes  []string `json:"line_hashes,omitempty"`
+}
+
+// String formats the anchor as file:start-end
+func (a Anchor) String() string {
+	if a.StartLine == a.EndLine {
+		return fmt.Sprintf("%s:%d", a.File, a.StartLine)
+	}
+	return fmt.Sprintf("%s:%d-%d", a.File, a.StartLine, a.EndLine)
+}
+
+// Annotation is a keel:DEC-xxxx marker found in a source comment.
+// Annotations are read from the working tree, not the ledger.
+type Annotation struct {
+	DecisionID string `json:"decision_id"`
+	File       string `json:"file"`
+	Line       int    `json:"line"`
 }
 
 // Decision represents a recorded decision in the ledger
@@ -42,12 +75,24 @@ type Decision struct {
 	DecidedBy       DecidedBy      `json:"decided_by"`
 	Files           []string       `json:"files,omitempty"`
 	Symbols         []string       `json:"symbols,omitempty"`
+	Anchors         []Anchor       `json:"anchors,omitempty"`
 	Refs            []string       `json:"refs,omitempty"`
 	Status          DecisionStatus `json:"status"`
 	SupersededBy    *string        `json:"superseded_by,omitempty"`
 	Supersedes      *string        `json:"supersedes,omitempty"`
 	Hypothesis      *string        `json:"hypothesis,omitempty"`
 	SuccessCriteria *string        `json:"success_criteria,omitempty"`
+	ReviewBy        *string        `json:"review_by,omitempty"`
+	ExpiresAt       *string        `json:"expires_at,omitempty"`
+	Approvals       []Approval     `json:"approvals,omitempty"`
+
+	// Ledger is the repo-relative directory of the ledger holding the
+	// decision ("." for the root). The index fills it in; it is not stored.
+	Ledger string `json:"ledger,omitempty"`
+
+	// Upstream names the upstream repository a read-only decision was
+	// indexed from; its ID carries the same name (platform:DEC-a1b2)
+	Upstream string `json:"upstream,omitempty"`
 }
 
 // DecisionInput represents the input for creating a new decision
@@ -60,22 +105,66 @@ type DecisionInput struct {
 	DecidedBy       *DecidedBy   `json:"decided_by,omitempty"`
 	Files           []string     `json:"files,omitempty"`
 	Symbols         []string     `json:"symbols,omitempty"`
+	Anchors         []Anchor     `json:"anchors,omitempty"`
 	Refs            []string     `json:"refs,omitempty"`
 	Hypothesis      *string      `json:"hypothesis,omitempty"`
 	SuccessCriteria *string      `json:"success_criteria,omitempty"`
 	Supersedes      *string      `json:"supersedes,omitempty"`
+	ReviewBy        *string      `json:"review_by,omitempty"`
+	ExpiresAt       *string      `json:"expires_at,omitempty"`
 }
 
-// ValidDecisionTypes returns all valid decision types
-func ValidDecisionTypes() []DecisionType {
+// BuiltinTypes returns the decision types every repository supports
+func BuiltinTypes() []DecisionType {
 	return []DecisionType{TypeProduct, TypeProcess, TypeConstraint, TypeLearning}
 }
 
-// IsValidType checks if a string is a valid decision type
-func IsValidType(t string) bool {
-	switch DecisionType(t) {
-	case TypeProduct, TypeProcess, TypeConstraint, TypeLearning:
-		return true
+// ParseDate parses a review or expiry date.
+// Accepts a plain date (2006-01-02) or a full RFC3339 timestamp.
+func ParseDate(s string) (time.Time, error) {
+	if t, err := time.Parse("2006-01-02", s); err == nil {
+		return t.UTC(), nil
+	}
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid date: %s. Expected YYYY-MM-DD or RFC3339", s)
+	}
+	return t.UTC(), nil
+}
+
+// FormatDate formats a review or expiry date for storage.
+// Dates are stored in UTC so they compare correctly as strings in the index.
+func FormatDate(t time.Time) string {
+	return t.UTC().Format(time.RFC3339)
+}
+
+// IsExpired reports whether the decision's expiry date has passed
+func (d *Decision) IsExpired(now time.Time) bool {
+	return datePassed(d.ExpiresAt, now)
+}
+
+// IsReviewDue reports whether the decision's review date has passed
+func (d *Decision) IsReviewDue(now time.Time) bool {
+	return datePassed(d.ReviewBy, now)
+}
+
+func datePassed(date *string, now time.Time) bool {
+	if date == nil || *date == "" {
+		return false
+	}
+	t, err := ParseDate(*date)
+	if err != nil {
+		return false
+	}
+	return !now.Before(t)
+}
+
+// HasApprovalFrom reports whether the identifier has already approved the decision
+func (d *Decision) HasApprovalFrom(identifier string) bool {
+	for _, a := range d.Approvals {
+		if a.By == identifier {
+			return true
+		}
 	}
 	return false
 }
@@ -112,10 +201,13 @@ func NewDecision(id string, input DecisionInput) *Decision {
 		DecidedBy:       decidedBy,
 		Files:           input.Files,
 		Symbols:         input.Symbols,
+		Anchors:         input.Anchors,
 		Refs:            input.Refs,
 		Status:          StatusActive,
 		Supersedes:      input.Supersedes,
 		Hypothesis:      input.Hypothesis,
 		SuccessCriteria: input.SuccessCriteria,
+		ReviewBy:        input.ReviewBy,
+		ExpiresAt:       input.ExpiresAt,
 	}
 }
@@ -0,0 +1,69 @@
+package types
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseDate(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    string
+		wantErr bool
+	}{
+		{in: "2027-03-01", want: "2027-03-01T00:00:00Z"},
+		{in: "2027-03-01T09:30:00Z", want: "2027-03-01T09:30:00Z"},
+		{in: "2027-03-01T09:30:00+02:00", want: "2027-03-01T07:30:00Z"},
+		{in: "01/03/2027", wantErr: true},
+		{in: "2027-02-30", wantErr: true},
+		{in: "", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, err := ParseDate(tt.in)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("ParseDate(%q) = %v, want an error", tt.in, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if FormatDate(got) != tt.want {
+				t.Errorf("ParseDate(%q) = %s, want %s", tt.in, FormatDate(got), tt.want)
+			}
+		})
+	}
+}
+
+func TestDatesPassed(t *testing.T) {
+	now := time.Date(2027, 3, 1, 12, 0, 0, 0, time.UTC)
+	date := func(s string) *string { return &s }
+
+	tests := []struct {
+		name string
+		date *string
+		want bool
+	}{
+		{name: "unset", date: nil, want: false},
+		{name: "empty", date: date(""), want: false},
+		{name: "yesterday", date: date("2027-02-28"), want: true},
+		{name: "today", date: date("2027-03-01"), want: true},
+		{name: "exactly now", date: date("2027-03-01T12:00:00Z"), want: true},
+		{name: "later today", date: date("2027-03-01T12:00:01Z"), want: false},
+		{name: "tomorrow", date: date("2027-03-02"), want: false},
+		{name: "unparseable", date: date("soon"), want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := &Decision{ExpiresAt: tt.date, ReviewBy: tt.date}
+			if got := d.IsExpired(now); got != tt.want {
+				t.Errorf("IsExpired() = %v, want %v", got, tt.want)
+			}
+			if got := d.IsReviewDue(now); got != tt.want {
+				t.Errorf("IsReviewDue() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
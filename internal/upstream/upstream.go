@@ -0,0 +1,194 @@
+// Package upstream fetches decision ledgers from other repositories, such
+// as a platform repository's org-wide constraints, into .keel/upstream/.
+// The index reads the cached copies read-only under the upstream's name.
+package upstream
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/tyroneavnit/keel/internal/git"
+	"github.com/tyroneavnit/keel/internal/store"
+)
+
+// DirName is the directory under .keel holding upstream ledgers
+const DirName = "upstream"
+
+// DefaultPath is where a ledger lives in an upstream repository
+const DefaultPath = ".keel/decisions.jsonl"
+
+// Source says where an upstream ledger comes from
+type Source struct {
+	URL  string // local path (relative to the repository root) or git remote
+	Ref  string // branch, tag or commit; default HEAD
+	Path string // ledger file in the repository; default .keel/decisions.jsonl
+}
+
+// Result describes a synced upstream
+type Result struct {
+	Name      string `json:"name"`
+	Commit    string `json:"commit,omitempty"` // empty when copied from a plain directory
+	Decisions int    `json:"decisions"`
+}
+
+// Dir returns the directory caching upstream ledgers
+func Dir(repoRoot string) string {
+	return filepath.Join(store.GetKeelDir(repoRoot), DirName)
+}
+
+// LedgerPath returns the cached ledger of an upstream
+func LedgerPath(repoRoot, name string) string {
+	return filepath.Join(Dir(repoRoot), name+".jsonl")
+}
+
+// Names lists the upstreams with a cached ledger
+func Names(repoRoot string) ([]string, error) {
+	entries, err := os.ReadDir(Dir(repoRoot))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, e := range entries {
+		if name, ok := strings.CutSuffix(e.Name(), ".jsonl"); ok && !e.IsDir() {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// Sync fetches an upstream's ledger into the cache. A local path is read
+// in place, from the ref in its git history, or as a plain file outside git.
+// A remote is fetched into a bare repository kept beside the cached ledger.
+func Sync(repoRoot, name string, src Source) (*Result, error) {
+	ledgerPath := src.Path
+	if ledgerPath == "" {
+		ledgerPath = DefaultPath
+	}
+	ref := src.Ref
+	if ref == "" {
+		ref = "HEAD"
+	}
+
+	var content []byte
+	var commit string
+	var err error
+	if local := localPath(repoRoot, src.URL); local != "" {
+		content, commit, err = readLocal(local, ref, ledgerPath)
+	} else {
+		content, commit, err = fetchRemote(repoRoot, name, src.URL, ref, ledgerPath)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("upstream %s: %w", name, err)
+	}
+
+	if err := os.MkdirAll(Dir(repoRoot), 0755); err != nil {
+		return nil, err
+	}
+	path := LedgerPath(repoRoot, name)
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, content, 0644); err != nil {
+		return nil, err
+	}
+	state, err := store.GetLatestStateFile(tmp)
+	if err != nil {
+		os.Remove(tmp)
+		return nil, fmt.Errorf("upstream %s: %w", name, err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return nil, err
+	}
+	return &Result{Name: name, Commit: commit, Decisions: len(state)}, nil
+}
+
+// Prune removes cached upstreams that are no longer configured
+func Prune(repoRoot string, keep []string) error {
+	kept := make(map[string]bool, len(keep))
+	for _, name := range keep {
+		kept[name] = true
+	}
+	names, err := Names(repoRoot)
+	if err != nil {
+		return err
+	}
+	for _, name := range names {
+		if kept[name] {
+			continue
+		}
+		if err := os.Remove(LedgerPath(repoRoot, name)); err != nil {
+			return err
+		}
+		os.RemoveAll(filepath.Join(Dir(repoRoot), name+".git"))
+	}
+	return nil
+}
+
+// remotePattern matches URLs git fetches over the network, including the
+// scp-like user@host:path form
+var remotePattern = regexp.MustCompile(`^[a-z][a-z0-9+.-]*://|^[^/:]+@[^/:]+:`)
+
+// localPath returns the absolute directory a local URL names, or "" for a remote
+func localPath(repoRoot, url string) string {
+	if remotePattern.MatchString(url) {
+		return ""
+	}
+	if !filepath.IsAbs(url) {
+		url = filepath.Join(repoRoot, url)
+	}
+	return url
+}
+
+// readLocal reads a ledger from a local clone, bare or not, at ref. A plain
+// directory outside git is read as it is.
+func readLocal(dir, ref, ledgerPath string) ([]byte, string, error) {
+	if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+		return nil, "", fmt.Errorf("%s is not a directory", dir)
+	}
+	if _, err := git.Run(dir, "rev-parse", "--git-dir"); err != nil {
+		content, err := os.ReadFile(filepath.Join(dir, filepath.FromSlash(ledgerPath)))
+		return content, "", err
+	}
+	return show(dir, ref, ledgerPath)
+}
+
+// fetchRemote fetches ref from a remote into the upstream's cached bare
+// repository and reads the ledger from it
+func fetchRemote(repoRoot, name, url, ref, ledgerPath string) ([]byte, string, error) {
+	cache := filepath.Join(Dir(repoRoot), name+".git")
+	if _, err := os.Stat(cache); os.IsNotExist(err) {
+		if err := os.MkdirAll(cache, 0755); err != nil {
+			return nil, "", err
+		}
+		if _, err := git.Run(cache, "init", "--bare", "--quiet"); err != nil {
+			return nil, "", err
+		}
+	}
+	if _, err := git.Run(cache, "fetch", "--quiet", "--depth", "1", "--end-of-options", url, ref); err != nil {
+		return nil, "", err
+	}
+	return show(cache, "FETCH_HEAD", ledgerPath)
+}
+
+// show reads a file at a ref of the repository in dir
+func show(dir, ref, ledgerPath string) ([]byte, string, error) {
+	commit, err := git.Run(dir, "rev-parse", "--verify", "--quiet", "--end-of-options", ref+"^{commit}")
+	if err != nil || commit == "" {
+		return nil, "", fmt.Errorf("unknown ref %s", ref)
+	}
+	content, err := git.Run(dir, "show", "--end-of-options", commit+":"+ledgerPath)
+	if err != nil {
+		return nil, "", fmt.Errorf("no %s at %s", ledgerPath, ref)
+	}
+	if content != "" {
+		content += "\n"
+	}
+	return []byte(content), commit, nil
+}
@@ -0,0 +1,169 @@
+package upstream
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/tyroneavnit/keel/internal/git"
+	"github.com/tyroneavnit/keel/internal/store"
+)
+
+const (
+	firstDecision  = `{"id":"DEC-0001","created_at":"2026-01-01T00:00:00Z","type":"constraint","problem":"p","choice":"Use Postgres","decided_by":{"role":"human"},"status":"active"}`
+	secondDecision = `{"id":"DEC-0002","created_at":"2026-01-02T00:00:00Z","type":"constraint","problem":"p","choice":"Encrypt at rest","decided_by":{"role":"human"},"status":"active"}`
+)
+
+func runGit(t *testing.T, dir string, args ...string) string {
+	t.Helper()
+	out, err := git.Run(dir, append([]string{"-c", "user.name=Platform", "-c", "user.email=platform@example.com"}, args...)...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return out
+}
+
+// platformRepo creates a bare repository whose main branch records one
+// decision at tag v1 and two at its tip, as a team would push them
+func platformRepo(t *testing.T) (bare, v1, tip string) {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+
+	work := t.TempDir()
+	runGit(t, work, "init", "--quiet", "--initial-branch", "main")
+	ledger := filepath.Join(work, filepath.FromSlash(DefaultPath))
+	if err := os.MkdirAll(filepath.Dir(ledger), 0755); err != nil {
+		t.Fatal(err)
+	}
+	for i, line := range []string{firstDecision, secondDecision} {
+		f, err := os.OpenFile(ledger, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+		f.WriteString(line + "\n")
+		f.Close()
+		runGit(t, work, "add", ".")
+		runGit(t, work, "commit", "--quiet", "-m", "Record decision")
+		if i == 0 {
+			runGit(t, work, "tag", "v1")
+		}
+	}
+
+	bare = filepath.Join(t.TempDir(), "platform.git")
+	runGit(t, work, "clone", "--quiet", "--bare", work, bare)
+	return bare, runGit(t, bare, "rev-parse", "v1^{commit}"), runGit(t, bare, "rev-parse", "main")
+}
+
+func TestSync(t *testing.T) {
+	bare, v1, tip := platformRepo(t)
+
+	plain := t.TempDir()
+	if err := os.WriteFile(filepath.Join(plain, "constraints.jsonl"), []byte(firstDecision+"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// A ref that git would read as an option runs a command if passed unguarded
+	pwned := filepath.Join(t.TempDir(), "pwned")
+	injected := "--upload-pack=touch " + pwned + "; git-upload-pack"
+
+	tests := []struct {
+		name       string
+		src        Source
+		wantCommit string
+		wantCount  int
+		wantErr    string
+	}{
+		{name: "local bare repository", src: Source{URL: bare}, wantCommit: tip, wantCount: 2},
+		{name: "local tag", src: Source{URL: bare, Ref: "v1"}, wantCommit: v1, wantCount: 1},
+		{name: "remote URL", src: Source{URL: "file://" + filepath.ToSlash(bare), Ref: "main"}, wantCommit: tip, wantCount: 2},
+		{name: "remote tag", src: Source{URL: "file://" + filepath.ToSlash(bare), Ref: "v1"}, wantCommit: v1, wantCount: 1},
+		{name: "plain directory", src: Source{URL: plain, Path: "constraints.jsonl"}, wantCount: 1},
+		{name: "unknown ref", src: Source{URL: bare, Ref: "v9"}, wantErr: "unknown ref v9"},
+		{name: "no ledger", src: Source{URL: bare, Path: "docs/decisions.jsonl"}, wantErr: "no docs/decisions.jsonl at HEAD"},
+		{name: "missing directory", src: Source{URL: "missing"}, wantErr: "is not a directory"},
+		{name: "option as remote ref", src: Source{URL: "file://" + filepath.ToSlash(bare), Ref: injected}, wantErr: "git fetch"},
+		{name: "option as local ref", src: Source{URL: bare, Ref: injected}, wantErr: "unknown ref"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := t.TempDir()
+			if err := store.EnsureKeelDir(root); err != nil {
+				t.Fatal(err)
+			}
+
+			res, err := Sync(root, "platform", tt.src)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Sync() err = %v, want %q", err, tt.wantErr)
+				}
+				if _, err := os.Stat(LedgerPath(root, "platform")); !os.IsNotExist(err) {
+					t.Errorf("failed sync left a cached ledger")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			want := &Result{Name: "platform", Commit: tt.wantCommit, Decisions: tt.wantCount}
+			if !reflect.DeepEqual(res, want) {
+				t.Errorf("Sync() = %+v, want %+v", res, want)
+			}
+
+			state, err := store.GetLatestStateFile(LedgerPath(root, "platform"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(state) != tt.wantCount {
+				t.Errorf("cached ledger has %d decisions, want %d", len(state), tt.wantCount)
+			}
+		})
+	}
+
+	if _, err := os.Stat(pwned); !os.IsNotExist(err) {
+		t.Errorf("a ref was run as a git option")
+	}
+}
+
+func TestSyncAgainAndPrune(t *testing.T) {
+	bare, _, tip := platformRepo(t)
+	root := t.TempDir()
+	if err := store.EnsureKeelDir(root); err != nil {
+		t.Fatal(err)
+	}
+	remote := Source{URL: "file://" + filepath.ToSlash(bare), Ref: "v1"}
+
+	for _, name := range []string{"platform", "security"} {
+		if _, err := Sync(root, name, remote); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// A second sync reuses the cached bare repository and moves to the new ref
+	remote.Ref = "main"
+	res, err := Sync(root, "platform", remote)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Commit != tip || res.Decisions != 2 {
+		t.Errorf("second Sync() = %+v, want commit %s and 2 decisions", res, tip)
+	}
+
+	if err := Prune(root, []string{"platform"}); err != nil {
+		t.Fatal(err)
+	}
+	names, err := Names(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(names, []string{"platform"}) {
+		t.Errorf("Names() = %v, want [platform]", names)
+	}
+	if _, err := os.Stat(filepath.Join(Dir(root), "security.git")); !os.IsNotExist(err) {
+		t.Errorf("pruned upstream kept its bare repository")
+	}
+}
@@ -0,0 +1,213 @@
+package keel
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/tyroneavnit/keel/internal/anchor"
+	"github.com/tyroneavnit/keel/internal/query"
+	"github.com/tyroneavnit/keel/internal/scan"
+	"github.com/tyroneavnit/keel/internal/store"
+	"github.com/tyroneavnit/keel/internal/types"
+)
+
+// ScanResult holds the keel: markers found in source comments and the
+// markers that point at unknown or superseded decisions
+type ScanResult struct {
+	Files       int               `json:"files_scanned"`
+	Annotations []Annotation      `json:"annotations"`
+	Issues      []AnnotationIssue `json:"issues"`
+}
+
+// AnnotationIssue is a marker that no longer points at a decision in force
+type AnnotationIssue struct {
+	DecisionID string `json:"decision_id"`
+	FilePath   string `json:"file_path"`
+	Line       int    `json:"line"`
+	Issue      string `json:"issue"`
+}
+
+// ValidationIssue is a file, symbol or anchor a decision links to that no
+// longer matches the working tree
+type ValidationIssue struct {
+	DecisionID string `json:"decision_id"`
+	FilePath   string `json:"file_path,omitempty"`
+	Symbol     string `json:"symbol,omitempty"`
+	Anchor     string `json:"anchor,omitempty"`
+	Issue      string `json:"issue"`
+	Fixed      bool   `json:"fixed,omitempty"`
+}
+
+// Due returns active decisions whose review or expiry date is on or before
+// at, soonest first
+func (l *Ledger) Due(ctx context.Context, at time.Time) ([]*Decision, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return query.Due(l.db, at)
+}
+
+// Scan finds keel: markers in the repository's source comments and indexes
+// them, replacing those from the previous scan
+func (l *Ledger) Scan(ctx context.Context) (*ScanResult, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if err := l.writable(); err != nil {
+		return nil, err
+	}
+
+	found, err := scan.Repo(l.root, l.ids)
+	if err != nil {
+		return nil, err
+	}
+	if err := l.db.ReplaceAnnotations(found.Annotations); err != nil {
+		return nil, fmt.Errorf("failed to index annotations: %w", err)
+	}
+
+	result := &ScanResult{Files: found.Files, Annotations: found.Annotations}
+	for _, a := range found.Annotations {
+		d, err := query.ByID(l.db, a.DecisionID)
+		if err != nil {
+			return nil, err
+		}
+		issue := AnnotationIssue{DecisionID: a.DecisionID, FilePath: a.File, Line: a.Line}
+		switch {
+		case d == nil:
+			issue.Issue = "unknown decision"
+		case d.Status == types.StatusSuperseded && d.SupersededBy != nil:
+			issue.Issue = fmt.Sprintf("superseded by %s", *d.SupersededBy)
+		case d.Status == types.StatusSuperseded:
+			issue.Issue = "superseded"
+		default:
+			continue
+		}
+		result.Issues = append(result.Issues, issue)
+	}
+	return result, nil
+}
+
+// Validate checks the files, Go symbols and anchors of this repository's
+// active decisions against the working tree. Anchored code that moved or
+// was edited is located again by fuzzy matching; with fix the relocated
+// anchors are recorded in the ledger and their issues marked fixed.
+func (l *Ledger) Validate(ctx context.Context, fix bool) ([]ValidationIssue, error) {
+	decisions, err := l.List(ctx, Filter{Status: string(types.StatusActive), Local: true})
+	if err != nil {
+		return nil, err
+	}
+
+	var issues []ValidationIssue
+	for _, d := range decisions {
+		for _, file := range d.Files {
+			if _, err := os.Stat(filepath.Join(l.root, file)); os.IsNotExist(err) {
+				issues = append(issues, ValidationIssue{DecisionID: d.ID, FilePath: file, Issue: "file not found"})
+			}
+		}
+	}
+
+	for _, d := range decisions {
+		_, missing, err := l.goSymbols.Resolve(d.Symbols)
+		if err != nil {
+			return nil, err
+		}
+		for _, symbol := range missing {
+			issues = append(issues, ValidationIssue{DecisionID: d.ID, Symbol: symbol, Issue: "symbol not found"})
+		}
+	}
+
+	for _, d := range decisions {
+		if len(d.Anchors) == 0 {
+			continue
+		}
+		var anchorIssues []ValidationIssue
+		if fix {
+			anchorIssues, err = l.relocateAnchors(d)
+		} else {
+			anchorIssues, _, err = l.checkAnchors(d, false)
+		}
+		if err != nil {
+			return nil, err
+		}
+		issues = append(issues, anchorIssues...)
+	}
+	return issues, nil
+}
+
+// relocateAnchors checks a decision's anchors under the ledger lock and
+// records any that moved. The decision is read again under the lock so an
+// update written since it was listed is not undone.
+func (l *Ledger) relocateAnchors(listed *Decision) ([]ValidationIssue, error) {
+	unlock, err := l.lock(listed.Ledger)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	d, err := store.GetDecisionByID(listed.ID, store.LedgerDir(l.root, listed.Ledger))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read decision: %w", err)
+	}
+	if d == nil || d.Status != types.StatusActive {
+		return nil, nil
+	}
+	d.Ledger = listed.Ledger
+
+	issues, relocated, err := l.checkAnchors(d, true)
+	if err != nil || relocated == nil {
+		return issues, err
+	}
+	d.Anchors = relocated
+	if err := store.AppendDecision(d, store.LedgerDir(l.root, d.Ledger)); err != nil {
+		return nil, fmt.Errorf("failed to update anchors: %w", err)
+	}
+	if err := l.db.IndexDecision(d); err != nil {
+		return nil, fmt.Errorf("failed to index decision: %w", err)
+	}
+	return issues, nil
+}
+
+// checkAnchors compares a decision's anchors with the working tree. When any
+// anchor moved or drifted it also returns the full relocated anchor list,
+// and those issues are marked fixed when they are about to be recorded.
+func (l *Ledger) checkAnchors(d *Decision, fixing bool) ([]ValidationIssue, []types.Anchor, error) {
+	var issues []ValidationIssue
+	relocated := make([]types.Anchor, 0, len(d.Anchors))
+	changed := false
+
+	for _, a := range d.Anchors {
+		result, err := anchor.Check(l.root, a)
+		if err != nil {
+			return nil, nil, err
+		}
+		relocated = append(relocated, result.Anchor)
+
+		issue := ValidationIssue{DecisionID: d.ID, Anchor: a.String()}
+		switch result.Status {
+		case anchor.StatusOK:
+			continue
+		case anchor.StatusMoved:
+			issue.Issue = fmt.Sprintf("anchored code moved to lines %d-%d", result.Anchor.StartLine, result.Anchor.EndLine)
+			issue.Fixed = fixing
+			changed = true
+		case anchor.StatusDrifted:
+			issue.Issue = fmt.Sprintf("anchored code changed (%.0f%% similar), closest match at lines %d-%d",
+				result.Similarity*100, result.Anchor.StartLine, result.Anchor.EndLine)
+			issue.Fixed = fixing
+			changed = true
+		case anchor.StatusLost:
+			issue.Issue = "anchored code not found"
+		case anchor.StatusMissing:
+			issue.Issue = "anchored file not found"
+		}
+		issues = append(issues, issue)
+	}
+
+	if !changed {
+		return issues, nil, nil
+	}
+	return issues, relocated, nil
+}
@@ -0,0 +1,251 @@
+package keel
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/tyroneavnit/keel/internal/errs"
+	"github.com/tyroneavnit/keel/internal/prompt"
+	"github.com/tyroneavnit/keel/internal/query"
+	"github.com/tyroneavnit/keel/internal/store"
+	"github.com/tyroneavnit/keel/internal/symbols"
+	"github.com/tyroneavnit/keel/internal/types"
+)
+
+// ContextRequest describes a context lookup. SymbolAt takes precedence over
+// Ref, which takes precedence over Paths. A path is a repo-relative file,
+// file:line, or a symbol name.
+type ContextRequest struct {
+	Paths    []string
+	Ref      string
+	SymbolAt string // file:line
+	Author   string // only decisions made by this identifier
+	Follow   bool   // with Ref, replace superseded decisions with the heads of their chains
+	Batch    bool   // report matched paths per decision even for a single path
+}
+
+// ContextResult holds the decisions that apply to a lookup. Decisions and
+// constraints still awaiting sign-off are held back in Pending.
+type ContextResult struct {
+	Title       string
+	Batch       bool
+	Paths       []string
+	Decisions   []*Decision
+	Constraints []*Decision
+	Pending     []*Decision
+	Matched     map[string][]string // decision ID -> requested paths it matched
+
+	targets []prompt.Target
+}
+
+// Context looks up the decisions and active constraints that apply to files,
+// a line's enclosing symbol, or an external reference. With nested ledgers,
+// a file gets decisions only from the ledgers on its path from the root;
+// a ref or an empty lookup gets the constraints of the ledger decisions are
+// written to and of those above it.
+func (l *Ledger) Context(ctx context.Context, req ContextRequest) (*ContextResult, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	res := &ContextResult{Matched: make(map[string][]string)}
+
+	if req.SymbolAt != "" {
+		// Query by the function, method or type enclosing a line
+		file, line, err := ParseFileLine(req.SymbolAt)
+		if err != nil {
+			return nil, err
+		}
+		res.Title = req.SymbolAt
+		symbolName := ""
+		sym, err := symbols.SymbolAt(l.root, file, line)
+		if err != nil {
+			return nil, err
+		}
+		if sym != nil {
+			symbolName = sym.Name
+			res.Title = fmt.Sprintf("%s (%s)", req.SymbolAt, sym.Name)
+		}
+		res.targets = []prompt.Target{{File: file, Line: line, Symbol: symbolName}}
+		result, err := query.ForSymbolAt(l.db, symbolName, file, line)
+		if err != nil {
+			return nil, err
+		}
+		res.Decisions = inScope(result.Decisions, file)
+		res.Constraints = inScope(result.Constraints, file)
+	} else if req.Ref != "" {
+		// Query by ref
+		res.Title = fmt.Sprintf("ref:%s", req.Ref)
+		res.targets = []prompt.Target{{Ref: req.Ref}}
+		lookup := query.ByRef
+		if req.Follow {
+			lookup = query.HeadsByRef
+		}
+		var err error
+		res.Decisions, err = lookup(l.db, req.Ref)
+		if err != nil {
+			return nil, err
+		}
+		constraints, err := query.ActiveConstraints(l.db)
+		if err != nil {
+			return nil, err
+		}
+		res.Constraints = inScope(constraints, l.ledger)
+	} else {
+		// Query by file path, or by file:line to rank anchored decisions first.
+		// Each decision is kept once, with the paths it matched.
+		paths := uniquePaths(req.Paths)
+		res.Paths = paths
+		res.Batch = req.Batch || len(paths) > 1
+		res.Title = fmt.Sprintf("%d files", len(paths))
+		if len(paths) == 1 {
+			res.Title = paths[0]
+		}
+		for _, path := range paths {
+			result, target, err := l.contextForPath(path)
+			if err != nil {
+				return nil, err
+			}
+			res.targets = append(res.targets, target)
+			scope := target.File
+			if scope == "" {
+				scope = l.ledger
+			}
+			for _, d := range inScope(result.Decisions, scope) {
+				if _, ok := res.Matched[d.ID]; !ok {
+					res.Decisions = append(res.Decisions, d)
+				}
+				res.Matched[d.ID] = append(res.Matched[d.ID], path)
+			}
+			for _, c := range inScope(result.Constraints, scope) {
+				if !containsDecision(res.Constraints, c.ID) {
+					res.Constraints = append(res.Constraints, c)
+				}
+			}
+		}
+		if len(paths) == 0 {
+			constraints, err := query.ActiveConstraints(l.db)
+			if err != nil {
+				return nil, err
+			}
+			res.Constraints = inScope(constraints, l.ledger)
+		}
+	}
+
+	res.Decisions = query.FilterByAuthor(res.Decisions, req.Author)
+
+	// Decisions still awaiting sign-off are reported separately
+	pol := l.cfg.Approvals()
+	decisions, pendingDecisions := pol.Split(res.Decisions)
+	constraints, pendingConstraints := pol.Split(res.Constraints)
+	res.Decisions, res.Constraints = decisions, constraints
+	res.Pending = pendingDecisions
+	for _, c := range pendingConstraints {
+		if !containsDecision(res.Pending, c.ID) {
+			res.Pending = append(res.Pending, c)
+		}
+	}
+
+	return res, nil
+}
+
+// Prompt renders the result as ranked Markdown for a model's context,
+// trimmed to roughly budget tokens (0 = unlimited)
+func (r *ContextResult) Prompt(budget int) string {
+	entries := prompt.Rank(r.Decisions, r.Constraints, r.targets, time.Now())
+	if r.Batch {
+		for i := range entries {
+			entries[i].Files = r.Matched[entries[i].Decision.ID]
+		}
+	}
+	return prompt.Render(r.Title, entries, budget)
+}
+
+// contextForPath looks up decisions for a file path or file:line. When
+// nothing is linked to the path it is tried as a symbol, including the file
+// that declares it when it resolves in the Go sources.
+func (l *Ledger) contextForPath(path string) (*query.ContextResult, prompt.Target, error) {
+	target := prompt.Target{File: path}
+	var result *query.ContextResult
+	var err error
+	if file, line, lineErr := ParseFileLine(path); lineErr == nil {
+		target = prompt.Target{File: file, Line: line}
+		result, err = query.ForLine(l.db, file, line)
+	} else {
+		result, err = query.ForContext(l.db, path)
+	}
+	if err != nil {
+		return nil, target, err
+	}
+	if len(result.Decisions) > 0 {
+		return result, target, nil
+	}
+
+	symbolName, symbolFile := path, ""
+	sym, err := l.goSymbols.Lookup(path)
+	if err != nil {
+		return nil, target, err
+	}
+	if sym != nil {
+		symbolName, symbolFile = sym.Name, sym.File
+	}
+	symbolResult, err := query.ForSymbol(l.db, symbolName, symbolFile)
+	if err != nil {
+		return nil, target, err
+	}
+	if len(symbolResult.Decisions) == 0 {
+		return result, target, nil
+	}
+	return symbolResult, prompt.Target{File: symbolFile, Symbol: symbolName}, nil
+}
+
+// ParseFileLine splits a file:line location
+func ParseFileLine(s string) (string, int, error) {
+	i := strings.LastIndex(s, ":")
+	if i < 0 {
+		return "", 0, errs.New(errs.Usage, "invalid location: %s. Expected file:line", s)
+	}
+	line, err := strconv.Atoi(s[i+1:])
+	if err != nil || line < 1 {
+		return "", 0, errs.New(errs.Usage, "invalid line number in %s", s)
+	}
+	return s[:i], line, nil
+}
+
+// uniquePaths cleans paths and drops duplicates, keeping their order
+func uniquePaths(paths []string) []string {
+	seen := make(map[string]bool, len(paths))
+	unique := make([]string, 0, len(paths))
+	for _, p := range paths {
+		p = filepath.ToSlash(filepath.Clean(p))
+		if !seen[p] {
+			seen[p] = true
+			unique = append(unique, p)
+		}
+	}
+	return unique
+}
+
+// inScope keeps the decisions from ledgers that apply to a repo-relative
+// path: the root ledger and those nested on the way to it
+func inScope(decisions []*types.Decision, path string) []*types.Decision {
+	var kept []*types.Decision
+	for _, d := range decisions {
+		if store.LedgerCovers(d.Ledger, path) {
+			kept = append(kept, d)
+		}
+	}
+	return kept
+}
+
+func containsDecision(decisions []*types.Decision, id string) bool {
+	for _, d := range decisions {
+		if d.ID == id {
+			return true
+		}
+	}
+	return false
+}
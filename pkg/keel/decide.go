@@ -0,0 +1,553 @@
+package keel
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/tyroneavnit/keel/internal/anchor"
+	"github.com/tyroneavnit/keel/internal/errs"
+	"github.com/tyroneavnit/keel/internal/identity"
+	"github.com/tyroneavnit/keel/internal/query"
+	"github.com/tyroneavnit/keel/internal/store"
+	"github.com/tyroneavnit/keel/internal/symbols"
+	"github.com/tyroneavnit/keel/internal/types"
+)
+
+// DecisionRequest holds the fields of a new decision, as given to keel
+// decide, an MCP tool call or an HTTP request body. Dates are YYYY-MM-DD
+// or RFC3339.
+type DecisionRequest struct {
+	Type          string   `json:"type"`
+	Problem       string   `json:"problem"`
+	Choice        string   `json:"choice"`
+	Rationale     string   `json:"rationale"`
+	Files         []string `json:"files"`
+	Symbols       []string `json:"symbols"`
+	Anchors       []string `json:"anchors"` // file:start-end
+	Refs          []string `json:"refs"`
+	Supersedes    string   `json:"supersedes"`
+	ReviewBy      string   `json:"review_by"`
+	ExpiresAt     string   `json:"expires_at"`
+	Agent         bool     `json:"agent"`
+	As            string   `json:"as"` // defaults to $KEEL_AGENT or git user.email
+	NoSymbolCheck bool     `json:"-"`  // record Go symbols without resolving them
+}
+
+// Amendment changes an active decision in place, for corrections that don't
+// warrant superseding it. Empty fields are left as they are; a list replaces
+// the decision's list.
+type Amendment struct {
+	Rationale     string   `json:"rationale"`
+	Files         []string `json:"files"`
+	Symbols       []string `json:"symbols"`
+	Anchors       []string `json:"anchors"` // file:start-end
+	Refs          []string `json:"refs"`
+	ReviewBy      string   `json:"review_by"`
+	ExpiresAt     string   `json:"expires_at"`
+	NoSymbolCheck bool     `json:"-"`
+}
+
+// Decide records a new decision. A decision that supersedes another also
+// marks the old one superseded.
+func (l *Ledger) Decide(ctx context.Context, req DecisionRequest) (*Decision, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	input, err := l.decisionInput(req)
+	if err != nil {
+		return nil, err
+	}
+	return l.record(input, l.ledger)
+}
+
+// Supersede records a decision replacing rawID, in the original's ledger.
+// Fields given in req override the original's. The type, problem, symbols
+// and refs are copied from the original when not given; files and anchors
+// are copied together unless either is given. req.Supersedes may be left
+// empty or name rawID.
+func (l *Ledger) Supersede(ctx context.Context, rawID string, req DecisionRequest) (*Decision, error) {
+	original, err := l.Get(ctx, rawID)
+	if err != nil {
+		return nil, err
+	}
+	if original.Upstream != "" {
+		return nil, readOnlyError(original)
+	}
+	if strings.TrimSpace(req.Choice) == "" {
+		return nil, errs.New(errs.ValidationFailed, "choice is required")
+	}
+	if req.Supersedes != "" {
+		if normalized, err := l.ids.Normalize(req.Supersedes); err != nil || normalized != original.ID {
+			return nil, errs.New(errs.Usage, "supersedes is %s, but the decision being superseded is %s", req.Supersedes, original.ID)
+		}
+	}
+
+	// Build new decision input
+	input := types.DecisionInput{
+		Type:       original.Type,
+		Problem:    original.Problem,
+		Choice:     req.Choice,
+		Files:      original.Files,
+		Anchors:    original.Anchors,
+		Symbols:    original.Symbols,
+		Refs:       original.Refs,
+		Supersedes: &original.ID,
+	}
+
+	if req.Type != "" {
+		if !l.cfg.HasType(req.Type) {
+			return nil, errs.New(errs.ValidationFailed, "invalid type: %s. Must be one of: %s", req.Type, strings.Join(l.cfg.TypeNames(), ", "))
+		}
+		input.Type = types.DecisionType(req.Type)
+	}
+	if req.Problem != "" {
+		input.Problem = req.Problem
+	}
+	if req.Rationale != "" {
+		input.Rationale = &req.Rationale
+	}
+
+	if len(req.Files) > 0 || len(req.Anchors) > 0 {
+		input.Files = req.Files
+		input.Anchors = nil
+		if len(req.Anchors) > 0 {
+			if input.Anchors, err = l.buildAnchors(req.Anchors); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if len(req.Symbols) > 0 {
+		if input.Symbols, err = l.resolveSymbols(req.Symbols, req.NoSymbolCheck); err != nil {
+			return nil, err
+		}
+	}
+	if len(req.Refs) > 0 {
+		input.Refs = req.Refs
+	}
+
+	if input.ReviewBy, err = parseDate(req.ReviewBy); err != nil {
+		return nil, err
+	}
+	if input.ExpiresAt, err = parseDate(req.ExpiresAt); err != nil {
+		return nil, err
+	}
+
+	input.DecidedBy = l.decidedBy(req.Agent, req.As)
+
+	// Save the new decision and mark the original as superseded
+	return l.record(input, original.Ledger)
+}
+
+// Amend applies an amendment to an active decision and returns the result
+func (l *Ledger) Amend(ctx context.Context, rawID string, a Amendment) (*Decision, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	normalizedID, err := l.ids.Normalize(rawID)
+	if err != nil {
+		return nil, err
+	}
+	if a.empty() {
+		return nil, errs.New(errs.Usage, "nothing to amend: give a rationale, files, symbols, anchors, refs or a date")
+	}
+
+	// Resolve everything that reads the source tree before taking the lock
+	var symbolNames []string
+	if len(a.Symbols) > 0 {
+		if symbolNames, err = l.resolveSymbols(a.Symbols, a.NoSymbolCheck); err != nil {
+			return nil, err
+		}
+	}
+	var anchors []types.Anchor
+	if len(a.Anchors) > 0 {
+		if anchors, err = l.buildAnchors(a.Anchors); err != nil {
+			return nil, err
+		}
+	}
+	reviewBy, err := parseDate(a.ReviewBy)
+	if err != nil {
+		return nil, err
+	}
+	expiresAt, err := parseDate(a.ExpiresAt)
+	if err != nil {
+		return nil, err
+	}
+
+	ledger, err := l.ledgerOf(normalizedID)
+	if err != nil {
+		return nil, err
+	}
+	unlock, err := l.lock(ledger)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	d, err := store.GetDecisionByID(normalizedID, store.LedgerDir(l.root, ledger))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read decision: %w", err)
+	}
+	if d == nil {
+		return nil, errs.New(errs.NotFound, "decision %s not found", normalizedID)
+	}
+	if d.Status != types.StatusActive {
+		return nil, errs.New(errs.Conflict, "decision %s is %s; supersede its replacement instead", d.ID, d.Status)
+	}
+
+	if a.Rationale != "" {
+		d.Rationale = &a.Rationale
+	}
+	if len(a.Files) > 0 {
+		d.Files = a.Files
+	}
+	if symbolNames != nil {
+		d.Symbols = symbolNames
+	}
+	if anchors != nil {
+		d.Anchors = anchors
+	}
+	if len(a.Refs) > 0 {
+		d.Refs = a.Refs
+	}
+	if reviewBy != nil {
+		d.ReviewBy = reviewBy
+	}
+	if expiresAt != nil {
+		d.ExpiresAt = expiresAt
+	}
+	if err := l.checkDecision(d); err != nil {
+		return nil, err
+	}
+
+	d.Ledger = ledger
+	if err := store.AppendDecision(d, store.LedgerDir(l.root, ledger)); err != nil {
+		return nil, fmt.Errorf("failed to save amendment: %w", err)
+	}
+	if err := l.db.IndexDecision(d); err != nil {
+		return nil, fmt.Errorf("failed to index amendment: %w", err)
+	}
+	return d, nil
+}
+
+// ApprovalResult is the outcome of Approve
+type ApprovalResult struct {
+	Decision *Decision `json:"decision"`
+	By       string    `json:"by"`
+	Added    bool      `json:"added"`    // false when By had already approved
+	Required int       `json:"required"` // approvals the policy requires, 0 when none
+}
+
+// Approve records a sign-off on an active decision. An empty approver is
+// the git user.email. Approving a decision twice records nothing.
+func (l *Ledger) Approve(ctx context.Context, rawID, approver string) (*ApprovalResult, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	normalizedID, err := l.ids.Normalize(rawID)
+	if err != nil {
+		return nil, err
+	}
+	if approver == "" {
+		approver = identity.GitEmail(l.root)
+	}
+	if approver == "" {
+		return nil, errs.New(errs.Usage, "could not determine approver. Set git user.email or pass --as")
+	}
+
+	ledger, err := l.ledgerOf(normalizedID)
+	if err != nil {
+		return nil, err
+	}
+	unlock, err := l.lock(ledger)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	// Check and append the latest state, read under the lock, so a
+	// supersession or approval written meanwhile is not undone
+	d, err := store.GetDecisionByID(normalizedID, store.LedgerDir(l.root, ledger))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read decision: %w", err)
+	}
+	if d == nil {
+		return nil, errs.New(errs.NotFound, "decision %s not found", normalizedID)
+	}
+	d.Ledger = ledger
+	if d.Status != types.StatusActive {
+		return nil, errs.New(errs.Conflict, "decision %s is %s and cannot be approved", normalizedID, d.Status)
+	}
+	if !l.cfg.AllowSelfApproval() && d.DecidedBy.Identifier != nil && *d.DecidedBy.Identifier == approver {
+		return nil, errs.New(errs.ValidationFailed, "%s made decision %s and cannot approve it", approver, normalizedID)
+	}
+
+	result := &ApprovalResult{Decision: d, By: approver}
+	if !d.HasApprovalFrom(approver) {
+		d.Approvals = append(d.Approvals, types.Approval{
+			By:         approver,
+			ApprovedAt: time.Now().UTC().Format(time.RFC3339Nano),
+		})
+		if err := store.AppendDecision(d, store.LedgerDir(l.root, ledger)); err != nil {
+			return nil, fmt.Errorf("failed to save approval: %w", err)
+		}
+		if err := l.db.IndexDecision(d); err != nil {
+			return nil, fmt.Errorf("failed to index approval: %w", err)
+		}
+		result.Added = true
+	}
+	result.Required = l.RequiredApprovals(d)
+	return result, nil
+}
+
+func (a Amendment) empty() bool {
+	return a.Rationale == "" && len(a.Files) == 0 && len(a.Symbols) == 0 && len(a.Anchors) == 0 &&
+		len(a.Refs) == 0 && a.ReviewBy == "" && a.ExpiresAt == ""
+}
+
+// decisionInput validates a request and resolves its symbols, anchors,
+// dates and author
+func (l *Ledger) decisionInput(req DecisionRequest) (types.DecisionInput, error) {
+	// Validate type
+	if !l.cfg.HasType(req.Type) {
+		return types.DecisionInput{}, errs.New(errs.ValidationFailed, "invalid type: %s. Must be one of: %s", req.Type, strings.Join(l.cfg.TypeNames(), ", "))
+	}
+	if strings.TrimSpace(req.Problem) == "" || strings.TrimSpace(req.Choice) == "" {
+		return types.DecisionInput{}, errs.New(errs.ValidationFailed, "problem and choice are required")
+	}
+
+	// Build input
+	input := types.DecisionInput{
+		Type:    types.DecisionType(req.Type),
+		Problem: req.Problem,
+		Choice:  req.Choice,
+		Files:   req.Files,
+		Refs:    req.Refs,
+	}
+
+	if req.Rationale != "" {
+		input.Rationale = &req.Rationale
+	}
+
+	var err error
+	if len(req.Symbols) > 0 {
+		if input.Symbols, err = l.resolveSymbols(req.Symbols, req.NoSymbolCheck); err != nil {
+			return types.DecisionInput{}, err
+		}
+	}
+
+	if len(req.Anchors) > 0 {
+		if input.Anchors, err = l.buildAnchors(req.Anchors); err != nil {
+			return types.DecisionInput{}, err
+		}
+	}
+
+	if req.Supersedes != "" {
+		normalized, err := l.ids.Normalize(req.Supersedes)
+		if err != nil {
+			return types.DecisionInput{}, err
+		}
+		input.Supersedes = &normalized
+	}
+
+	if input.ReviewBy, err = parseDate(req.ReviewBy); err != nil {
+		return types.DecisionInput{}, err
+	}
+	if input.ExpiresAt, err = parseDate(req.ExpiresAt); err != nil {
+		return types.DecisionInput{}, err
+	}
+
+	input.DecidedBy = l.decidedBy(req.Agent, req.As)
+	return input, nil
+}
+
+// record creates a decision from input, appends it to a ledger and indexes
+// it. A decision that supersedes another also marks the old one.
+func (l *Ledger) record(input types.DecisionInput, ledger string) (*Decision, error) {
+	decisionID := l.ids.Generate(input.Problem, input.Choice)
+
+	decision := types.NewDecision(decisionID, input)
+	if err := l.checkDecision(decision); err != nil {
+		return nil, err
+	}
+
+	// The superseded decision may live in another ledger
+	ledgers := []string{ledger}
+	oldLedger := ledger
+	if input.Supersedes != nil {
+		var err error
+		if oldLedger, err = l.ledgerOf(*input.Supersedes); err != nil {
+			return nil, err
+		}
+		ledgers = append(ledgers, oldLedger)
+	}
+	unlock, err := l.lock(ledgers...)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	// Read the superseded decision under the lock so two writers can't
+	// both replace it
+	var oldDecision *types.Decision
+	if input.Supersedes != nil {
+		oldDecision, err = store.GetDecisionByID(*input.Supersedes, store.LedgerDir(l.root, oldLedger))
+		if err != nil {
+			return nil, fmt.Errorf("failed to get superseded decision: %w", err)
+		}
+		if oldDecision == nil {
+			return nil, errs.New(errs.NotFound, "decision %s not found", *input.Supersedes)
+		}
+		if oldDecision.Status == types.StatusSuperseded {
+			if oldDecision.SupersededBy != nil {
+				return nil, errs.New(errs.Conflict, "decision %s is already superseded by %s", oldDecision.ID, *oldDecision.SupersededBy)
+			}
+			return nil, errs.New(errs.Conflict, "decision %s is already superseded", oldDecision.ID)
+		}
+	}
+
+	// Append to JSONL
+	decision.Ledger = ledger
+	if err := store.AppendDecision(decision, store.LedgerDir(l.root, ledger)); err != nil {
+		return nil, fmt.Errorf("failed to save decision: %w", err)
+	}
+	if err := l.db.IndexDecision(decision); err != nil {
+		return nil, fmt.Errorf("failed to index decision: %w", err)
+	}
+
+	// Mark old decision as superseded
+	if oldDecision != nil {
+		oldDecision.Status = types.StatusSuperseded
+		oldDecision.SupersededBy = &decisionID
+		oldDecision.Ledger = oldLedger
+		if err := store.AppendDecision(oldDecision, store.LedgerDir(l.root, oldLedger)); err != nil {
+			return nil, fmt.Errorf("failed to update superseded decision: %w", err)
+		}
+		if err := l.db.IndexDecision(oldDecision); err != nil {
+			return nil, fmt.Errorf("failed to index superseded decision: %w", err)
+		}
+	}
+
+	return decision, nil
+}
+
+// ledgerOf returns the ledger holding a decision
+func (l *Ledger) ledgerOf(decisionID string) (string, error) {
+	d, err := query.ByID(l.db, decisionID)
+	if err != nil {
+		return "", err
+	}
+	if d == nil {
+		return "", errs.New(errs.NotFound, "decision %s not found", decisionID)
+	}
+	if d.Upstream != "" {
+		return "", readOnlyError(d)
+	}
+	return d.Ledger, nil
+}
+
+// readOnlyError reports an attempt to change an upstream decision
+func readOnlyError(d *types.Decision) error {
+	return errs.New(errs.Conflict, "decision %s comes from upstream %s and is read-only here; change it in that repository", d.ID, d.Upstream)
+}
+
+// writable fails for a ledger opened as of an earlier point
+func (l *Ledger) writable() error {
+	if l.asOf != nil {
+		return errs.New(errs.Usage, "the ledger as of %s is read-only", l.asOf.Spec)
+	}
+	return nil
+}
+
+// lock takes the write locks of ledgers in a fixed order, so writers that
+// need the same two ledgers can't each hold one while waiting for the other
+func (l *Ledger) lock(ledgers ...string) (func(), error) {
+	if err := l.writable(); err != nil {
+		return nil, err
+	}
+	sorted := append([]string(nil), ledgers...)
+	sort.Strings(sorted)
+	var unlocks []func()
+	unlockAll := func() {
+		for i := len(unlocks) - 1; i >= 0; i-- {
+			unlocks[i]()
+		}
+	}
+	for i, ledger := range sorted {
+		if i > 0 && ledger == sorted[i-1] {
+			continue
+		}
+		unlock, err := store.Lock(store.LedgerDir(l.root, ledger))
+		if err != nil {
+			unlockAll()
+			return nil, err
+		}
+		unlocks = append(unlocks, unlock)
+	}
+	return unlockAll, nil
+}
+
+// resolveSymbols checks Go symbols against the source tree and returns the
+// names in canonical spelling, unless the check is skipped
+func (l *Ledger) resolveSymbols(names []string, skipCheck bool) ([]string, error) {
+	if skipCheck {
+		return names, nil
+	}
+	resolved, missing, err := l.goSymbols.Resolve(names)
+	if err != nil {
+		return nil, err
+	}
+	if len(missing) > 0 {
+		return nil, errs.New(errs.ValidationFailed, "symbols not found in Go sources: %s (use --no-symbol-check to record anyway)", strings.Join(missing, ", "))
+	}
+	return symbols.Canonical(names, resolved), nil
+}
+
+// buildAnchors fingerprints the line ranges given as file:start-end
+func (l *Ledger) buildAnchors(specs []string) ([]types.Anchor, error) {
+	anchors := make([]types.Anchor, 0, len(specs))
+	for _, spec := range specs {
+		file, start, end, err := anchor.Parse(spec)
+		if err != nil {
+			return nil, err
+		}
+		a, err := anchor.New(l.root, file, start, end)
+		if err != nil {
+			return nil, err
+		}
+		anchors = append(anchors, a)
+	}
+	return anchors, nil
+}
+
+// checkDecision enforces the repository config on a decision
+func (l *Ledger) checkDecision(d *types.Decision) error {
+	if problems := l.cfg.CheckDecision(d); len(problems) > 0 {
+		return errs.New(errs.ValidationFailed, "invalid %s decision: %s", d.Type, strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+func (l *Ledger) decidedBy(agent bool, as string) *types.DecidedBy {
+	role := "human"
+	if agent {
+		role = "agent"
+	}
+	decidedBy := identity.Resolve(l.root, role, as)
+	return &decidedBy
+}
+
+// parseDate validates an optional date and normalizes it for storage
+func parseDate(value string) (*string, error) {
+	if value == "" {
+		return nil, nil
+	}
+	t, err := types.ParseDate(value)
+	if err != nil {
+		return nil, &errs.Error{Kind: errs.ValidationFailed, Message: err.Error(), Err: err}
+	}
+	formatted := types.FormatDate(t)
+	return &formatted, nil
+}
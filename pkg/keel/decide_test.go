@@ -0,0 +1,94 @@
+package keel
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+func TestSupersede(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name     string
+		req      DecisionRequest
+		wantKind ErrorKind
+		check    func(t *testing.T, original, d *Decision)
+	}{
+		{
+			name: "copies unset fields",
+			req:  DecisionRequest{Choice: "In a queue"},
+			check: func(t *testing.T, original, d *Decision) {
+				if d.Type != original.Type || d.Problem != original.Problem {
+					t.Errorf("type, problem = %s, %q; want %s, %q", d.Type, d.Problem, original.Type, original.Problem)
+				}
+				if !reflect.DeepEqual(d.Symbols, original.Symbols) || !reflect.DeepEqual(d.Files, original.Files) || !reflect.DeepEqual(d.Refs, original.Refs) {
+					t.Errorf("links = %v %v %v, want %v %v %v", d.Symbols, d.Files, d.Refs, original.Symbols, original.Files, original.Refs)
+				}
+			},
+		},
+		{
+			name: "overrides given fields",
+			req: DecisionRequest{
+				Type:          "constraint",
+				Choice:        "In a queue",
+				Symbols:       []string{"retry.Queue"},
+				Files:         []string{"queue.go"},
+				NoSymbolCheck: true,
+			},
+			check: func(t *testing.T, original, d *Decision) {
+				if d.Type != TypeConstraint {
+					t.Errorf("type = %s, want constraint", d.Type)
+				}
+				if !reflect.DeepEqual(d.Symbols, []string{"retry.Queue"}) || !reflect.DeepEqual(d.Files, []string{"queue.go"}) {
+					t.Errorf("symbols, files = %v, %v", d.Symbols, d.Files)
+				}
+				if !reflect.DeepEqual(d.Refs, original.Refs) {
+					t.Errorf("refs = %v, want %v", d.Refs, original.Refs)
+				}
+			},
+		},
+		{
+			name:     "invalid type",
+			req:      DecisionRequest{Type: "nope", Choice: "In a queue"},
+			wantKind: KindValidationFailed,
+		},
+		{
+			name:     "supersedes another decision",
+			req:      DecisionRequest{Choice: "In a queue", Supersedes: "DEC-ffff"},
+			wantKind: KindUsage,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := openTestLedger(t)
+			original, err := l.Decide(ctx, DecisionRequest{
+				Type:          "product",
+				Problem:       "Where retries live",
+				Choice:        "In the client",
+				Files:         []string{"client.go"},
+				Symbols:       []string{"retry.Client"},
+				Refs:          []string{"JIRA-1"},
+				NoSymbolCheck: true,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			d, err := l.Supersede(ctx, original.ID, tt.req)
+			if tt.wantKind != "" {
+				if KindOf(err) != tt.wantKind {
+					t.Fatalf("err = %v, want kind %s", err, tt.wantKind)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if d.Supersedes == nil || *d.Supersedes != original.ID {
+				t.Errorf("supersedes = %v, want %s", d.Supersedes, original.ID)
+			}
+			tt.check(t, original, d)
+		})
+	}
+}
@@ -0,0 +1,231 @@
+package keel
+
+import (
+	"context"
+	"strings"
+
+	"github.com/tyroneavnit/keel/internal/errs"
+	"github.com/tyroneavnit/keel/internal/git"
+	"github.com/tyroneavnit/keel/internal/history"
+)
+
+// Kinds of Change
+const (
+	ChangeAdded      = history.ChangeAdded
+	ChangeSuperseded = history.ChangeSuperseded
+	ChangeStatus     = history.ChangeStatus
+	ChangeAmended    = history.ChangeAmended
+	ChangeRemoved    = history.ChangeRemoved
+)
+
+// Change is how one decision differs between two revisions: added,
+// superseded, changed status, amended or removed. Amended fields and lost
+// file, symbol and ref links are listed.
+type (
+	Change      = history.Change
+	FieldChange = history.FieldChange
+	ChangeLink  = history.Link
+)
+
+// Kinds of Event
+const (
+	EventCreated    = history.EventCreated
+	EventSuperseded = history.EventSuperseded
+	EventApproved   = history.EventApproved
+	EventRelocated  = history.EventRelocated
+	EventAmended    = history.EventAmended
+	EventRecorded   = history.EventRecorded
+)
+
+// Event is one ledger line recorded for a decision, with the fields it
+// changed and the commit that added it. Commit is nil for an uncommitted
+// line; its PR is read from squash and merge commit messages.
+type (
+	Event  = history.Event
+	Commit = history.Commit
+)
+
+// Version is one decision in a supersession chain, with its ledger events.
+// Head marks the chain's active end, the decision in force.
+type Version struct {
+	Decision *Decision `json:"decision"`
+	Head     bool      `json:"head"`
+	History  []Event   `json:"history"`
+}
+
+// Resolution is where a decision's supersession chain leads
+type Resolution struct {
+	ID    string    `json:"id"`    // the decision asked about
+	Chain []string  `json:"chain"` // IDs followed, from ID to the head
+	Head  *Decision `json:"head"`  // the decision in force
+}
+
+// DiffResult holds the decision changes between two revisions
+type DiffResult struct {
+	From    string   `json:"from"` // commit hashes
+	To      string   `json:"to"`
+	Changes []Change `json:"changes"`
+}
+
+// Diff compares every ledger committed at two git revisions. With to
+// empty, from may be a range: a..b compares a with b, and a...b compares b
+// with its merge base with a, as a pull request would.
+func (l *Ledger) Diff(ctx context.Context, from, to string) (*DiffResult, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if !git.IsRepo(l.root) {
+		return nil, errs.New(errs.Usage, "diffing revisions needs a git repository")
+	}
+	if to == "" {
+		if a, b, ok := strings.Cut(from, "..."); ok {
+			from, to = orHead(a), orHead(b)
+			base, err := git.MergeBase(l.root, from, to)
+			if err != nil {
+				return nil, errs.New(errs.Usage, "no merge base for %s and %s", from, to)
+			}
+			from = base
+		} else if a, b, ok := strings.Cut(from, ".."); ok {
+			from, to = orHead(a), orHead(b)
+		} else {
+			to = "HEAD"
+		}
+	}
+
+	var points [2]*history.Point
+	for i, rev := range []string{from, to} {
+		commit := git.ResolveCommit(l.root, rev)
+		if commit == "" {
+			return nil, errs.New(errs.Usage, "unknown revision %s", rev)
+		}
+		points[i] = &history.Point{Spec: rev, Commit: commit}
+	}
+	before, err := points[0].State(l.root)
+	if err != nil {
+		return nil, err
+	}
+	after, err := points[1].State(l.root)
+	if err != nil {
+		return nil, err
+	}
+
+	changes := history.Diff(before, after)
+	if changes == nil {
+		changes = []Change{}
+	}
+	return &DiffResult{From: points[0].Commit, To: points[1].Commit, Changes: changes}, nil
+}
+
+// Blame returns every ledger line recorded for a decision, oldest first,
+// each mapped to the commit, author and pull request that added it
+func (l *Ledger) Blame(ctx context.Context, rawID string) ([]Event, error) {
+	d, err := l.Get(ctx, rawID)
+	if err != nil {
+		return nil, err
+	}
+	if d.Upstream != "" {
+		return nil, errs.New(errs.Usage, "decision %s comes from upstream %s; its history is in that repository", d.ID, d.Upstream)
+	}
+	return history.Events(l.root, d.Ledger, d.ID)
+}
+
+// Resolve follows a decision's superseded_by links to the head of its
+// chain, the decision now in force. A decision that isn't superseded is its
+// own head. A chain that loops fails with KindValidationFailed, and one
+// that names an unknown decision with KindNotFound.
+func (l *Ledger) Resolve(ctx context.Context, rawID string) (*Resolution, error) {
+	d, err := l.Get(ctx, rawID)
+	if err != nil {
+		return nil, err
+	}
+
+	res := &Resolution{ID: d.ID, Chain: []string{d.ID}}
+	seen := map[string]bool{d.ID: true}
+	for d.SupersededBy != nil {
+		next := *d.SupersededBy
+		if seen[next] {
+			return nil, errs.New(errs.ValidationFailed, "supersession cycle: %s → %s", strings.Join(res.Chain, " → "), next)
+		}
+		successor, err := l.lookup(ctx, next)
+		if err != nil {
+			return nil, err
+		}
+		if successor == nil {
+			return nil, errs.New(errs.NotFound, "%s is superseded by %s, which is not in the ledger", d.ID, next)
+		}
+		seen[next] = true
+		res.Chain = append(res.Chain, next)
+		d = successor
+	}
+	res.Head = d
+	return res, nil
+}
+
+// History returns the supersession chain a decision belongs to, oldest
+// first: the decisions it replaced, itself, and those that replaced it,
+// each with every ledger line recorded for it. Upstream decisions carry no
+// events. A chain that loops back on itself stops at the repeat.
+func (l *Ledger) History(ctx context.Context, rawID string) ([]Version, error) {
+	d, err := l.Get(ctx, rawID)
+	if err != nil {
+		return nil, err
+	}
+
+	seen := map[string]bool{d.ID: true}
+	chain := []*Decision{d}
+	for cur := d; cur.Supersedes != nil && !seen[*cur.Supersedes]; {
+		prev, err := l.lookup(ctx, *cur.Supersedes)
+		if err != nil {
+			return nil, err
+		}
+		if prev == nil {
+			break
+		}
+		seen[prev.ID] = true
+		chain = append([]*Decision{prev}, chain...)
+		cur = prev
+	}
+	for cur := d; cur.SupersededBy != nil && !seen[*cur.SupersededBy]; {
+		next, err := l.lookup(ctx, *cur.SupersededBy)
+		if err != nil {
+			return nil, err
+		}
+		if next == nil {
+			break
+		}
+		seen[next.ID] = true
+		chain = append(chain, next)
+		cur = next
+	}
+
+	versions := make([]Version, len(chain))
+	for i, v := range chain {
+		versions[i] = Version{Decision: v, History: []Event{}}
+		if v.Upstream == "" {
+			if versions[i].History, err = history.Events(l.root, v.Ledger, v.ID); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if last := &versions[len(versions)-1]; last.Decision.Status == StatusActive {
+		last.Head = true
+	}
+	return versions, nil
+}
+
+// lookup returns a decision by normalized ID, or nil when there is none
+func (l *Ledger) lookup(ctx context.Context, decisionID string) (*Decision, error) {
+	d, err := l.Get(ctx, decisionID)
+	if errs.KindOf(err) == errs.NotFound {
+		return nil, nil
+	}
+	return d, err
+}
+
+// orHead reads an omitted side of a range as HEAD, as git does
+func orHead(rev string) string {
+	if rev == "" {
+		return "HEAD"
+	}
+	return rev
+}
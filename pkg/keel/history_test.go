@@ -0,0 +1,92 @@
+package keel
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/tyroneavnit/keel/internal/store"
+)
+
+// ledgerLine records a decision, superseded by next unless next is empty
+func ledgerLine(id, next string) string {
+	line := fmt.Sprintf(`{"id":%q,"created_at":"2026-01-01T00:00:00Z","type":"product","problem":"p","choice":"c","decided_by":{"role":"human"},"refs":["JIRA-1"]`, id)
+	if next == "" {
+		return line + `,"status":"active"}`
+	}
+	return line + fmt.Sprintf(`,"status":"superseded","superseded_by":%q}`, next)
+}
+
+func TestDecisionHeadsAgreeWithResolve(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name     string
+		lines    []string
+		wantHead string // "" when the chain has no head
+		wantKind ErrorKind
+	}{
+		{
+			name:     "chain",
+			lines:    []string{ledgerLine("DEC-000a", "DEC-000b"), ledgerLine("DEC-000b", "DEC-000c"), ledgerLine("DEC-000c", "")},
+			wantHead: "DEC-000c",
+		},
+		{
+			name:     "loop",
+			lines:    []string{ledgerLine("DEC-000a", "DEC-000b"), ledgerLine("DEC-000b", "DEC-000c"), ledgerLine("DEC-000c", "DEC-000b")},
+			wantKind: KindValidationFailed,
+		},
+		{
+			name:     "self loop",
+			lines:    []string{ledgerLine("DEC-000a", "DEC-000a")},
+			wantKind: KindValidationFailed,
+		},
+		{
+			name:     "unknown successor",
+			lines:    []string{ledgerLine("DEC-000a", "DEC-000b"), ledgerLine("DEC-000b", "DEC-ffff")},
+			wantKind: KindNotFound,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := initRepo(t)
+			writeFile(t, root, store.KeelDir+"/decisions.jsonl", strings.Join(tt.lines, "\n")+"\n")
+			l, err := Open(ctx, WithRepoRoot(root))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer l.Close()
+
+			res, err := l.Resolve(ctx, "DEC-000a")
+			switch {
+			case tt.wantKind != "":
+				if KindOf(err) != tt.wantKind {
+					t.Errorf("Resolve err = %v, want kind %s", err, tt.wantKind)
+				}
+			case err != nil:
+				t.Fatal(err)
+			case res.Head.ID != tt.wantHead:
+				t.Errorf("Resolve head = %s, want %s", res.Head.ID, tt.wantHead)
+			}
+
+			rows, err := l.Query(ctx, "SELECT head_id FROM decision_heads WHERE id = 'DEC-000a'")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(rows.Rows) != 1 {
+				t.Fatalf("decision_heads has %d rows for DEC-000a, want 1", len(rows.Rows))
+			}
+			head, _ := rows.Rows[0]["head_id"].(string)
+			if head != tt.wantHead {
+				t.Errorf("decision_heads head_id = %v, want %q", rows.Rows[0]["head_id"], tt.wantHead)
+			}
+
+			heads, err := l.Context(ctx, ContextRequest{Ref: "JIRA-1", Follow: true})
+			if err != nil {
+				t.Fatal(err)
+			}
+			checkIDs(t, "JIRA-1", heads.Decisions, tt.wantHead)
+		})
+	}
+}
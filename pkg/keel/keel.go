@@ -0,0 +1,300 @@
+// Package keel reads and writes a repository's decision ledger. It is the
+// API the keel CLI is built on, for Go tools that would otherwise shell out.
+//
+//	l, err := keel.Open(ctx, keel.WithRepoRoot(dir))
+//	if err != nil {
+//		return err
+//	}
+//	defer l.Close()
+//
+//	res, err := l.Context(ctx, keel.ContextRequest{Paths: []string{"billing/invoice.go"}})
+//
+// A repository may hold nested ledgers, such as services/billing/.keel,
+// alongside the root one. Reads cover every ledger; new decisions go to
+// the ledger chosen with WithLedger, and updates to the ledger holding the
+// decision. Writes append to decisions.jsonl under the same lock as the
+// CLI, so a Ledger can be used alongside other keel processes. A Ledger is not safe
+// for concurrent use; serialize calls or open one per goroutine.
+package keel
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"path/filepath"
+
+	"github.com/tyroneavnit/keel/internal/config"
+	"github.com/tyroneavnit/keel/internal/errs"
+	"github.com/tyroneavnit/keel/internal/history"
+	"github.com/tyroneavnit/keel/internal/id"
+	"github.com/tyroneavnit/keel/internal/index"
+	"github.com/tyroneavnit/keel/internal/query"
+	"github.com/tyroneavnit/keel/internal/store"
+	"github.com/tyroneavnit/keel/internal/symbols"
+	"github.com/tyroneavnit/keel/internal/types"
+)
+
+// Decisions and their parts, in the same JSON shape as the ledger and the
+// CLI's --json output
+type (
+	Decision       = types.Decision
+	DecisionType   = types.DecisionType
+	DecisionStatus = types.DecisionStatus
+	DecidedBy      = types.DecidedBy
+	Approval       = types.Approval
+	Anchor         = types.Anchor
+	Annotation     = types.Annotation
+	Symbol         = symbols.Symbol
+	Config         = config.Config
+)
+
+const (
+	TypeProduct    = types.TypeProduct
+	TypeProcess    = types.TypeProcess
+	TypeConstraint = types.TypeConstraint
+	TypeLearning   = types.TypeLearning
+
+	StatusActive     = types.StatusActive
+	StatusSuperseded = types.StatusSuperseded
+)
+
+// ErrorKind classifies the errors Ledger methods return. Kinds match the
+// codes in the CLI's JSON error output.
+type ErrorKind = errs.Kind
+
+const (
+	KindInternal         = errs.Internal
+	KindUsage            = errs.Usage
+	KindNotInitialized   = errs.NotInitialized
+	KindNotFound         = errs.NotFound
+	KindInvalidID        = errs.InvalidID
+	KindConflict         = errs.Conflict
+	KindValidationFailed = errs.ValidationFailed
+)
+
+// KindOf returns the kind of an error returned by a Ledger method
+func KindOf(err error) ErrorKind {
+	return errs.KindOf(err)
+}
+
+// Ledger is an open decision ledger and its index
+type Ledger struct {
+	root      string
+	ledger    string
+	asOf      *history.Point
+	cfg       *Config
+	ids       id.Format
+	db        *index.DB
+	goSymbols *symbols.GoSymbols
+}
+
+// Option configures Open
+type Option func(*options)
+
+type options struct {
+	root   string
+	ledger string
+	asOf   string
+	cfg    *Config
+}
+
+// WithRepoRoot opens the ledgers of the repository at root. By default it
+// is found from $KEEL_DIR or the working directory: the git toplevel.
+func WithRepoRoot(root string) Option {
+	return func(o *options) { o.root = root }
+}
+
+// WithLedger writes new decisions to the ledger in dir, relative to the
+// repository root. By default it is the root ledger, or with no
+// WithRepoRoot the nearest ledger above the working directory.
+func WithLedger(dir string) Option {
+	return func(o *options) { o.ledger = dir }
+}
+
+// WithAsOf reads the ledgers as they were at a git revision (a commit,
+// branch or tag) or an RFC 3339 time. A revision reads the committed
+// ledgers; a time replays the current ledgers up to it. The view is built
+// once into .keel/cache/ and is read-only: writes fail with KindUsage.
+func WithAsOf(spec string) Option {
+	return func(o *options) { o.asOf = spec }
+}
+
+// WithConfig uses cfg instead of reading .keel/config.yaml
+func WithConfig(cfg *Config) Option {
+	return func(o *options) { o.cfg = cfg }
+}
+
+// Open opens a repository's ledger, which must have been created with keel
+// init. Its decision types and ID format come from the config, so ledgers
+// of repositories configured differently can be open side by side.
+func Open(ctx context.Context, opts ...Option) (*Ledger, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	var o options
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if o.root == "" {
+		root, err := store.ResolveRoot("")
+		if err != nil {
+			return nil, err
+		}
+		o.root = root
+		if o.ledger == "" {
+			if o.ledger, err = store.CurrentLedger("", root); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if err := store.RequireInit(o.root); err != nil {
+		return nil, err
+	}
+	o.ledger = path.Clean(filepath.ToSlash(o.ledger))
+	if o.ledger != store.RootLedger && !store.IsInitialized(store.LedgerDir(o.root, o.ledger)) {
+		return nil, errs.New(errs.NotInitialized, "no ledger in %s. Run 'keel init %s' first", o.ledger, o.ledger)
+	}
+
+	if o.cfg == nil {
+		loaded, err := config.Load(o.root)
+		if err != nil {
+			return nil, err
+		}
+		o.cfg = loaded
+	}
+
+	var asOf *history.Point
+	var db *index.DB
+	if o.asOf != "" {
+		point, err := history.Resolve(o.root, o.asOf)
+		if err != nil {
+			return nil, err
+		}
+		key, err := point.Key(o.root)
+		if err != nil {
+			return nil, err
+		}
+		db, err = index.OpenFrozen(o.root, key, func() (map[string]map[string]*types.Decision, error) {
+			return point.State(o.root)
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to build index as of %s: %w", o.asOf, err)
+		}
+		asOf = point
+	} else {
+		var err error
+		if db, err = index.Open(o.root, o.cfg.SharedIndex()); err != nil {
+			return nil, fmt.Errorf("failed to open index: %w", err)
+		}
+	}
+
+	return &Ledger{
+		root:      o.root,
+		ledger:    o.ledger,
+		asOf:      asOf,
+		cfg:       o.cfg,
+		ids:       o.cfg.IDFormat(),
+		db:        db,
+		goSymbols: symbols.NewGoSymbols(o.root),
+	}, nil
+}
+
+// Close closes the index
+func (l *Ledger) Close() error {
+	return l.db.Close()
+}
+
+// Root returns the repository root
+func (l *Ledger) Root() string {
+	return l.root
+}
+
+// LedgerDir returns the repo-relative directory of the ledger new
+// decisions are written to ("." for the root ledger)
+func (l *Ledger) LedgerDir() string {
+	return l.ledger
+}
+
+// Ledgers returns the repo-relative directories of every ledger in the
+// repository, the root ledger first
+func (l *Ledger) Ledgers(ctx context.Context) ([]string, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return store.Ledgers(l.root)
+}
+
+// AsOf returns the revision or time the ledger was opened at with
+// WithAsOf, or "" for the current ledgers
+func (l *Ledger) AsOf() string {
+	if l.asOf == nil {
+		return ""
+	}
+	return l.asOf.Spec
+}
+
+// Config returns the repository config the ledger was opened with
+func (l *Ledger) Config() *Config {
+	return l.cfg
+}
+
+// Refresh picks up changes made since the ledger was opened: decisions
+// written by other processes, and edited source files for symbol lookups.
+// Long-running callers should refresh before each request.
+func (l *Ledger) Refresh(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	l.goSymbols = symbols.NewGoSymbols(l.root)
+	return l.db.Refresh()
+}
+
+// Get returns a decision by ID, in any accepted spelling (DEC-a1b2, a1b2)
+func (l *Ledger) Get(ctx context.Context, rawID string) (*Decision, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	normalizedID, err := l.ids.Normalize(rawID)
+	if err != nil {
+		return nil, err
+	}
+	d, err := query.ByID(l.db, normalizedID)
+	if err != nil {
+		return nil, err
+	}
+	if d == nil {
+		return nil, errs.New(errs.NotFound, "decision %s not found", normalizedID)
+	}
+	return d, nil
+}
+
+// Constraints returns the active constraints in force: those not expired
+// and not awaiting approval
+func (l *Ledger) Constraints(ctx context.Context) ([]*Decision, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	constraints, err := query.ActiveConstraints(l.db)
+	if err != nil {
+		return nil, err
+	}
+	ratified, _ := l.cfg.Approvals().Split(constraints)
+	return ratified, nil
+}
+
+// RequiredApprovals returns how many approvals a decision needs under the
+// repository policy, or 0 when approvals are turned off
+func (l *Ledger) RequiredApprovals(d *Decision) int {
+	return l.cfg.Approvals().RequiredApprovals(d)
+}
+
+// Symbol resolves a qualified Go symbol, such as billing.(*Invoice).Total,
+// in the repository's sources. It returns nil when nothing is declared by that name.
+func (l *Ledger) Symbol(ctx context.Context, name string) (*Symbol, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return l.goSymbols.Lookup(name)
+}
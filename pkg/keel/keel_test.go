@@ -0,0 +1,188 @@
+package keel
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/tyroneavnit/keel/internal/query"
+	"github.com/tyroneavnit/keel/internal/store"
+)
+
+// initRepo creates an empty ledger in a temporary directory
+func initRepo(t *testing.T) string {
+	t.Helper()
+	root := t.TempDir()
+	if err := store.EnsureKeelDir(root); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(store.GetDecisionsPath(root), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	return root
+}
+
+// openTestLedger opens a new empty ledger
+func openTestLedger(t *testing.T) *Ledger {
+	t.Helper()
+	t.Setenv("KEEL_AGENT", "tester@example.com")
+	l, err := Open(context.Background(), WithRepoRoot(initRepo(t)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { l.Close() })
+	return l
+}
+
+func writeFile(t *testing.T, root, name, content string) {
+	t.Helper()
+	path := filepath.Join(root, name)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestAmendReplacesLinks(t *testing.T) {
+	ctx := context.Background()
+	l := openTestLedger(t)
+	writeFile(t, l.Root(), "new.go", "package main\n\nfunc main() {}\n")
+	writeFile(t, l.Root(), "other.go", "package main\n\nfunc other() {}\n")
+
+	d, err := l.Decide(ctx, DecisionRequest{
+		Type:    "product",
+		Problem: "Where retries live",
+		Choice:  "In the client",
+		Files:   []string{"old.go"},
+		Refs:    []string{"JIRA-1"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name  string
+		amend Amendment
+		check func(t *testing.T)
+	}{
+		{
+			name:  "files",
+			amend: Amendment{Files: []string{"new.go"}},
+			check: func(t *testing.T) {
+				expectContext(t, l, "old.go", "")
+				expectContext(t, l, "new.go", d.ID)
+			},
+		},
+		{
+			name:  "anchors",
+			amend: Amendment{Files: []string{"other.go"}, Anchors: []string{"new.go:1-3"}},
+			check: func(t *testing.T) {
+				expectContext(t, l, "other.go", d.ID)
+				expectContext(t, l, "new.go", d.ID)
+			},
+		},
+		{
+			name:  "anchors moved",
+			amend: Amendment{Anchors: []string{"other.go:1-3"}},
+			check: func(t *testing.T) {
+				expectContext(t, l, "new.go", "")
+				expectContext(t, l, "other.go", d.ID)
+			},
+		},
+		{
+			name:  "refs",
+			amend: Amendment{Refs: []string{"JIRA-2"}},
+			check: func(t *testing.T) {
+				expectRef(t, l, "JIRA-1", "")
+				expectRef(t, l, "JIRA-2", d.ID)
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := l.Amend(ctx, d.ID, tt.amend); err != nil {
+				t.Fatal(err)
+			}
+			tt.check(t)
+		})
+	}
+}
+
+// expectContext checks which decision ForContext returns for a path, if any
+func expectContext(t *testing.T, l *Ledger, path, want string) {
+	t.Helper()
+	res, err := query.ForContext(l.db, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkIDs(t, path, res.Decisions, want)
+}
+
+func expectRef(t *testing.T, l *Ledger, ref, want string) {
+	t.Helper()
+	decisions, err := query.ByRef(l.db, ref)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkIDs(t, ref, decisions, want)
+}
+
+func checkIDs(t *testing.T, target string, decisions []*Decision, want string) {
+	t.Helper()
+	var got []string
+	for _, d := range decisions {
+		got = append(got, d.ID)
+	}
+	if want == "" && len(got) > 0 {
+		t.Errorf("%s: got %v, want no decisions", target, got)
+	}
+	if want != "" && (len(got) != 1 || got[0] != want) {
+		t.Errorf("%s: got %v, want [%s]", target, got, want)
+	}
+}
+
+func TestLedgersKeepTheirOwnConfig(t *testing.T) {
+	ctx := context.Background()
+	plain := openTestLedger(t)
+
+	root := initRepo(t)
+	writeFile(t, root, ".keel/config.yaml", "id:\n  prefix: ADR\n  length: 6\ntypes:\n  experiment: {}\n")
+	custom, err := Open(ctx, WithRepoRoot(root))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer custom.Close()
+
+	tests := []struct {
+		name       string
+		ledger     *Ledger
+		typ        string
+		wantPrefix string
+		wantKind   ErrorKind
+	}{
+		{"custom format", custom, "experiment", "ADR-", ""},
+		{"default format", plain, "product", "DEC-", ""},
+		{"type from another config", plain, "experiment", "", KindValidationFailed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d, err := tt.ledger.Decide(ctx, DecisionRequest{Type: tt.typ, Problem: "p", Choice: "c"})
+			if tt.wantKind != "" {
+				if KindOf(err) != tt.wantKind {
+					t.Fatalf("err = %v, want kind %s", err, tt.wantKind)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !strings.HasPrefix(d.ID, tt.wantPrefix) || len(d.ID) != len(tt.wantPrefix)+tt.ledger.Config().ID.Length {
+				t.Errorf("ID = %s, want %s and %d hex chars", d.ID, tt.wantPrefix, tt.ledger.Config().ID.Length)
+			}
+		})
+	}
+}
@@ -0,0 +1,185 @@
+package keel
+
+import (
+	"context"
+	"strings"
+
+	"github.com/tyroneavnit/keel/internal/errs"
+	"github.com/tyroneavnit/keel/internal/index"
+	"github.com/tyroneavnit/keel/internal/query"
+	"github.com/tyroneavnit/keel/internal/types"
+)
+
+// Filter narrows List and Search. Empty fields match everything; the link
+// filters keep decisions linked to all of the given file, ref and symbol.
+type Filter struct {
+	Type   string
+	Status string // active, superseded, or "" or "all" for every status
+	Author string // identifier of who made the decision
+	Ledger string // repo-relative ledger directory, "." for the root
+	File   string
+	Ref    string
+	Symbol string
+	Local  bool // leave out read-only upstream decisions
+	Limit  int  // 0 = no limit
+}
+
+// QueryResult holds the rows of a SQL query. Byte values are returned as strings.
+type QueryResult struct {
+	Columns []string
+	Rows    []map[string]interface{}
+}
+
+// Link kinds
+const (
+	LinkSupersedes = "supersedes"
+	LinkRef        = "ref"
+	LinkFile       = "file"
+)
+
+// Link connects a decision to the decision it supersedes, a ref or a file
+type Link struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+	Kind string `json:"kind"` // supersedes, ref or file
+}
+
+// List returns the decisions matching a filter, newest first
+func (l *Ledger) List(ctx context.Context, f Filter) ([]*Decision, error) {
+	return l.Search(ctx, "", f)
+}
+
+// Search runs a full-text search over the problem, choice and rationale of
+// decisions. Every word must match, as a prefix; results are ordered by
+// relevance. Empty text lists decisions by the filter alone, newest first.
+func (l *Ledger) Search(ctx context.Context, text string, f Filter) ([]*Decision, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	opts, err := l.queryOptions(f)
+	if err != nil {
+		return nil, err
+	}
+
+	// The link filters apply after the query, so the limit must too
+	linked := f.File != "" || f.Ref != "" || f.Symbol != ""
+	if linked {
+		opts.Limit = 0
+	}
+
+	var decisions []*types.Decision
+	if strings.TrimSpace(text) == "" {
+		decisions, err = query.All(l.db, opts)
+	} else {
+		decisions, err = query.Search(l.db, text, opts)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !linked {
+		return decisions, nil
+	}
+
+	filters := []struct {
+		value  string
+		lookup func(*index.DB, string) ([]*types.Decision, error)
+	}{
+		{f.File, query.ByFile},
+		{f.Ref, query.ByRef},
+		{f.Symbol, query.BySymbol},
+	}
+	for _, filter := range filters {
+		if filter.value == "" {
+			continue
+		}
+		matches, err := filter.lookup(l.db, filter.value)
+		if err != nil {
+			return nil, err
+		}
+		ids := make(map[string]bool, len(matches))
+		for _, d := range matches {
+			ids[d.ID] = true
+		}
+		var kept []*types.Decision
+		for _, d := range decisions {
+			if ids[d.ID] {
+				kept = append(kept, d)
+			}
+		}
+		decisions = kept
+	}
+
+	if f.Limit > 0 && len(decisions) > f.Limit {
+		decisions = decisions[:f.Limit]
+	}
+	return decisions, nil
+}
+
+// Query runs a read-only SQL statement against the index. The schema is
+// listed in keel sql --help.
+func (l *Ledger) Query(ctx context.Context, statement string) (*QueryResult, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	columns, rows, err := query.Raw(l.db, statement)
+	if err != nil {
+		return nil, err
+	}
+	return &QueryResult{Columns: columns, Rows: rows}, nil
+}
+
+// Links returns every supersession and ref link, and file links when files is set
+func (l *Ledger) Links(ctx context.Context, files bool) ([]Link, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	decisions, err := query.All(l.db, query.Options{})
+	if err != nil {
+		return nil, err
+	}
+
+	links := []Link{}
+	for _, d := range decisions {
+		if d.Supersedes != nil {
+			links = append(links, Link{From: d.ID, To: *d.Supersedes, Kind: LinkSupersedes})
+		}
+	}
+
+	refs, err := query.AllRefs(l.db)
+	if err != nil {
+		return nil, err
+	}
+	for _, link := range refs {
+		links = append(links, Link{From: link.DecisionID, To: link.RefID, Kind: LinkRef})
+	}
+
+	if files {
+		fileLinks, err := query.AllFileLinks(l.db)
+		if err != nil {
+			return nil, err
+		}
+		for _, link := range fileLinks {
+			links = append(links, Link{From: link.DecisionID, To: link.FilePath, Kind: LinkFile})
+		}
+	}
+	return links, nil
+}
+
+// queryOptions validates a filter's type, status and limit
+func (l *Ledger) queryOptions(f Filter) (query.Options, error) {
+	if f.Type != "" && !l.cfg.HasType(f.Type) {
+		return query.Options{}, errs.New(errs.Usage, "invalid type: %s. Must be one of: %s", f.Type, strings.Join(l.cfg.TypeNames(), ", "))
+	}
+	status := f.Status
+	switch status {
+	case "all":
+		status = ""
+	case "", string(types.StatusActive), string(types.StatusSuperseded):
+	default:
+		return query.Options{}, errs.New(errs.Usage, "invalid status: %s. Must be one of: active, superseded, all", f.Status)
+	}
+	if f.Limit < 0 {
+		return query.Options{}, errs.New(errs.Usage, "invalid limit: %d", f.Limit)
+	}
+	return query.Options{Type: f.Type, Status: status, Author: f.Author, Ledger: f.Ledger, Local: f.Local, Limit: f.Limit}, nil
+}
@@ -0,0 +1,59 @@
+package keel
+
+import (
+	"context"
+	"errors"
+
+	"github.com/tyroneavnit/keel/internal/errs"
+	"github.com/tyroneavnit/keel/internal/upstream"
+)
+
+// UpstreamResult describes an upstream ledger after a sync
+type UpstreamResult = upstream.Result
+
+// SyncUpstream refreshes the cached ledgers of the configured upstreams, or
+// of the named ones, and reindexes them. Their decisions are read-only and
+// their IDs carry the upstream's name (platform:DEC-a1b2). A full sync also
+// drops upstreams that are no longer configured.
+func (l *Ledger) SyncUpstream(ctx context.Context, names ...string) ([]*UpstreamResult, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if err := l.writable(); err != nil {
+		return nil, err
+	}
+	all := len(names) == 0
+	if all {
+		names = l.cfg.UpstreamNames()
+	}
+	for _, name := range names {
+		if _, ok := l.cfg.Upstream[name]; !ok {
+			return nil, errs.New(errs.Usage, "unknown upstream %s. Configure it under upstream.%s in .keel/config.yaml", name, name)
+		}
+	}
+
+	var results []*UpstreamResult
+	var failures []error
+	for _, name := range names {
+		if err := ctx.Err(); err != nil {
+			return results, err
+		}
+		u := l.cfg.Upstream[name]
+		res, err := upstream.Sync(l.root, name, upstream.Source{URL: u.URL, Ref: u.Ref, Path: u.Path})
+		if err != nil {
+			failures = append(failures, err)
+			continue
+		}
+		results = append(results, res)
+	}
+	if all {
+		if err := upstream.Prune(l.root, names); err != nil {
+			failures = append(failures, err)
+		}
+	}
+
+	if err := l.db.Refresh(); err != nil {
+		return results, err
+	}
+	return results, errors.Join(failures...)
+}